@@ -2,6 +2,8 @@ package main
 
 import (
 	"context"
+	"os/signal"
+	"syscall"
 
 	"github.com/screamsoul/go-metrics-tpl/internal/server"
 	"github.com/screamsoul/go-metrics-tpl/internal/versions"
@@ -11,8 +13,8 @@ import (
 func main() {
 	versions.PrintBuildInfo()
 
-	ctx, cansel := context.WithCancel(context.Background())
-	defer cansel()
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+	defer stop()
 
 	cfg, err := server.NewConfig()
 
@@ -26,5 +28,7 @@ func main() {
 
 	logger := logging.GetLogger()
 
-	server.Start(ctx, cfg, logger)
+	if err := server.Start(ctx, cfg, logger); err != nil {
+		panic(err)
+	}
 }
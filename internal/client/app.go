@@ -2,43 +2,78 @@ package client
 
 import (
 	"context"
+	"crypto/rsa"
 	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/screamsoul/go-metrics-tpl/internal/collectors"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
 	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
 	"github.com/screamsoul/go-metrics-tpl/internal/repositories/memory"
 	"github.com/screamsoul/go-metrics-tpl/pkg/backoff"
+	"github.com/screamsoul/go-metrics-tpl/pkg/encryption"
 	"github.com/screamsoul/go-metrics-tpl/pkg/logging"
+	"github.com/screamsoul/go-metrics-tpl/pkg/utils"
 	"go.uber.org/zap"
 )
 
+// MetricSender sends a batch of metrics to the server.
+type MetricSender interface {
+	SendMetric(ctx context.Context, metricsList []metrics.Metrics) error
+}
+
 func sender(
 	ctx context.Context,
 	metricRepo repositories.CollectionMetric,
 	backoffIntervals []time.Duration,
-	metricClient *MetricsClient,
+	metricClient MetricSender,
 	reportInterval time.Duration,
+	spool *Spool,
 ) {
 	logger := logging.GetLogger()
+
+	send := func(batch []metrics.Metrics) error {
+		sendMetric := func() error {
+			return metricClient.SendMetric(ctx, batch)
+		}
+		return backoff.RetryWithBackoff(backoffIntervals, IsTemporaryNetworkError, sendMetric)
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
-			metricsList, err := metricRepo.List(ctx)
+			batch, err := metricRepo.Reserve(ctx)
 			if err != nil {
-				panic(err)
+				logger.Error("reserve metric batch error", zap.Error(err))
+				break
 			}
 
-			sendMetric := func() error {
-				return metricClient.SendMetric(ctx, metricsList)
+			// spooled batches are sent first to keep the order of updates.
+			if spool != nil {
+				err = spool.Replay(ctx, send)
+			}
+			if err == nil {
+				err = send(batch)
 			}
 
-			if err := backoff.RetryWithBackoff(backoffIntervals, IsTemporaryNetworkError, sendMetric); err != nil {
+			switch {
+			case err == nil:
+				metricRepo.Commit(batch)
+			case IsRejectedError(err):
+				// the batch is rejected the same way on every retry, so it is dropped.
+				logger.Error("metric batch is rejected by the server, drop it", zap.Error(err))
+				metricRepo.Commit(batch)
+			case spool != nil && IsUnavailableError(err) && spool.Push(batch) == nil:
+				logger.Warn("send metric error, batch is spooled", zap.Error(err))
+				metricRepo.Commit(batch)
+			default:
 				logger.Error("send metric error", zap.Error(err))
+				metricRepo.Rollback(batch)
 			}
 		}
 
@@ -46,19 +81,29 @@ func sender(
 	}
 }
 
+// updater polls the collector every pollInterval and stores the collected metrics.
 func updater(
 	ctx context.Context,
 	metricRepo repositories.CollectionMetric,
+	collector collectors.Collector,
 	pollInterval time.Duration,
 ) {
+	logger := logging.GetLogger().With(zap.String("collector", collector.Name()))
+
 	for {
 		select {
 		case <-ctx.Done():
 			return
 		default:
-			metricRepo.Update()
-			metricRepo.UpdateRuntime()
-			metricRepo.UpdateGopsutil()
+			batch, err := collector.Collect(ctx)
+			if err != nil {
+				logger.Warn("collect metrics error", zap.Error(err))
+			}
+			if len(batch) > 0 {
+				if err := metricRepo.BulkAdd(ctx, batch); err != nil {
+					logger.Error("store collected metrics error", zap.Error(err))
+				}
+			}
 			time.Sleep(pollInterval)
 		}
 	}
@@ -76,12 +121,75 @@ func Start(cfg *Config, logger *zap.Logger) {
 	pollInterval := time.Duration(cfg.PollInterval) * time.Second
 	reportInterval := time.Duration(cfg.ReportInterval) * time.Second
 
-	metricClient := NewMetricsClient(cfg.CompressRequest, cfg.HashBodyKey, cfg.GetUpdateMetricURL())
+	opts, err := cfg.Options()
+	if err != nil {
+		logger.Fatal("configure collectors error", zap.Error(err))
+	}
+	registry, err := collectors.NewDefaultRegistry(opts)
+	if err != nil {
+		logger.Fatal("configure collectors error", zap.Error(err))
+	}
+	scheduled, err := scheduleCollectors(registry, &cfg.CollectorConfig, pollInterval)
+	if err != nil {
+		logger.Fatal("configure collectors error", zap.Error(err))
+	}
+
+	var publicKey *rsa.PublicKey
+	if cfg.CryptoKey != "" {
+		if publicKey, err = encryption.LoadPublicKey(cfg.CryptoKey); err != nil {
+			logger.Fatal("load crypto key error", zap.Error(err))
+		}
+	}
+
+	var metricClient MetricSender
+	if cfg.GRPCAddress != "" {
+		grpcClient, err := NewGRPCMetricsClient(cfg.CompressRequest, cfg.HashBodyKey, cfg.GRPCAddress)
+		if err != nil {
+			logger.Fatal("create grpc client error", zap.Error(err))
+		}
+		defer utils.CloseForse(grpcClient)
+		metricClient = grpcClient
+		logger.Info("use grpc server", zap.String("address", cfg.GRPCAddress))
+	} else {
+		metricClient = NewMetricsClient(cfg.CompressRequest, cfg.HashBodyKey, publicKey, cfg.GetUpdateMetricURL())
+	}
+
+	var spool *Spool
+	if cfg.SpoolDir != "" {
+		var err error
+		if spool, err = NewSpool(cfg.SpoolDir, cfg.SpoolMaxSize, cfg.SpoolMaxAge); err != nil {
+			logger.Error("open spool error, unsent metrics will not be buffered", zap.Error(err))
+			spool = nil
+		} else {
+			logger.Info("use spool", zap.String("dir", cfg.SpoolDir), zap.Int("batches", spool.Len()))
+		}
+	}
+
+	for _, s := range scheduled {
+		go updater(ctx, metricRepo, s.collector, s.pollInterval)
+		logger.Info("start collector",
+			zap.String("collector", s.collector.Name()),
+			zap.Duration("poll_interval", s.pollInterval),
+		)
+	}
+	pushDone := make(chan struct{})
+	if cfg.PushConfig.Enabled() {
+		pushServer := NewPushServer(metricRepo)
+		if err := pushServer.Listen(cfg.PushAddress, cfg.PushSocket); err != nil {
+			logger.Fatal("start push server error", zap.Error(err))
+		}
+		go func() {
+			defer close(pushDone)
+			pushServer.Run(ctx)
+		}()
+		logger.Info("start push server", zap.String("address", cfg.PushAddress), zap.String("socket", cfg.PushSocket))
+	} else {
+		close(pushDone)
+	}
 
-	go updater(ctx, metricRepo, pollInterval)
 	logger.Info("start senders", zap.Int("count_senders", cfg.RateLimit))
 	for i := 0; i < cfg.RateLimit; i++ {
-		go sender(ctx, metricRepo, cfg.BackoffIntervals, metricClient, reportInterval)
+		go sender(ctx, metricRepo, cfg.BackoffIntervals, metricClient, reportInterval, spool)
 	}
 
 	sigChan := make(chan os.Signal, 1)
@@ -93,5 +201,6 @@ func Start(cfg *Config, logger *zap.Logger) {
 	}()
 
 	<-ctx.Done()
+	<-pushDone
 	fmt.Println("Agent closed:", ctx.Err())
 }
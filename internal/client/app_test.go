@@ -2,13 +2,19 @@ package client
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/screamsoul/go-metrics-tpl/internal/collectors"
 	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories/memory"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 type MockMetricStorage struct {
@@ -21,16 +27,36 @@ func (m *MockMetricStorage) List(ctx context.Context) ([]metrics.Metrics, error)
 	return args.Get(0).([]metrics.Metrics), args.Error(1)
 }
 
-func (m *MockMetricStorage) Update() {
-	m.Called()
+// Reserve mocks the Reserve method
+func (m *MockMetricStorage) Reserve(ctx context.Context) ([]metrics.Metrics, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]metrics.Metrics), args.Error(1)
+}
+
+func (m *MockMetricStorage) Commit(batch []metrics.Metrics) {
+	m.Called(batch)
+}
+
+func (m *MockMetricStorage) Rollback(batch []metrics.Metrics) {
+	m.Called(batch)
 }
 
-func (m *MockMetricStorage) UpdateRuntime() {
-	m.Called()
+func (m *MockMetricStorage) BulkAdd(ctx context.Context, batch []metrics.Metrics) error {
+	args := m.Called(ctx, batch)
+	return args.Error(0)
 }
 
-func (m *MockMetricStorage) UpdateGopsutil() {
-	m.Called()
+type MockCollector struct {
+	mock.Mock
+}
+
+func (m *MockCollector) Name() string {
+	return "mock"
+}
+
+func (m *MockCollector) Collect(ctx context.Context) ([]metrics.Metrics, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]metrics.Metrics), args.Error(1)
 }
 
 func TestUpdaterUpdatesMetricsAtRegularIntervals(t *testing.T) {
@@ -38,20 +64,21 @@ func TestUpdaterUpdatesMetricsAtRegularIntervals(t *testing.T) {
 	defer cancel()
 
 	mockRepo := new(MockMetricStorage)
+	mockCollector := new(MockCollector)
 	pollInterval := 100 * time.Millisecond
 
-	mockRepo.On("Update").Return()
-	mockRepo.On("UpdateRuntime").Return()
-	mockRepo.On("UpdateGopsutil").Return()
+	batch := []metrics.Metrics{{ID: "PollCount", MType: metrics.Counter, Delta: new(int64)}}
+	mockCollector.On("Collect", ctx).Return(batch, nil)
+	mockRepo.On("BulkAdd", ctx, batch).Return(nil)
 
-	go updater(ctx, mockRepo, pollInterval)
+	go updater(ctx, mockRepo, mockCollector, pollInterval)
 
-	time.Sleep(300 * time.Millisecond)
+	// polls at 0, 100 and 200ms, the sleep ends between polls to not race with the fourth one.
+	time.Sleep(250 * time.Millisecond)
 	cancel()
 
-	mockRepo.AssertNumberOfCalls(t, "Update", 3)
-	mockRepo.AssertNumberOfCalls(t, "UpdateRuntime", 3)
-	mockRepo.AssertNumberOfCalls(t, "UpdateGopsutil", 3)
+	mockCollector.AssertNumberOfCalls(t, "Collect", 3)
+	mockRepo.AssertNumberOfCalls(t, "BulkAdd", 3)
 }
 
 // Successfully retrieves metrics from metricRepo and sends them using metricClient
@@ -67,17 +94,209 @@ func TestSender_SuccessfullySendsMetrics(t *testing.T) {
 
 	// Create a MetricsClient instance
 	metricClient := NewMetricsClient(
-		false, "", server.URL,
+		false, "", nil, server.URL,
 	)
 	mockMetricStorage := new(MockMetricStorage)
 
 	metricsList := []metrics.Metrics{{ID: "test_metric", MType: metrics.Gauge, Value: new(float64)}}
-	mockMetricStorage.On("List", ctx).Return(metricsList, nil)
+	mockMetricStorage.On("Reserve", ctx).Return(metricsList, nil)
+	mockMetricStorage.On("Commit", metricsList).Return()
+	// the batch in flight fails when the test context is canceled
+	mockMetricStorage.On("Rollback", metricsList).Return().Maybe()
 
 	backoffIntervals := []time.Duration{time.Millisecond}
 	reportInterval := time.Millisecond
 
-	go sender(ctx, mockMetricStorage, backoffIntervals, metricClient, reportInterval)
+	go sender(ctx, mockMetricStorage, backoffIntervals, metricClient, reportInterval, nil)
 
 	time.Sleep(2 * reportInterval)
 }
+
+// Counter deltas are acknowledged only after the server accepts the batch
+func TestSender_SendsCounterDeltas(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		received int64
+		fail     = true
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var batch []metrics.Metrics
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for _, m := range batch {
+			if m.ID == "PollCount" {
+				received += *m.Delta
+			}
+		}
+	}))
+	defer server.Close()
+
+	metricRepo := memory.NewCollectionMetricStorage()
+	metricClient := NewMetricsClient(false, "", nil, server.URL)
+
+	poller := collectors.NewPollCollector()
+	poll := func() {
+		batch, err := poller.Collect(ctx)
+		require.NoError(t, err)
+		require.NoError(t, metricRepo.BulkAdd(ctx, batch))
+	}
+
+	for i := 0; i < 3; i++ {
+		poll()
+	}
+
+	for i := 0; i < 4; i++ {
+		go sender(ctx, metricRepo, nil, metricClient, 5*time.Millisecond, nil)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+
+	poll()
+	poll()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return received == 5
+	}, time.Second, 5*time.Millisecond)
+
+	time.Sleep(30 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, int64(5), received)
+}
+
+// Batches failed to send are spooled and delivered before the new ones
+func TestSender_ReplaysSpoolBeforeNewBatches(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		fail    = true
+		batches []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if fail {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var batch []metrics.Metrics
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		batches = append(batches, batch[0].ID)
+	}))
+	defer server.Close()
+
+	spool, err := NewSpool(t.TempDir(), 0, 0)
+	assert.NoError(t, err)
+
+	metricClient := NewMetricsClient(false, "", nil, server.URL)
+	mockMetricStorage := new(MockMetricStorage)
+
+	first := []metrics.Metrics{{ID: "first", MType: metrics.Gauge, Value: new(float64)}}
+	second := []metrics.Metrics{{ID: "second", MType: metrics.Gauge, Value: new(float64)}}
+	mockMetricStorage.On("Reserve", ctx).Return(first, nil).Once()
+	mockMetricStorage.On("Reserve", ctx).Return(second, nil)
+	mockMetricStorage.On("Commit", mock.Anything).Return()
+	mockMetricStorage.On("Rollback", mock.Anything).Return().Maybe()
+
+	go sender(ctx, mockMetricStorage, nil, metricClient, 10*time.Millisecond, spool)
+
+	assert.Eventually(t, func() bool { return spool.Len() > 0 }, time.Second, time.Millisecond)
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(batches) > 0 && batches[len(batches)-1] == "second"
+	}, time.Second, time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "first", batches[0])
+}
+
+// A batch rejected by the server is dropped and does not block the spool
+func TestSender_DropsRejectedBatches(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var (
+		mu      sync.Mutex
+		batches []string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var batch []metrics.Metrics
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil || batch[0].ID == "rejected" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		batches = append(batches, batch[0].ID)
+	}))
+	defer server.Close()
+
+	spool, err := NewSpool(t.TempDir(), 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, spool.Push([]metrics.Metrics{{ID: "rejected", MType: metrics.Gauge, Value: new(float64)}}))
+
+	metricClient := NewMetricsClient(false, "", nil, server.URL)
+	mockMetricStorage := new(MockMetricStorage)
+
+	rejected := []metrics.Metrics{{ID: "rejected", MType: metrics.Gauge, Value: new(float64)}}
+	next := []metrics.Metrics{{ID: "next", MType: metrics.Gauge, Value: new(float64)}}
+	mockMetricStorage.On("Reserve", ctx).Return(rejected, nil).Once()
+	mockMetricStorage.On("Reserve", ctx).Return(next, nil)
+	mockMetricStorage.On("Commit", mock.Anything).Return()
+	mockMetricStorage.On("Rollback", mock.Anything).Return().Maybe()
+
+	go sender(ctx, mockMetricStorage, nil, metricClient, 10*time.Millisecond, spool)
+
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(batches) > 0
+	}, time.Second, time.Millisecond)
+
+	// neither the spooled nor the new rejected batch is kept for a retry.
+	assert.Equal(t, 0, spool.Len())
+	mockMetricStorage.AssertCalled(t, "Commit", rejected)
+	mockMetricStorage.AssertNotCalled(t, "Rollback", rejected)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, "next", batches[0])
+}
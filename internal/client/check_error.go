@@ -6,6 +6,8 @@ import (
 	"net/http"
 
 	"github.com/go-resty/resty/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func IsTemporaryNetworkError(err error) bool {
@@ -22,5 +24,63 @@ func IsTemporaryNetworkError(err error) bool {
 		}
 	}
 
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &grpcErr) {
+		switch grpcErr.GRPCStatus().Code() {
+		case codes.Unavailable, codes.DeadlineExceeded:
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsUnavailableError reports whether the batch was not delivered because the server is unreachable
+// or overloaded: network errors, 408, 429, 502, 503 and 504 statuses and the similar gRPC codes.
+// Such batches can be spooled and sent later.
+func IsUnavailableError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var respErr *resty.ResponseError
+	if errors.As(err, &respErr) {
+		switch respErr.Response.StatusCode() {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &grpcErr) {
+		switch grpcErr.GRPCStatus().Code() {
+		case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsRejectedError reports whether the server rejected the batch itself, e.g. a bad hash
+// or an unencrypted body: 4xx statuses other than 408 and 429 and the similar gRPC codes.
+// Sending such a batch again gives the same result.
+func IsRejectedError(err error) bool {
+	var respErr *resty.ResponseError
+	if errors.As(err, &respErr) {
+		code := respErr.Response.StatusCode()
+		return code >= 400 && code < 500 && code != http.StatusRequestTimeout && code != http.StatusTooManyRequests
+	}
+
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &grpcErr) {
+		switch grpcErr.GRPCStatus().Code() {
+		case codes.InvalidArgument, codes.FailedPrecondition, codes.PermissionDenied, codes.Unauthenticated:
+			return true
+		}
+	}
+
 	return false
 }
@@ -8,6 +8,8 @@ import (
 
 	"github.com/go-resty/resty/v2"
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestIsTemporaryNetworkError(t *testing.T) {
@@ -63,6 +65,16 @@ func TestIsTemporaryNetworkError(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "grpc unavailable",
+			err:  status.Error(codes.Unavailable, "connection refused"),
+			want: true,
+		},
+		{
+			name: "grpc invalid argument",
+			err:  status.Error(codes.InvalidArgument, "bad metric"),
+			want: false,
+		},
 		{
 			name: "other error",
 			err:  errors.New("some other error"),
@@ -77,3 +89,35 @@ func TestIsTemporaryNetworkError(t *testing.T) {
 		})
 	}
 }
+
+func TestIsUnavailableAndRejectedError(t *testing.T) {
+	response := func(code int) error {
+		return &resty.ResponseError{
+			Response: &resty.Response{Request: &resty.Request{}, RawResponse: &http.Response{StatusCode: code}},
+		}
+	}
+
+	tests := []struct {
+		name        string
+		err         error
+		unavailable bool
+		rejected    bool
+	}{
+		{name: "connection refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("refused")}, unavailable: true},
+		{name: "bad gateway", err: response(http.StatusBadGateway), unavailable: true},
+		{name: "too many requests", err: response(http.StatusTooManyRequests), unavailable: true},
+		{name: "bad request", err: response(http.StatusBadRequest), rejected: true},
+		{name: "forbidden", err: response(http.StatusForbidden), rejected: true},
+		{name: "internal error", err: response(http.StatusInternalServerError)},
+		{name: "grpc unavailable", err: status.Error(codes.Unavailable, "down"), unavailable: true},
+		{name: "grpc invalid argument", err: status.Error(codes.InvalidArgument, "bad hash"), rejected: true},
+		{name: "other error", err: errors.New("some other error")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.unavailable, IsUnavailableError(tt.err))
+			assert.Equal(t, tt.rejected, IsRejectedError(tt.err))
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/collectors"
+)
+
+// scheduledCollector a collector with its poll interval.
+type scheduledCollector struct {
+	collector    collectors.Collector
+	pollInterval time.Duration
+}
+
+// scheduleCollectors returns the enabled collectors of the registry with their poll intervals,
+// collectors without an interval in the configuration use pollInterval.
+func scheduleCollectors(
+	registry *collectors.Registry,
+	cfg *CollectorConfig,
+	pollInterval time.Duration,
+) ([]scheduledCollector, error) {
+	intervals, err := cfg.PollIntervals()
+	if err != nil {
+		return nil, err
+	}
+	for name := range intervals {
+		if _, ok := registry.Get(name); !ok {
+			return nil, fmt.Errorf("collector interval of unknown collector `%s`", name)
+		}
+	}
+
+	enabled, err := registry.Enabled(cfg.DisabledCollectors)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduled := make([]scheduledCollector, 0, len(enabled))
+	for _, c := range enabled {
+		interval, ok := intervals[c.Name()]
+		if !ok {
+			interval = pollInterval
+		}
+		scheduled = append(scheduled, scheduledCollector{collector: c, pollInterval: interval})
+	}
+	return scheduled, nil
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/collectors"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type namedCollector string
+
+func (c namedCollector) Name() string {
+	return string(c)
+}
+
+func (c namedCollector) Collect(ctx context.Context) ([]metrics.Metrics, error) {
+	return nil, nil
+}
+
+func TestScheduleCollectors(t *testing.T) {
+	registry := collectors.NewRegistry()
+	for _, name := range []string{"poll", "runtime", "gopsutil"} {
+		require.NoError(t, registry.Register(namedCollector(name)))
+	}
+
+	scheduled, err := scheduleCollectors(registry, &CollectorConfig{
+		DisabledCollectors: []string{"runtime"},
+		CollectorIntervals: []string{"gopsutil=30s"},
+	}, 2*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, []scheduledCollector{
+		{collector: namedCollector("gopsutil"), pollInterval: 30 * time.Second},
+		{collector: namedCollector("poll"), pollInterval: 2 * time.Second},
+	}, scheduled)
+
+	_, err = scheduleCollectors(registry, &CollectorConfig{DisabledCollectors: []string{"disk"}}, time.Second)
+	assert.Error(t, err)
+
+	_, err = scheduleCollectors(registry, &CollectorConfig{CollectorIntervals: []string{"disk=1s"}}, time.Second)
+	assert.Error(t, err)
+}
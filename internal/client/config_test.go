@@ -2,11 +2,13 @@ package client_test
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/screamsoul/go-metrics-tpl/internal/client"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestConfig(t *testing.T) {
@@ -95,3 +97,74 @@ func TestBackoffIntervalConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+address: localhost:9090
+report_interval: 5
+poll_interval: 1
+rate_limit: 3
+spool_max_age: 1h
+backoff_intervals: [1s, 2s]
+`), 0o600))
+
+	os.Args = []string{"agent", "--config", path, "-r", "7"}
+	defer func() { os.Args = nil }()
+	t.Setenv("RATE_LIMIT", "2")
+
+	cfg, err := client.NewConfig()
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://localhost:9090", cfg.GetServerURL())
+	assert.Equal(t, 7, cfg.ReportInterval)
+	assert.Equal(t, 1, cfg.PollInterval)
+	assert.Equal(t, 2, cfg.RateLimit)
+	assert.Equal(t, time.Hour, cfg.SpoolMaxAge)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, cfg.BackoffIntervals)
+}
+
+func TestConfigGRPCWithCryptoKey(t *testing.T) {
+	os.Args = nil
+	t.Setenv("GRPC_ADDRESS", "localhost:3200")
+	t.Setenv("CRYPTO_KEY", "/tmp/public.pem")
+
+	_, err := client.NewConfig()
+	assert.Error(t, err)
+}
+
+func TestCollectorPollIntervals(t *testing.T) {
+	cfg := client.CollectorConfig{CollectorIntervals: []string{"gopsutil=30s", "runtime=500ms"}}
+	intervals, err := cfg.PollIntervals()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{"gopsutil": 30 * time.Second, "runtime": 500 * time.Millisecond}, intervals)
+
+	for _, bad := range []string{"gopsutil", "=1s", "gopsutil=fast", "gopsutil=0s"} {
+		cfg := client.CollectorConfig{CollectorIntervals: []string{bad}}
+		_, err := cfg.PollIntervals()
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestCollectorOptions(t *testing.T) {
+	cfg := client.CollectorConfig{NetInterfacesExclude: []string{"lo", "veth*"}, DiskDevices: []string{"sd*"}}
+	opts, err := cfg.Options()
+	require.NoError(t, err)
+	assert.False(t, opts.Interfaces.Match("veth0"))
+	assert.True(t, opts.Interfaces.Match("eth0"))
+	assert.False(t, opts.Devices.Match("nvme0n1"))
+
+	cfg = client.CollectorConfig{DiskDevicesExclude: []string{"["}}
+	_, err = cfg.Options()
+	assert.Error(t, err)
+
+	cfg = client.CollectorConfig{WatchProcesses: []string{"nginx=name:nginx", "api=pidfile:/run/api.pid"}}
+	opts, err = cfg.Options()
+	require.NoError(t, err)
+	require.Len(t, opts.Processes, 2)
+	assert.Equal(t, "api", opts.Processes[1].Label)
+
+	cfg = client.CollectorConfig{WatchProcesses: []string{"nginx=name:nginx", "nginx=name:nginx-debug"}}
+	_, err = cfg.Options()
+	assert.ErrorContains(t, err, "duplicate")
+}
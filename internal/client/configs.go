@@ -1,11 +1,14 @@
 package client
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/alexflint/go-arg"
+	"github.com/screamsoul/go-metrics-tpl/internal/collectors"
+	"github.com/screamsoul/go-metrics-tpl/pkg/configfile"
 )
 
 type Server struct {
@@ -14,10 +17,89 @@ type Server struct {
 	BackoffIntervals []time.Duration `arg:"--b-intervals,env:BACKOFF_INTERVALS" help:"Интервалы повтора запроса (default=1s,3s,5s)"`
 	BackoffRetries   bool            `arg:"--backoff,env:BACKOFF_RETRIES" default:"true" help:"Повтор запроса при разрыве соединения"`
 	HashBodyKey      string          `arg:"-k,env:KEY" default:"" help:"hash key"`
+	GRPCAddress      string          `arg:"--grpc-address,env:GRPC_ADDRESS" default:"" help:"Адрес и порт gRPC сервера, при указании метрики отправляются по gRPC вместо HTTP"`
+	CryptoKey        string          `arg:"--crypto-key,env:CRYPTO_KEY" default:"" help:"Путь к файлу с публичным RSA ключом сервера для шифрования метрик (несовместим с --grpc-address)"`
+}
+
+type SpoolConfig struct {
+	SpoolDir     string        `arg:"--spool-dir,env:SPOOL_DIR" default:"" help:"directory of the on-disk buffer of unsent metrics (empty disables)"`
+	SpoolMaxSize int64         `arg:"--spool-max-size,env:SPOOL_MAX_SIZE" default:"67108864" help:"max total size of the buffered metrics in bytes"`
+	SpoolMaxAge  time.Duration `arg:"--spool-max-age,env:SPOOL_MAX_AGE" default:"24h" help:"max age of the buffered metrics"`
+}
+
+type PushConfig struct {
+	PushAddress string `arg:"--push-address,env:PUSH_ADDRESS" default:"" help:"local address accepting application metrics on /update/ and /updates/, e.g. 127.0.0.1:8081 (empty disables)"`
+	PushSocket  string `arg:"--push-socket,env:PUSH_SOCKET" default:"" help:"unix socket accepting application metrics on /update/ and /updates/ (empty disables)"`
+}
+
+// Enabled reports whether any push listener is configured.
+func (c *PushConfig) Enabled() bool {
+	return c.PushAddress != "" || c.PushSocket != ""
+}
+
+type CollectorConfig struct {
+	DisabledCollectors   []string `arg:"--disable-collectors,env:DISABLE_COLLECTORS" help:"names of the collectors not to poll: poll, runtime, gopsutil, disk, diskio, net, load, fd, process, cgroup or a custom one"`
+	CollectorIntervals   []string `arg:"--collector-intervals,env:COLLECTOR_INTERVALS" help:"poll intervals of collectors as name=duration, e.g. gopsutil=30s, other collectors use the poll interval"`
+	DiskDevices          []string `arg:"--disk-devices,env:DISK_DEVICES" help:"glob patterns of disk devices to report, e.g. sd*, all devices by default"`
+	DiskDevicesExclude   []string `arg:"--disk-devices-exclude,env:DISK_DEVICES_EXCLUDE" help:"glob patterns of disk devices not to report, e.g. loop*"`
+	NetInterfaces        []string `arg:"--net-interfaces,env:NET_INTERFACES" help:"glob patterns of network interfaces to report, e.g. eth*, all interfaces by default"`
+	NetInterfacesExclude []string `arg:"--net-interfaces-exclude,env:NET_INTERFACES_EXCLUDE" help:"glob patterns of network interfaces not to report, e.g. lo,veth*"`
+	WatchProcesses       []string `arg:"--watch-processes,env:WATCH_PROCESSES" help:"processes to report as label=kind:value, kind is name, pidfile or cmdline (regex), e.g. nginx=name:nginx"`
+	CgroupRoot           string   `arg:"--cgroup-root,env:CGROUP_ROOT" default:"/sys/fs/cgroup" help:"cgroup v2 group reported by the cgroup collector"`
+}
+
+// Options returns the options of the built-in collectors.
+func (c *CollectorConfig) Options() (opts collectors.Options, err error) {
+	if opts.Devices, err = collectors.NewFilter(c.DiskDevices, c.DiskDevicesExclude); err != nil {
+		return opts, fmt.Errorf("disk devices: %w", err)
+	}
+	if opts.Interfaces, err = collectors.NewFilter(c.NetInterfaces, c.NetInterfacesExclude); err != nil {
+		return opts, fmt.Errorf("net interfaces: %w", err)
+	}
+
+	opts.CgroupRoot = c.CgroupRoot
+
+	labels := make(map[string]bool, len(c.WatchProcesses))
+	for _, item := range c.WatchProcesses {
+		w, err := collectors.ParseProcessWatch(item)
+		if err != nil {
+			return opts, err
+		}
+		if labels[w.Label] {
+			return opts, fmt.Errorf("process watch `%s`: duplicate label `%s`", item, w.Label)
+		}
+		labels[w.Label] = true
+		opts.Processes = append(opts.Processes, w)
+	}
+	return opts, nil
+}
+
+// PollIntervals returns the poll intervals of collectors by name.
+func (c *CollectorConfig) PollIntervals() (map[string]time.Duration, error) {
+	intervals := make(map[string]time.Duration, len(c.CollectorIntervals))
+	for _, item := range c.CollectorIntervals {
+		name, value, ok := strings.Cut(item, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("collector interval `%s`: expected name=duration", item)
+		}
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("collector interval `%s`: %w", item, err)
+		}
+		if interval <= 0 {
+			return nil, fmt.Errorf("collector interval `%s`: must be positive", item)
+		}
+		intervals[name] = interval
+	}
+	return intervals, nil
 }
 
 type Config struct {
 	Server
+	SpoolConfig
+	CollectorConfig
+	PushConfig
+	ConfigFile     string `arg:"-c,--config,env:CONFIG" default:"" help:"Путь к файлу конфигурации в формате JSON или YAML (ключи - имена переменных окружения в нижнем регистре)"`
 	RateLimit      int    `arg:"-l,env:RATE_LIMIT" default:"1" help:"the number of simultaneous outgoing requests to the server"`
 	ReportInterval int    `arg:"-r,env:REPORT_INTERVAL" default:"10" help:"the frequency of sending metrics to the server"`
 	PollInterval   int    `arg:"-p,env:POLL_INTERVAL" default:"2" help:"the frequency of polling metrics from the runtime package"`
@@ -38,6 +120,12 @@ func NewConfig() (*Config, error) {
 
 	arg.MustParse(&cfg)
 
+	if cfg.ConfigFile != "" {
+		if err := configfile.Load(&cfg, cfg.ConfigFile, configfile.Args()); err != nil {
+			return nil, err
+		}
+	}
+
 	if cfg.Server.BackoffIntervals == nil && cfg.Server.BackoffRetries {
 		cfg.Server.BackoffIntervals = []time.Duration{1 * time.Second, 3 * time.Second, 5 * time.Second}
 	} else if !cfg.Server.BackoffRetries {
@@ -48,5 +136,17 @@ func NewConfig() (*Config, error) {
 		cfg.RateLimit = 1
 	}
 
+	if _, err := cfg.PollIntervals(); err != nil {
+		return nil, err
+	}
+	if _, err := cfg.Options(); err != nil {
+		return nil, err
+	}
+
+	// the grpc transport sends metrics unencrypted, so the key would silently be ignored.
+	if cfg.GRPCAddress != "" && cfg.CryptoKey != "" {
+		return nil, errors.New("crypto key is not supported by the grpc transport")
+	}
+
 	return &cfg, nil
 }
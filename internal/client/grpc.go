@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"net"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/pb"
+	"github.com/screamsoul/go-metrics-tpl/pkg/logging"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+)
+
+// GRPCMetricsClient reports metrics to the gRPC API of the server.
+type GRPCMetricsClient struct {
+	conn    *grpc.ClientConn
+	client  pb.MetricsClient
+	logger  *zap.Logger
+	address string
+}
+
+// NewRealIPUnaryInterceptor sends the agent address, which the server checks against the trusted subnet.
+func NewRealIPUnaryInterceptor(ip net.IP) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, pb.RealIPMetadataKey, ip.String())
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// NewHashUnaryInterceptor sends the hash of the request signed with the key.
+func NewHashUnaryInterceptor(hashKey string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if msg, ok := req.(proto.Message); ok {
+			hash, err := pb.HashSum(msg, hashKey)
+			if err != nil {
+				return err
+			}
+			ctx = metadata.AppendToOutgoingContext(ctx, pb.HashMetadataKey, hash)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func NewGRPCMetricsClient(compressRequest bool, hashKey string, address string) (*GRPCMetricsClient, error) {
+	logger := logging.GetLogger()
+
+	var interceptors []grpc.UnaryClientInterceptor
+
+	if ip, err := OutboundIP(address); err == nil {
+		interceptors = append(interceptors, NewRealIPUnaryInterceptor(ip))
+	} else {
+		logger.Warn("outbound address is unknown, x-real-ip is not sent", zap.Error(err))
+	}
+
+	if hashKey != "" {
+		interceptors = append(interceptors, NewHashUnaryInterceptor(hashKey))
+	}
+
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(interceptors...),
+	}
+
+	if compressRequest {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.UseCompressor(gzip.Name)))
+	}
+
+	conn, err := grpc.NewClient(address, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GRPCMetricsClient{
+		conn:    conn,
+		client:  pb.NewMetricsClient(conn),
+		logger:  logger,
+		address: address,
+	}, nil
+}
+
+func (client *GRPCMetricsClient) SendMetric(ctx context.Context, metricsList []metrics.Metrics) error {
+	_, err := client.client.UpdateMetrics(ctx, &pb.UpdateMetricsRequest{Metrics: pb.FromMetrics(metricsList)})
+	if err != nil {
+		client.logger.Error("send error", zap.Error(err))
+		return err
+	}
+
+	client.logger.Info(
+		"send metric", zap.Any("metric", metricsList), zap.String("address", client.address),
+	)
+	return nil
+}
+
+// Close closes the connection to the server.
+func (client *GRPCMetricsClient) Close() error {
+	return client.conn.Close()
+}
@@ -0,0 +1,70 @@
+package client_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/client"
+	"github.com/screamsoul/go-metrics-tpl/internal/grpcserver"
+	"github.com/screamsoul/go-metrics-tpl/internal/middlewares"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Compressed and signed batch from a trusted agent is accepted by the grpc server
+func TestGRPCSendMetric_Success(t *testing.T) {
+	storage := memory.NewMemStorage()
+
+	server, err := grpcserver.NewServer(storage, middlewares.NewHashKey("testKey"), []string{"127.0.0.0/8"})
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Stop()
+
+	grpcClient, err := client.NewGRPCMetricsClient(true, "testKey", listener.Addr().String())
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, grpcClient.Close())
+	}()
+
+	delta := int64(5)
+	metricsList := []metrics.Metrics{
+		{ID: "metric1", MType: metrics.Gauge, Value: new(float64)},
+		{ID: "metric2", MType: metrics.Counter, Delta: &delta},
+	}
+
+	require.NoError(t, grpcClient.SendMetric(context.Background(), metricsList))
+
+	metric := metrics.Metrics{ID: "metric2", MType: metrics.Counter}
+	require.NoError(t, storage.Get(context.Background(), &metric))
+	assert.Equal(t, delta, *metric.Delta)
+}
+
+// Batch signed with another key is rejected
+func TestGRPCSendMetric_WrongKey(t *testing.T) {
+	server, err := grpcserver.NewServer(memory.NewMemStorage(), middlewares.NewHashKey("testKey"), nil)
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Stop()
+
+	grpcClient, err := client.NewGRPCMetricsClient(false, "otherKey", listener.Addr().String())
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, grpcClient.Close())
+	}()
+
+	err = grpcClient.SendMetric(context.Background(), []metrics.Metrics{{ID: "metric1", MType: metrics.Gauge, Value: new(float64)}})
+	assert.Error(t, err)
+}
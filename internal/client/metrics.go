@@ -4,13 +4,19 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/rsa"
 	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net"
+	"net/url"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/pkg/encryption"
 	"github.com/screamsoul/go-metrics-tpl/pkg/logging"
+	"github.com/screamsoul/go-metrics-tpl/pkg/utils"
 	"go.uber.org/zap"
 )
 
@@ -63,13 +69,70 @@ func NewHashSumHeaderMiddleware(hashKey string) func(c *resty.Client, r *resty.R
 
 		dst := h.Sum(nil)
 
-		r.Header.Set("HashSHA256", string(dst))
+		r.Header.Set("HashSHA256", hex.EncodeToString(dst))
 
 		return nil
 	}
 }
 
-func NewMetricsClient(compressRequest bool, hashKey string, uploadURL string) *MetricsClient {
+// NewRealIPHeaderMiddleware sets the X-Real-IP header, which the server checks against the trusted subnet.
+func NewRealIPHeaderMiddleware(ip net.IP) func(c *resty.Client, r *resty.Request) error {
+	return func(c *resty.Client, r *resty.Request) error {
+		r.Header.Set("X-Real-IP", ip.String())
+
+		return nil
+	}
+}
+
+// OutboundIP returns the address of the interface used to reach the server at address (host:port).
+// No packets are sent: connecting a UDP socket only selects the route.
+func OutboundIP(address string) (net.IP, error) {
+	conn, err := net.Dial("udp", address)
+	if err != nil {
+		return nil, err
+	}
+	defer utils.CloseForse(conn)
+
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
+
+func outboundIPFromURL(uploadURL string) (net.IP, error) {
+	u, err := url.Parse(uploadURL)
+	if err != nil {
+		return nil, err
+	}
+
+	address := u.Host
+	if u.Port() == "" {
+		address = net.JoinHostPort(u.Hostname(), "80")
+	}
+
+	return OutboundIP(address)
+}
+
+// NewEncryptBodyMiddleware encrypts the body with the server's public key.
+// It must be registered last, so that the server decrypts the body before checking it.
+func NewEncryptBodyMiddleware(publicKey *rsa.PublicKey) func(c *resty.Client, r *resty.Request) error {
+	return func(c *resty.Client, r *resty.Request) error {
+		bodyBytes, ok := r.Body.([]byte)
+		if !ok {
+			return fmt.Errorf("body is not of type []byte")
+		}
+
+		encrypted, err := encryption.Encrypt(publicKey, bodyBytes)
+		if err != nil {
+			return err
+		}
+
+		r.Body = encrypted
+
+		r.Header.Set(encryption.HeaderName, encryption.Scheme)
+
+		return nil
+	}
+}
+
+func NewMetricsClient(compressRequest bool, hashKey string, publicKey *rsa.PublicKey, uploadURL string) *MetricsClient {
 
 	client := &MetricsClient{
 		*resty.New(),
@@ -77,6 +140,12 @@ func NewMetricsClient(compressRequest bool, hashKey string, uploadURL string) *M
 		uploadURL,
 	}
 
+	if ip, err := outboundIPFromURL(uploadURL); err == nil {
+		client.OnBeforeRequest(NewRealIPHeaderMiddleware(ip))
+	} else {
+		client.logger.Warn("outbound address is unknown, X-Real-IP is not sent", zap.Error(err))
+	}
+
 	if compressRequest {
 		client.OnBeforeRequest(NewGzipCompressBodyMiddleware())
 	}
@@ -85,6 +154,10 @@ func NewMetricsClient(compressRequest bool, hashKey string, uploadURL string) *M
 		client.OnBeforeRequest(NewHashSumHeaderMiddleware(hashKey))
 	}
 
+	if publicKey != nil {
+		client.OnBeforeRequest(NewEncryptBodyMiddleware(publicKey))
+	}
+
 	return client
 }
 
@@ -94,7 +167,7 @@ func (client *MetricsClient) SendMetric(ctx context.Context, metricsList []metri
 		panic(err)
 	}
 
-	resp, err := resty.New().R().
+	resp, err := client.R().
 		SetContext(ctx).
 		SetHeader("Content-Type", "application/json").
 		SetBody(jsonData).
@@ -105,8 +178,16 @@ func (client *MetricsClient) SendMetric(ctx context.Context, metricsList []metri
 		return err
 	}
 
+	if resp.IsError() {
+		// the batch is not accepted, so counter deltas must not be acknowledged.
+		return &resty.ResponseError{
+			Response: resp,
+			Err:      fmt.Errorf("unexpected response status: %s", resp.Status()),
+		}
+	}
+
 	client.logger.Info(
-		"send metric", zap.Any("metric", resp.Request.Body), zap.String("url", client.uploadURL),
+		"send metric", zap.Any("metric", metricsList), zap.String("url", client.uploadURL),
 	)
 	return nil
 }
@@ -5,7 +5,11 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
 	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -13,8 +17,10 @@ import (
 
 	"github.com/go-resty/resty/v2"
 	"github.com/screamsoul/go-metrics-tpl/internal/client"
+	"github.com/screamsoul/go-metrics-tpl/internal/middlewares"
 	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewGzipCompressBodyMiddleware_CompressesValidBody(t *testing.T) {
@@ -48,7 +54,7 @@ func TestCorrectlySetsHashSHA256Header(t *testing.T) {
 
 	err := middleware(nil, req)
 	assert.NoError(t, err)
-	assert.Equal(t, string(expectedHashSum), req.Header.Get("HashSHA256"))
+	assert.Equal(t, hex.EncodeToString(expectedHashSum), req.Header.Get("HashSHA256"))
 }
 
 // Successfully sends a list of metrics to the specified upload URL
@@ -61,7 +67,7 @@ func TestSendMetric_Success(t *testing.T) {
 
 	// Create a MetricsClient instance
 	client := client.NewMetricsClient(
-		false, "", server.URL,
+		false, "", nil, server.URL,
 	)
 
 	// Create a context
@@ -79,3 +85,53 @@ func TestSendMetric_Success(t *testing.T) {
 	// Assert no error occurred
 	assert.NoError(t, err)
 }
+
+// Compressed, signed and encrypted batch is accepted by the server middlewares
+func TestSendMetric_Encrypted(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	var received []metrics.Metrics
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+	})
+
+	server := httptest.NewServer(
+		middlewares.NewDecryptMiddleware(privateKey)(
+			middlewares.NewHashSumHeaderMiddleware("testKey")(
+				middlewares.GzipDecompressMiddleware(handler),
+			),
+		),
+	)
+	defer server.Close()
+
+	client := client.NewMetricsClient(
+		true, "testKey", &privateKey.PublicKey, server.URL,
+	)
+
+	metricsList := []metrics.Metrics{
+		{ID: "metric1", MType: "gauge", Value: new(float64)},
+		{ID: "metric2", MType: "counter", Delta: new(int64)},
+	}
+
+	assert.NoError(t, client.SendMetric(context.Background(), metricsList))
+	assert.Equal(t, metricsList, received)
+}
+
+// The agent address is sent in the X-Real-IP header
+func TestSendMetric_SetsRealIP(t *testing.T) {
+	var realIP string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		realIP = r.Header.Get("X-Real-IP")
+	}))
+	defer server.Close()
+
+	client := client.NewMetricsClient(
+		false, "", nil, server.URL,
+	)
+
+	assert.NoError(t, client.SendMetric(context.Background(), []metrics.Metrics{}))
+	assert.Equal(t, "127.0.0.1", realIP)
+}
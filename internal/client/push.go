@@ -0,0 +1,168 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/screamsoul/go-metrics-tpl/internal/middlewares"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"github.com/screamsoul/go-metrics-tpl/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// pushShutdownTimeout the time given to in-flight push requests on shutdown.
+const pushShutdownTimeout = 5 * time.Second
+
+// PushServer a local endpoint for applications on the host: it accepts metrics in the JSON
+// format of the server on POST /update/ and /updates/ and adds them to the agent storage,
+// so they are sent with the agent batches, signing, compression and retries.
+//
+// Counters and histograms are added to the values collected before, gauges replace them.
+type PushServer struct {
+	store     repositories.CollectionMetric
+	logger    *zap.Logger
+	server    *http.Server
+	listeners []net.Listener
+	socket    string
+}
+
+func NewPushServer(store repositories.CollectionMetric) *PushServer {
+	s := &PushServer{
+		store:  store,
+		logger: logging.GetLogger(),
+	}
+
+	router := chi.NewRouter()
+	router.Use(middlewares.GzipDecompressMiddleware)
+	router.Post("/update/", s.UpdateMetric)
+	router.Post("/updates/", s.UpdateMetricBulk)
+
+	s.server = &http.Server{Handler: router}
+	return s
+}
+
+// Listen binds the TCP address and the Unix socket, an empty value disables the listener.
+// A socket file left by a previous run is removed.
+func (s *PushServer) Listen(address, socket string) error {
+	if address != "" {
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			return err
+		}
+		s.listeners = append(s.listeners, listener)
+	}
+
+	if socket != "" {
+		if info, err := os.Lstat(socket); err == nil && info.Mode().Type() == fs.ModeSocket {
+			_ = os.Remove(socket)
+		}
+		listener, err := net.Listen("unix", socket)
+		if err != nil {
+			s.close()
+			return err
+		}
+		s.listeners = append(s.listeners, listener)
+		s.socket = socket
+	}
+
+	return nil
+}
+
+// Addrs returns the bound addresses.
+func (s *PushServer) Addrs() []net.Addr {
+	addrs := make([]net.Addr, 0, len(s.listeners))
+	for _, l := range s.listeners {
+		addrs = append(addrs, l.Addr())
+	}
+	return addrs
+}
+
+// Run serves the bound listeners until the context is done, then waits for in-flight requests.
+func (s *PushServer) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, l := range s.listeners {
+		wg.Add(1)
+		go func(l net.Listener) {
+			defer wg.Done()
+			if err := s.server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				s.logger.Error("push server error", zap.String("address", l.Addr().String()), zap.Error(err))
+			}
+		}(l)
+	}
+
+	<-ctx.Done()
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), pushShutdownTimeout)
+	defer cancel()
+	if err := s.server.Shutdown(shutdownCtx); err != nil {
+		s.logger.Error("push server shutdown error", zap.Error(err))
+	}
+	wg.Wait()
+
+	if s.socket != "" {
+		_ = os.Remove(s.socket)
+	}
+}
+
+func (s *PushServer) close() {
+	for _, l := range s.listeners {
+		_ = l.Close()
+	}
+	s.listeners = nil
+}
+
+// UpdateMetric handler, adds one metric.
+func (s *PushServer) UpdateMetric(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "content type must be application/json", http.StatusBadRequest)
+		return
+	}
+
+	var m metrics.Metrics
+	if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.add(w, r, []metrics.Metrics{m})
+}
+
+// UpdateMetricBulk handler, adds the list of metrics, an invalid metric rejects the whole list.
+func (s *PushServer) UpdateMetricBulk(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		http.Error(w, "content type must be application/json", http.StatusBadRequest)
+		return
+	}
+
+	var batch []metrics.Metrics
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s.add(w, r, batch)
+}
+
+func (s *PushServer) add(w http.ResponseWriter, r *http.Request, batch []metrics.Metrics) {
+	for _, m := range batch {
+		if err := m.ValidateValue(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// the in-memory storage fails only on a histogram with bounds other than the collected ones.
+	if err := s.store.BulkAdd(r.Context(), batch); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
@@ -0,0 +1,102 @@
+package client
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pushRequest(t *testing.T, client *http.Client, url, body string, compress bool) int {
+	t.Helper()
+
+	data := []byte(body)
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write(data)
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+		data = buf.Bytes()
+	}
+
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+	if compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	res, err := client.Do(req)
+	require.NoError(t, err)
+	res.Body.Close()
+	return res.StatusCode
+}
+
+func TestPushServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// the pushed counter is added to the collected one.
+	delta := int64(2)
+	store := memory.NewCollectionMetricStorage()
+	require.NoError(t, store.Add(ctx, metrics.Metrics{ID: "jobs", MType: metrics.Counter, Delta: &delta}))
+
+	socket := filepath.Join(t.TempDir(), "push.sock")
+	server := NewPushServer(store)
+	require.NoError(t, server.Listen("127.0.0.1:0", socket))
+	require.Len(t, server.Addrs(), 2)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.Run(ctx)
+	}()
+
+	tcpURL := "http://" + server.Addrs()[0].String()
+	socketClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+
+	assert.Equal(t, http.StatusOK, pushRequest(t, http.DefaultClient, tcpURL+"/update/",
+		`{"id":"jobs","type":"counter","delta":3}`, false))
+	assert.Equal(t, http.StatusOK, pushRequest(t, socketClient, "http://agent/updates/",
+		`[{"id":"queue","type":"gauge","value":7.5},`+
+			`{"id":"latency","type":"histogram","histogram":{"bounds":[1],"counts":[1,0],"sum":0.5,"count":1}}]`, false))
+	assert.Equal(t, http.StatusOK, pushRequest(t, http.DefaultClient, tcpURL+"/updates/",
+		`[{"id":"jobs","type":"counter","delta":1,"labels":{"queue":"mail"}}]`, true))
+
+	// an invalid metric rejects the whole list.
+	assert.Equal(t, http.StatusBadRequest, pushRequest(t, http.DefaultClient, tcpURL+"/updates/",
+		`[{"id":"lost","type":"gauge","value":1},{"id":"jobs","type":"counter"}]`, false))
+	assert.Equal(t, http.StatusBadRequest, pushRequest(t, http.DefaultClient, tcpURL+"/update/",
+		`{"id":"jobs","type":"counter","delta":1`, false))
+
+	batch, err := store.Reserve(ctx)
+	require.NoError(t, err)
+
+	got := make(map[string]metrics.Metrics, len(batch))
+	for _, m := range batch {
+		got[m.ID+m.Labels.String()] = m
+	}
+	require.Len(t, got, 4)
+	assert.Equal(t, int64(5), *got["jobs"].Delta)
+	assert.Equal(t, int64(1), *got["jobs"+metrics.Labels{"queue": "mail"}.String()].Delta)
+	assert.Equal(t, 7.5, *got["queue"].Value)
+	assert.Equal(t, []uint64{1, 0}, got["latency"].Histogram.Counts)
+
+	cancel()
+	<-done
+	_, err = os.Stat(socket)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
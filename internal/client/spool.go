@@ -0,0 +1,225 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/pkg/logging"
+	"go.uber.org/zap"
+)
+
+const spoolFileExt = ".json"
+
+// spoolFile a batch persisted in the spool directory.
+type spoolFile struct {
+	seq  uint64
+	size int64
+}
+
+// Spool an on-disk buffer of metric batches which were not delivered to the server.
+//
+// Batches are stored one per file and replayed in the order they were pushed.
+// The spool is bounded by the total size of files and the age of a batch:
+// the oldest batches are dropped when the size is exceeded, expired batches are dropped on replay.
+type Spool struct {
+	// replayMu serialises replays, so concurrent senders do not send spooled batches out of order.
+	replayMu sync.Mutex
+	// mu guards the files, it is not held while a batch is sent.
+	mu       sync.Mutex
+	dir      string
+	maxBytes int64
+	maxAge   time.Duration
+	files    []spoolFile
+	size     int64
+	nextSeq  uint64
+	logger   *zap.Logger
+}
+
+// NewSpool opens the spool directory, creating it if needed, and loads batches left by the previous run.
+func NewSpool(dir string, maxBytes int64, maxAge time.Duration) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	spool := &Spool{
+		dir:      dir,
+		maxBytes: maxBytes,
+		maxAge:   maxAge,
+		logger:   logging.GetLogger(),
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, spoolFileExt) {
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, spoolFileExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		spool.files = append(spool.files, spoolFile{seq: seq, size: info.Size()})
+		spool.size += info.Size()
+	}
+
+	sort.Slice(spool.files, func(i, j int) bool { return spool.files[i].seq < spool.files[j].seq })
+	if n := len(spool.files); n > 0 {
+		spool.nextSeq = spool.files[n-1].seq + 1
+	}
+
+	return spool, nil
+}
+
+func (s *Spool) path(seq uint64) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%020d%s", seq, spoolFileExt))
+}
+
+// Len returns the number of batches in the spool.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.files)
+}
+
+// Push persists the batch at the end of the spool, dropping the oldest batches if the size is exceeded.
+func (s *Spool) Push(batch []metrics.Metrics) error {
+	data, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxBytes > 0 && int64(len(data)) > s.maxBytes {
+		return fmt.Errorf("batch of %d bytes exceeds the spool size", len(data))
+	}
+
+	seq := s.nextSeq
+	tmp := s.path(seq) + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.path(seq)); err != nil {
+		return err
+	}
+
+	s.nextSeq++
+	s.files = append(s.files, spoolFile{seq: seq, size: int64(len(data))})
+	s.size += int64(len(data))
+
+	for s.maxBytes > 0 && s.size > s.maxBytes {
+		s.logger.Warn("spool is full, drop the oldest batch", zap.Uint64("seq", s.files[0].seq))
+		s.removeFirst()
+	}
+
+	return nil
+}
+
+// removeFirst deletes the oldest batch, must be called with the lock held.
+func (s *Spool) removeFirst() {
+	file := s.files[0]
+	if err := os.Remove(s.path(file.seq)); err != nil && !os.IsNotExist(err) {
+		s.logger.Error("remove spool file error", zap.Error(err))
+	}
+	s.files = s.files[1:]
+	s.size -= file.size
+}
+
+// removeSeq deletes the batch if it is still the oldest one, must be called with the lock held.
+// The batch is gone if it was dropped by Push while being sent.
+func (s *Spool) removeSeq(seq uint64) {
+	if len(s.files) > 0 && s.files[0].seq == seq {
+		s.removeFirst()
+	}
+}
+
+// Replay sends spooled batches in order, removing each one after it is sent.
+// A batch rejected by the server (see IsRejectedError) is dropped, otherwise Replay stops
+// at the first failed batch, which stays at the head of the spool.
+// Concurrent replays wait for each other, batches may be pushed while a batch is sent.
+func (s *Spool) Replay(ctx context.Context, send func(batch []metrics.Metrics) error) error {
+	s.replayMu.Lock()
+	defer s.replayMu.Unlock()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batch, seq, ok, err := s.head()
+		if err != nil || !ok {
+			return err
+		}
+
+		err = send(batch)
+		if err != nil && !IsRejectedError(err) {
+			return err
+		}
+		if err != nil {
+			s.logger.Error("spooled batch is rejected by the server, drop it", zap.Uint64("seq", seq), zap.Error(err))
+		}
+
+		s.mu.Lock()
+		s.removeSeq(seq)
+		s.mu.Unlock()
+	}
+}
+
+// head returns the oldest batch and its sequence number, false if the spool is empty.
+// Lost, expired and corrupted batches are dropped.
+func (s *Spool) head() ([]metrics.Metrics, uint64, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.files) > 0 {
+		seq := s.files[0].seq
+		path := s.path(seq)
+
+		info, err := os.Stat(path)
+		if err != nil {
+			s.logger.Error("spool file is lost", zap.String("path", path), zap.Error(err))
+			s.removeFirst()
+			continue
+		}
+		if s.maxAge > 0 && time.Since(info.ModTime()) > s.maxAge {
+			s.logger.Warn("drop expired spool batch", zap.String("path", path))
+			s.removeFirst()
+			continue
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, 0, false, err
+		}
+
+		var batch []metrics.Metrics
+		if err := json.Unmarshal(data, &batch); err != nil {
+			s.logger.Error("drop corrupted spool batch", zap.String("path", path), zap.Error(err))
+			s.removeFirst()
+			continue
+		}
+
+		return batch, seq, true, nil
+	}
+
+	return nil, 0, false, nil
+}
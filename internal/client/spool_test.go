@@ -0,0 +1,211 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func spoolBatch(id string) []metrics.Metrics {
+	delta := int64(1)
+	return []metrics.Metrics{{ID: id, MType: metrics.Counter, Delta: &delta}}
+}
+
+func collectReplay(t *testing.T, spool *Spool) []string {
+	var ids []string
+	err := spool.Replay(context.Background(), func(batch []metrics.Metrics) error {
+		ids = append(ids, batch[0].ID)
+		return nil
+	})
+	require.NoError(t, err)
+	return ids
+}
+
+func TestSpool_ReplayInOrder(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0, 0)
+	require.NoError(t, err)
+
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, spool.Push(spoolBatch(id)))
+	}
+	assert.Equal(t, 3, spool.Len())
+
+	assert.Equal(t, []string{"a", "b", "c"}, collectReplay(t, spool))
+	assert.Equal(t, 0, spool.Len())
+}
+
+func TestSpool_ReplayStopsAtFailure(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, spool.Push(spoolBatch("a")))
+	require.NoError(t, spool.Push(spoolBatch("b")))
+
+	errSend := errors.New("send error")
+	err = spool.Replay(context.Background(), func(batch []metrics.Metrics) error {
+		return errSend
+	})
+	assert.ErrorIs(t, err, errSend)
+	assert.Equal(t, 2, spool.Len())
+
+	assert.Equal(t, []string{"a", "b"}, collectReplay(t, spool))
+}
+
+func TestSpool_ReplayDropsRejectedBatch(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0, 0)
+	require.NoError(t, err)
+
+	require.NoError(t, spool.Push(spoolBatch("rejected")))
+	require.NoError(t, spool.Push(spoolBatch("b")))
+
+	// the rejected head does not block the batches behind it.
+	var ids []string
+	err = spool.Replay(context.Background(), func(batch []metrics.Metrics) error {
+		if batch[0].ID == "rejected" {
+			return status.Error(codes.InvalidArgument, "bad hash")
+		}
+		ids = append(ids, batch[0].ID)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, ids)
+	assert.Equal(t, 0, spool.Len())
+}
+
+func TestSpool_PushDuringReplay(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, spool.Push(spoolBatch("a")))
+
+	sending := make(chan struct{})
+	release := make(chan struct{})
+	replayed := make(chan []string)
+	go func() {
+		var ids []string
+		_ = spool.Replay(context.Background(), func(batch []metrics.Metrics) error {
+			if batch[0].ID == "a" {
+				close(sending)
+				<-release
+			}
+			ids = append(ids, batch[0].ID)
+			return nil
+		})
+		replayed <- ids
+	}()
+
+	// a batch is pushed while the replay is sending, the push does not wait for the send.
+	<-sending
+	pushed := make(chan error)
+	go func() { pushed <- spool.Push(spoolBatch("b")) }()
+	select {
+	case err := <-pushed:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("push is blocked by the replay")
+	}
+
+	// a concurrent replay waits for the running one, so the batches are sent once and in order.
+	second := make(chan int)
+	go func() {
+		var sent int
+		_ = spool.Replay(context.Background(), func(batch []metrics.Metrics) error {
+			sent++
+			return nil
+		})
+		second <- sent
+	}()
+
+	close(release)
+	assert.Equal(t, []string{"a", "b"}, <-replayed)
+	assert.Zero(t, <-second)
+	assert.Equal(t, 0, spool.Len())
+}
+
+func TestSpool_PushDropsBatchBeingSent(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, spool.Push(spoolBatch("a")))
+	require.NoError(t, spool.Push(spoolBatch("b")))
+
+	var ids []string
+	err = spool.Replay(context.Background(), func(batch []metrics.Metrics) error {
+		ids = append(ids, batch[0].ID)
+		if batch[0].ID == "a" {
+			// the spool is full, the batch being sent is dropped.
+			spool.mu.Lock()
+			spool.removeFirst()
+			spool.mu.Unlock()
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// the next batch is not removed instead of the dropped one.
+	assert.Equal(t, []string{"a", "b"}, ids)
+	assert.Equal(t, 0, spool.Len())
+}
+
+func TestSpool_DropsOldestWhenFull(t *testing.T) {
+	dir := t.TempDir()
+
+	probe, err := NewSpool(filepath.Join(dir, "probe"), 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, probe.Push(spoolBatch("a")))
+
+	spool, err := NewSpool(filepath.Join(dir, "spool"), 2*probe.size, 0)
+	require.NoError(t, err)
+
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, spool.Push(spoolBatch(id)))
+	}
+
+	assert.Equal(t, []string{"b", "c"}, collectReplay(t, spool))
+}
+
+func TestSpool_RejectsBatchBiggerThanSpool(t *testing.T) {
+	spool, err := NewSpool(t.TempDir(), 8, 0)
+	require.NoError(t, err)
+
+	assert.Error(t, spool.Push(spoolBatch("a")))
+	assert.Equal(t, 0, spool.Len())
+}
+
+func TestSpool_DropsExpiredBatches(t *testing.T) {
+	dir := t.TempDir()
+
+	spool, err := NewSpool(dir, 0, time.Hour)
+	require.NoError(t, err)
+
+	require.NoError(t, spool.Push(spoolBatch("old")))
+	require.NoError(t, spool.Push(spoolBatch("new")))
+
+	old := time.Now().Add(-2 * time.Hour)
+	require.NoError(t, os.Chtimes(spool.path(0), old, old))
+
+	assert.Equal(t, []string{"new"}, collectReplay(t, spool))
+}
+
+func TestSpool_LoadsExistingBatches(t *testing.T) {
+	dir := t.TempDir()
+
+	spool, err := NewSpool(dir, 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, spool.Push(spoolBatch("a")))
+	require.NoError(t, spool.Push(spoolBatch("b")))
+
+	reopened, err := NewSpool(dir, 0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, reopened.Len())
+
+	require.NoError(t, reopened.Push(spoolBatch("c")))
+	assert.Equal(t, []string{"a", "b", "c"}, collectReplay(t, reopened))
+}
@@ -0,0 +1,245 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+)
+
+// DefaultCgroupRoot the mount point of the cgroup v2 hierarchy, in a container it is the container cgroup.
+const DefaultCgroupRoot = "/sys/fs/cgroup"
+
+// CgroupCollector reports resource usage of the cgroup v2 group, e.g. the container the agent runs in:
+//
+//   - CgroupMemoryUsage, CgroupMemoryLimit and CgroupMemoryUsedPercent gauges from memory.current and memory.max;
+//   - CgroupCPUPercent and CgroupCPULimit (in CPUs) gauges, CgroupCPUUsageUsec, CgroupCPUUserUsec,
+//     CgroupCPUSystemUsec, CgroupCPUPeriods, CgroupCPUThrottledPeriods and CgroupCPUThrottledUsec
+//     counters from cpu.stat and cpu.max;
+//   - CgroupIOReadBytes, CgroupIOWriteBytes, CgroupIOReads and CgroupIOWrites counters
+//     with the device label (major:minor) from io.stat;
+//   - CgroupPids and CgroupPidsLimit gauges from pids.current and pids.max.
+//
+// Limits set to `max` are not reported. Files of controllers not enabled for the group are skipped,
+// nothing is reported if the root is not a cgroup v2 group.
+type CgroupCollector struct {
+	root   string
+	deltas counterDeltas
+
+	mu        sync.Mutex
+	lastUsage uint64
+	lastPoll  time.Time
+}
+
+func NewCgroupCollector(root string) *CgroupCollector {
+	if root == "" {
+		root = DefaultCgroupRoot
+	}
+	return &CgroupCollector{root: root}
+}
+
+func (c *CgroupCollector) Name() string {
+	return "cgroup"
+}
+
+func (c *CgroupCollector) Collect(ctx context.Context) ([]metrics.Metrics, error) {
+	// cgroup.controllers exists only in cgroup v2 groups.
+	if _, err := os.Stat(filepath.Join(c.root, "cgroup.controllers")); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	var (
+		result   []metrics.Metrics
+		counters []cumulativeCounter
+		errs     []error
+	)
+
+	memoryUsage, hasUsage, err := c.readValue("memory.current")
+	errs = append(errs, err)
+	memoryLimit, hasLimit, err := c.readValue("memory.max")
+	errs = append(errs, err)
+	if hasUsage {
+		result = append(result, gauge("CgroupMemoryUsage", float64(memoryUsage)))
+	}
+	if hasLimit {
+		result = append(result, gauge("CgroupMemoryLimit", float64(memoryLimit)))
+	}
+	if hasUsage && hasLimit && memoryLimit > 0 {
+		result = append(result, gauge("CgroupMemoryUsedPercent", float64(memoryUsage)/float64(memoryLimit)*100))
+	}
+
+	cpuStat, err := c.readKeyValues("cpu.stat")
+	errs = append(errs, err)
+	for key, name := range map[string]string{
+		"usage_usec":     "CgroupCPUUsageUsec",
+		"user_usec":      "CgroupCPUUserUsec",
+		"system_usec":    "CgroupCPUSystemUsec",
+		"nr_periods":     "CgroupCPUPeriods",
+		"nr_throttled":   "CgroupCPUThrottledPeriods",
+		"throttled_usec": "CgroupCPUThrottledUsec",
+	} {
+		if value, ok := cpuStat[key]; ok {
+			counters = append(counters, cumulativeCounter{name: name, value: value})
+		}
+	}
+	if usage, ok := cpuStat["usage_usec"]; ok {
+		if percent, ok := c.cpuPercent(usage); ok {
+			result = append(result, gauge("CgroupCPUPercent", percent))
+		}
+	}
+
+	cpuLimit, hasCPULimit, err := c.readCPUMax()
+	errs = append(errs, err)
+	if hasCPULimit {
+		result = append(result, gauge("CgroupCPULimit", cpuLimit))
+	}
+
+	ioCounters, err := c.readIOStat()
+	errs = append(errs, err)
+	counters = append(counters, ioCounters...)
+
+	pids, ok, err := c.readValue("pids.current")
+	errs = append(errs, err)
+	if ok {
+		result = append(result, gauge("CgroupPids", float64(pids)))
+	}
+	pidsLimit, ok, err := c.readValue("pids.max")
+	errs = append(errs, err)
+	if ok {
+		result = append(result, gauge("CgroupPidsLimit", float64(pidsLimit)))
+	}
+
+	return append(result, c.deltas.poll(counters)...), errors.Join(errs...)
+}
+
+// cpuPercent returns the CPU usage since the previous poll, 100 is one CPU.
+func (c *CgroupCollector) cpuPercent(usageUsec uint64) (float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	lastUsage, lastPoll := c.lastUsage, c.lastPoll
+	c.lastUsage, c.lastPoll = usageUsec, now
+
+	elapsed := now.Sub(lastPoll)
+	if lastPoll.IsZero() || elapsed <= 0 || usageUsec < lastUsage {
+		return 0, false
+	}
+	return float64(usageUsec-lastUsage) / float64(elapsed.Microseconds()) * 100, true
+}
+
+// read returns the content of the file, false if the file does not exist.
+func (c *CgroupCollector) read(name string) (string, bool, error) {
+	data, err := os.ReadFile(filepath.Join(c.root, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return strings.TrimSpace(string(data)), true, nil
+}
+
+// readValue reads a single value file, false if the file does not exist or the value is `max`.
+func (c *CgroupCollector) readValue(name string) (uint64, bool, error) {
+	data, ok, err := c.read(name)
+	if !ok || data == "max" {
+		return 0, false, err
+	}
+	value, err := strconv.ParseUint(data, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("%s: %w", name, err)
+	}
+	return value, true, nil
+}
+
+// readKeyValues reads a flat keyed file of `key value` lines.
+func (c *CgroupCollector) readKeyValues(name string) (map[string]uint64, error) {
+	data, ok, err := c.read(name)
+	if !ok {
+		return nil, err
+	}
+
+	values := make(map[string]uint64)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		values[fields[0]] = value
+	}
+	return values, nil
+}
+
+// readCPUMax reads the CPU limit `quota period` in CPUs, false if the quota is `max`.
+func (c *CgroupCollector) readCPUMax() (float64, bool, error) {
+	data, ok, err := c.read("cpu.max")
+	if !ok {
+		return 0, false, err
+	}
+
+	fields := strings.Fields(data)
+	if len(fields) != 2 {
+		return 0, false, fmt.Errorf("cpu.max: unexpected content `%s`", data)
+	}
+	if fields[0] == "max" {
+		return 0, false, nil
+	}
+	quota, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("cpu.max: %w", err)
+	}
+	period, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || period <= 0 {
+		return 0, false, fmt.Errorf("cpu.max: bad period `%s`", fields[1])
+	}
+	return quota / period, true, nil
+}
+
+// readIOStat reads io.stat lines `major:minor key=value...`.
+func (c *CgroupCollector) readIOStat() ([]cumulativeCounter, error) {
+	data, ok, err := c.read("io.stat")
+	if !ok || data == "" {
+		return nil, err
+	}
+
+	names := map[string]string{
+		"rbytes": "CgroupIOReadBytes",
+		"wbytes": "CgroupIOWriteBytes",
+		"rios":   "CgroupIOReads",
+		"wios":   "CgroupIOWrites",
+	}
+
+	var counters []cumulativeCounter
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		labels := metrics.Labels{"device": fields[0]}
+		for _, field := range fields[1:] {
+			key, raw, _ := strings.Cut(field, "=")
+			name, ok := names[key]
+			if !ok {
+				continue
+			}
+			value, err := strconv.ParseUint(raw, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("io.stat: %w", err)
+			}
+			counters = append(counters, cumulativeCounter{name: name, labels: labels, value: value})
+		}
+	}
+	return counters, nil
+}
@@ -0,0 +1,97 @@
+package collectors_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/collectors"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// copyFixture copies the fixture directory to a temporary one, so the test can change files.
+func copyFixture(t *testing.T, dir string) string {
+	t.Helper()
+
+	root := t.TempDir()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, e := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(root, e.Name()), data, 0o600))
+	}
+	return root
+}
+
+func byKey(batch []metrics.Metrics) map[string]metrics.Metrics {
+	result := make(map[string]metrics.Metrics, len(batch))
+	for _, m := range batch {
+		result[m.SeriesKey()] = m
+	}
+	return result
+}
+
+func TestCgroupCollector(t *testing.T) {
+	ctx := context.Background()
+	root := copyFixture(t, filepath.Join("testdata", "cgroup"))
+	c := collectors.NewCgroupCollector(root)
+
+	batch, err := c.Collect(ctx)
+	require.NoError(t, err)
+	got := byKey(batch)
+
+	assert.Equal(t, 268435456.0, *got["CgroupMemoryUsage"].Value)
+	assert.Equal(t, 1073741824.0, *got["CgroupMemoryLimit"].Value)
+	assert.Equal(t, 25.0, *got["CgroupMemoryUsedPercent"].Value)
+	assert.Equal(t, 1.5, *got["CgroupCPULimit"].Value)
+	assert.Equal(t, 12.0, *got["CgroupPids"].Value)
+	// the pids limit is `max`.
+	assert.NotContains(t, got, "CgroupPidsLimit")
+	// counters are reported from the second poll.
+	assert.NotContains(t, got, "CgroupCPUUsageUsec")
+	assert.NotContains(t, got, "CgroupCPUPercent")
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "cpu.stat"), []byte(
+		"usage_usec 5500000\nuser_usec 3300000\nsystem_usec 2200000\nnr_periods 110\nnr_throttled 13\nthrottled_usec 400000\n",
+	), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "io.stat"), []byte(
+		"8:0 rbytes=1052672 wbytes=2097152 rios=101 wios=200 dbytes=0 dios=0\n",
+	), 0o600))
+
+	batch, err = c.Collect(ctx)
+	require.NoError(t, err)
+	got = byKey(batch)
+
+	assert.Equal(t, int64(500000), *got["CgroupCPUUsageUsec"].Delta)
+	assert.Equal(t, int64(3), *got["CgroupCPUThrottledPeriods"].Delta)
+	assert.Equal(t, int64(150000), *got["CgroupCPUThrottledUsec"].Delta)
+	assert.Equal(t, int64(4096), *got[`CgroupIOReadBytes{device="8:0"}`].Delta)
+	assert.Equal(t, int64(1), *got[`CgroupIOReads{device="8:0"}`].Delta)
+	assert.NotContains(t, got, `CgroupIOReadBytes{device="253:0"}`)
+	assert.Positive(t, *got["CgroupCPUPercent"].Value)
+}
+
+func TestCgroupCollectorWithoutCgroupV2(t *testing.T) {
+	c := collectors.NewCgroupCollector(t.TempDir())
+
+	batch, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, batch)
+}
+
+func TestCgroupCollectorMissingControllers(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "cgroup.controllers"), []byte("memory\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "memory.current"), []byte("1024\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "memory.max"), []byte("max\n"), 0o600))
+
+	batch, err := collectors.NewCgroupCollector(root).Collect(context.Background())
+	require.NoError(t, err)
+	got := byKey(batch)
+	assert.Len(t, got, 1)
+	assert.Equal(t, 1024.0, *got["CgroupMemoryUsage"].Value)
+}
@@ -0,0 +1,162 @@
+package collectors
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+)
+
+// Collector collects a group of agent metrics.
+//
+// Collect returns gauges with current values and counters with increments since the previous call.
+// On error the collector may return the metrics it managed to collect, they are stored as well.
+type Collector interface {
+	Name() string
+	Collect(ctx context.Context) ([]metrics.Metrics, error)
+}
+
+// Registry a set of collectors addressed by name.
+type Registry struct {
+	mu         sync.RWMutex
+	collectors map[string]Collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: make(map[string]Collector)}
+}
+
+// Register adds the collector, names must be unique.
+func (r *Registry) Register(c Collector) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := c.Name()
+	if name == "" {
+		return fmt.Errorf("collector name is empty")
+	}
+	if _, ok := r.collectors[name]; ok {
+		return fmt.Errorf("collector `%s` is already registered", name)
+	}
+	r.collectors[name] = c
+	return nil
+}
+
+// Get returns the collector by name.
+func (r *Registry) Get(name string) (Collector, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.collectors[name]
+	return c, ok
+}
+
+// Names returns sorted names of the registered collectors.
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Enabled returns the collectors sorted by name except the disabled ones.
+// An unknown name in disabled is an error, so a typo in the configuration does not go unnoticed.
+func (r *Registry) Enabled(disabled []string) ([]Collector, error) {
+	if err := r.check(disabled); err != nil {
+		return nil, err
+	}
+
+	skip := make(map[string]bool, len(disabled))
+	for _, name := range disabled {
+		skip[name] = true
+	}
+
+	var enabled []Collector
+	for _, name := range r.Names() {
+		if !skip[name] {
+			c, _ := r.Get(name)
+			enabled = append(enabled, c)
+		}
+	}
+	return enabled, nil
+}
+
+// check returns an error listing names of unregistered collectors.
+func (r *Registry) check(names []string) error {
+	var unknown []string
+	for _, name := range names {
+		if _, ok := r.Get(name); !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown collectors: %s, available: %s",
+			strings.Join(unknown, ", "), strings.Join(r.Names(), ", "))
+	}
+	return nil
+}
+
+// Options configure the built-in collectors.
+type Options struct {
+	// Devices selects disks of the disk and diskio collectors.
+	Devices Filter
+	// Interfaces selects network interfaces of the net collector.
+	Interfaces Filter
+	// Processes services watched by the process collector.
+	Processes []ProcessWatch
+	// CgroupRoot the cgroup v2 group of the cgroup collector, DefaultCgroupRoot if empty.
+	CgroupRoot string
+}
+
+// builtin returns the built-in collectors.
+func builtin(opts Options) []Collector {
+	return []Collector{
+		NewPollCollector(),
+		NewRuntimeCollector(),
+		NewGopsutilCollector(),
+		NewDiskUsageCollector(opts.Devices),
+		NewDiskIOCollector(opts.Devices),
+		NewNetIOCollector(opts.Interfaces),
+		NewLoadCollector(),
+		NewFDCollector(),
+		NewProcessCollector(opts.Processes),
+		NewCgroupCollector(opts.CgroupRoot),
+	}
+}
+
+// custom the collectors added by Register.
+var custom = NewRegistry()
+
+// Register adds a custom collector to every registry created by NewDefaultRegistry,
+// usually from an init function. It panics if the name is empty or already registered.
+func Register(c Collector) {
+	if err := custom.Register(c); err != nil {
+		panic(err)
+	}
+}
+
+// NewDefaultRegistry returns a registry with the built-in collectors and the ones added by Register.
+func NewDefaultRegistry(opts Options) (*Registry, error) {
+	registry := NewRegistry()
+	for _, c := range builtin(opts) {
+		if err := registry.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, name := range custom.Names() {
+		c, _ := custom.Get(name)
+		if err := registry.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return registry, nil
+}
@@ -0,0 +1,74 @@
+package collectors_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/collectors"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type namedCollector string
+
+func (c namedCollector) Name() string {
+	return string(c)
+}
+
+func (c namedCollector) Collect(ctx context.Context) ([]metrics.Metrics, error) {
+	return nil, nil
+}
+
+func TestRegistry(t *testing.T) {
+	registry := collectors.NewRegistry()
+	require.NoError(t, registry.Register(namedCollector("b")))
+	require.NoError(t, registry.Register(namedCollector("a")))
+	require.NoError(t, registry.Register(namedCollector("c")))
+
+	assert.Error(t, registry.Register(namedCollector("a")))
+	assert.Error(t, registry.Register(namedCollector("")))
+	assert.Equal(t, []string{"a", "b", "c"}, registry.Names())
+
+	enabled, err := registry.Enabled([]string{"b"})
+	require.NoError(t, err)
+	assert.Equal(t, []collectors.Collector{namedCollector("a"), namedCollector("c")}, enabled)
+
+	_, err = registry.Enabled([]string{"b", "typo"})
+	assert.ErrorContains(t, err, "typo")
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	collectors.Register(namedCollector("custom"))
+	assert.Panics(t, func() { collectors.Register(namedCollector("custom")) })
+
+	registry, err := collectors.NewDefaultRegistry(collectors.Options{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"cgroup", "custom", "disk", "diskio", "fd", "gopsutil", "load", "net", "poll", "process", "runtime"}, registry.Names())
+}
+
+func names(batch []metrics.Metrics) []string {
+	result := make([]string, 0, len(batch))
+	for _, m := range batch {
+		result = append(result, m.ID)
+	}
+	return result
+}
+
+func TestBuiltinCollectors(t *testing.T) {
+	ctx := context.Background()
+
+	batch, err := collectors.NewPollCollector().Collect(ctx)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"PollCount", "RandomValue"}, names(batch))
+
+	batch, err = collectors.NewRuntimeCollector().Collect(ctx)
+	require.NoError(t, err)
+	assert.Len(t, batch, 27)
+	assert.Contains(t, names(batch), "HeapAlloc")
+
+	batch, err = collectors.NewGopsutilCollector().Collect(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, names(batch), "TotalMemory")
+	assert.Contains(t, names(batch), "CPUutilization1")
+}
@@ -0,0 +1,51 @@
+package collectors
+
+import (
+	"sync"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+)
+
+// cumulativeCounter a counter reported by the OS as a total, e.g. bytes sent since boot.
+type cumulativeCounter struct {
+	name   string
+	labels metrics.Labels
+	value  uint64
+}
+
+// counterDeltas converts cumulative OS counters to increments since the previous poll.
+//
+// The first value of a counter is a baseline and is not reported, a smaller value means
+// the counter was reset, e.g. an interface was recreated, and is reported as the increment.
+// Counters missing from a poll are forgotten.
+type counterDeltas struct {
+	mu   sync.Mutex
+	last map[string]uint64
+}
+
+func (d *counterDeltas) poll(counters []cumulativeCounter) []metrics.Metrics {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	next := make(map[string]uint64, len(counters))
+	result := make([]metrics.Metrics, 0, len(counters))
+	for _, c := range counters {
+		m := metrics.Metrics{ID: c.name, MType: metrics.Counter, Labels: c.labels}
+		key := m.SeriesKey()
+		next[key] = c.value
+
+		prev, ok := d.last[key]
+		if !ok {
+			continue
+		}
+		delta := int64(c.value)
+		if c.value >= prev {
+			delta = int64(c.value - prev)
+		}
+		m.Delta = &delta
+		result = append(result, m)
+	}
+	d.last = next
+
+	return result
+}
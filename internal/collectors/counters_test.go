@@ -0,0 +1,139 @@
+package collectors
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/net"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// values returns gauge values and counter deltas by series key.
+func values(batch []metrics.Metrics) map[string]float64 {
+	result := make(map[string]float64, len(batch))
+	for _, m := range batch {
+		if m.MType == metrics.Counter {
+			result[m.SeriesKey()] = float64(*m.Delta)
+		} else {
+			result[m.SeriesKey()] = *m.Value
+		}
+	}
+	return result
+}
+
+func TestCounterDeltas(t *testing.T) {
+	var deltas counterDeltas
+	labels := metrics.Labels{"interface": "eth0"}
+
+	// the first value is a baseline.
+	assert.Empty(t, deltas.poll([]cumulativeCounter{{name: "NetBytesSent", labels: labels, value: 100}}))
+
+	batch := deltas.poll([]cumulativeCounter{{name: "NetBytesSent", labels: labels, value: 150}})
+	require.Len(t, batch, 1)
+	assert.Equal(t, int64(50), *batch[0].Delta)
+	assert.Equal(t, labels, batch[0].Labels)
+
+	// a smaller value is a reset.
+	batch = deltas.poll([]cumulativeCounter{{name: "NetBytesSent", labels: labels, value: 20}})
+	require.Len(t, batch, 1)
+	assert.Equal(t, int64(20), *batch[0].Delta)
+
+	// a missing counter is forgotten and starts with a new baseline.
+	assert.Empty(t, deltas.poll(nil))
+	assert.Empty(t, deltas.poll([]cumulativeCounter{{name: "NetBytesSent", labels: labels, value: 500}}))
+}
+
+func TestNetIOCollector(t *testing.T) {
+	ctx := context.Background()
+	filter, err := NewFilter(nil, []string{"lo"})
+	require.NoError(t, err)
+
+	c := NewNetIOCollector(filter)
+	var sent uint64
+	c.ioCounters = func(ctx context.Context) ([]net.IOCountersStat, error) {
+		sent += 1000
+		return []net.IOCountersStat{
+			{Name: "eth0", BytesSent: sent, PacketsSent: sent / 100},
+			{Name: "lo", BytesSent: sent},
+		}, nil
+	}
+
+	batch, err := c.Collect(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, batch)
+
+	batch, err = c.Collect(ctx)
+	require.NoError(t, err)
+	got := values(batch)
+	assert.Len(t, got, 8)
+	assert.Equal(t, 1000.0, got[`NetBytesSent{interface="eth0"}`])
+	assert.Equal(t, 10.0, got[`NetPacketsSent{interface="eth0"}`])
+	assert.Equal(t, 0.0, got[`NetErrIn{interface="eth0"}`])
+}
+
+func TestDiskCollectors(t *testing.T) {
+	ctx := context.Background()
+	filter, err := NewFilter(nil, []string{"loop*"})
+	require.NoError(t, err)
+
+	usage := NewDiskUsageCollector(filter)
+	usage.partitions = func(ctx context.Context) ([]disk.PartitionStat, error) {
+		return []disk.PartitionStat{
+			{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4"},
+			{Device: "/dev/sda1", Mountpoint: "/", Fstype: "ext4"},
+			{Device: "/dev/loop0", Mountpoint: "/snap/core", Fstype: "squashfs"},
+		}, nil
+	}
+	usage.usage = func(ctx context.Context, path string) (*disk.UsageStat, error) {
+		return &disk.UsageStat{Path: path, Total: 100, Used: 40, Free: 60, UsedPercent: 40}, nil
+	}
+
+	batch, err := usage.Collect(ctx)
+	require.NoError(t, err)
+	got := values(batch)
+	assert.Len(t, got, 5)
+	assert.Equal(t, 60.0, got[`DiskFree{fstype="ext4",mountpoint="/"}`])
+
+	io := NewDiskIOCollector(filter)
+	var reads uint64
+	io.ioCounters = func(ctx context.Context) (map[string]disk.IOCountersStat, error) {
+		reads += 4096
+		return map[string]disk.IOCountersStat{
+			"sda":   {Name: "sda", ReadBytes: reads, ReadCount: reads / 4096},
+			"loop0": {Name: "loop0", ReadBytes: reads},
+		}, nil
+	}
+
+	_, err = io.Collect(ctx)
+	require.NoError(t, err)
+	batch, err = io.Collect(ctx)
+	require.NoError(t, err)
+	got = values(batch)
+	assert.Len(t, got, 7)
+	assert.Equal(t, 4096.0, got[`DiskReadBytes{device="sda"}`])
+	assert.Equal(t, 1.0, got[`DiskReads{device="sda"}`])
+}
+
+func TestFDCollector(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "file-nr")
+	require.NoError(t, os.WriteFile(path, []byte("2080\t80\t9223372036854775807\n"), 0o600))
+
+	c := &FDCollector{path: path}
+	batch, err := c.Collect(ctx)
+	require.NoError(t, err)
+	got := values(batch)
+	assert.Equal(t, 2000.0, got["OpenFDs"])
+	assert.Equal(t, 9223372036854775807.0, got["MaxFDs"])
+
+	// nothing is reported without procfs.
+	c.path = filepath.Join(t.TempDir(), "missing")
+	batch, err = c.Collect(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, batch)
+}
@@ -0,0 +1,124 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+// deviceName returns the device name without the /dev/ prefix, e.g. `sda1`.
+func deviceName(device string) string {
+	return strings.TrimPrefix(device, "/dev/")
+}
+
+// DiskUsageCollector reports the usage of mounted filesystems of physical devices:
+// the DiskTotal, DiskUsed and DiskFree gauges in bytes and the DiskUsedPercent
+// and DiskInodesUsedPercent gauges with the mountpoint and fstype labels.
+type DiskUsageCollector struct {
+	devices    Filter
+	partitions func(ctx context.Context) ([]disk.PartitionStat, error)
+	usage      func(ctx context.Context, path string) (*disk.UsageStat, error)
+}
+
+func NewDiskUsageCollector(devices Filter) *DiskUsageCollector {
+	return &DiskUsageCollector{
+		devices: devices,
+		partitions: func(ctx context.Context) ([]disk.PartitionStat, error) {
+			return disk.PartitionsWithContext(ctx, false)
+		},
+		usage: disk.UsageWithContext,
+	}
+}
+
+func (c *DiskUsageCollector) Name() string {
+	return "disk"
+}
+
+func (c *DiskUsageCollector) Collect(ctx context.Context) ([]metrics.Metrics, error) {
+	partitions, err := c.partitions(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		result []metrics.Metrics
+		errs   []error
+		seen   = make(map[string]bool)
+	)
+	for _, p := range partitions {
+		// a filesystem mounted several times is reported once.
+		if seen[p.Mountpoint] || !c.devices.Match(deviceName(p.Device)) {
+			continue
+		}
+		seen[p.Mountpoint] = true
+
+		usage, err := c.usage(ctx, p.Mountpoint)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("disk usage of %s: %w", p.Mountpoint, err))
+			continue
+		}
+
+		labels := metrics.Labels{"mountpoint": p.Mountpoint, "fstype": p.Fstype}
+		result = append(result,
+			labeledGauge("DiskTotal", labels, float64(usage.Total)),
+			labeledGauge("DiskUsed", labels, float64(usage.Used)),
+			labeledGauge("DiskFree", labels, float64(usage.Free)),
+			labeledGauge("DiskUsedPercent", labels, usage.UsedPercent),
+			labeledGauge("DiskInodesUsedPercent", labels, usage.InodesUsedPercent),
+		)
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// DiskIOCollector reports I/O of block devices with the device label: the DiskReadBytes,
+// DiskWriteBytes, DiskReads and DiskWrites counters and the DiskReadTime, DiskWriteTime
+// and DiskIOTime counters in milliseconds.
+type DiskIOCollector struct {
+	devices    Filter
+	deltas     counterDeltas
+	ioCounters func(ctx context.Context) (map[string]disk.IOCountersStat, error)
+}
+
+func NewDiskIOCollector(devices Filter) *DiskIOCollector {
+	return &DiskIOCollector{
+		devices: devices,
+		ioCounters: func(ctx context.Context) (map[string]disk.IOCountersStat, error) {
+			return disk.IOCountersWithContext(ctx)
+		},
+	}
+}
+
+func (c *DiskIOCollector) Name() string {
+	return "diskio"
+}
+
+func (c *DiskIOCollector) Collect(ctx context.Context) ([]metrics.Metrics, error) {
+	stats, err := c.ioCounters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counters := make([]cumulativeCounter, 0, len(stats)*7)
+	for name, s := range stats {
+		if !c.devices.Match(name) {
+			continue
+		}
+		labels := metrics.Labels{"device": name}
+		counters = append(counters,
+			cumulativeCounter{name: "DiskReadBytes", labels: labels, value: s.ReadBytes},
+			cumulativeCounter{name: "DiskWriteBytes", labels: labels, value: s.WriteBytes},
+			cumulativeCounter{name: "DiskReads", labels: labels, value: s.ReadCount},
+			cumulativeCounter{name: "DiskWrites", labels: labels, value: s.WriteCount},
+			cumulativeCounter{name: "DiskReadTime", labels: labels, value: s.ReadTime},
+			cumulativeCounter{name: "DiskWriteTime", labels: labels, value: s.WriteTime},
+			cumulativeCounter{name: "DiskIOTime", labels: labels, value: s.IoTime},
+		)
+	}
+
+	return c.deltas.poll(counters), nil
+}
@@ -0,0 +1,2 @@
+// Module with agent metric collectors and the registry the agent polls them from.
+package collectors
@@ -0,0 +1,54 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+)
+
+// FDCollector reports the OpenFDs and MaxFDs gauges: file descriptors open in the system
+// and the system limit. The numbers are read from /proc/sys/fs/file-nr, so nothing
+// is reported on systems without procfs.
+type FDCollector struct {
+	path string
+}
+
+func NewFDCollector() *FDCollector {
+	return &FDCollector{path: "/proc/sys/fs/file-nr"}
+}
+
+func (c *FDCollector) Name() string {
+	return "fd"
+}
+
+func (c *FDCollector) Collect(ctx context.Context) ([]metrics.Metrics, error) {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// file-nr holds the number of allocated, allocated but unused and maximum descriptors.
+	fields := strings.Fields(string(data))
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("%s: unexpected content `%s`", c.path, strings.TrimSpace(string(data)))
+	}
+	var values [3]float64
+	for i, field := range fields {
+		if values[i], err = strconv.ParseFloat(field, 64); err != nil {
+			return nil, fmt.Errorf("%s: %w", c.path, err)
+		}
+	}
+
+	return []metrics.Metrics{
+		gauge("OpenFDs", values[0]-values[1]),
+		gauge("MaxFDs", values[2]),
+	}, nil
+}
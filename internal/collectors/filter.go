@@ -0,0 +1,39 @@
+package collectors
+
+import (
+	"fmt"
+	"path"
+)
+
+// Filter selects devices or network interfaces by name with glob patterns, e.g. `sd*` or `eth0`.
+//
+// A name matches if Include is empty or it matches one of the Include patterns,
+// and it matches none of the Exclude patterns.
+type Filter struct {
+	Include []string
+	Exclude []string
+}
+
+// NewFilter returns the filter, patterns are checked for syntax errors.
+func NewFilter(include, exclude []string) (Filter, error) {
+	for _, pattern := range append(append([]string(nil), include...), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return Filter{}, fmt.Errorf("bad pattern `%s`: %w", pattern, err)
+		}
+	}
+	return Filter{Include: include, Exclude: exclude}, nil
+}
+
+// Match reports whether the name passes the filter.
+func (f Filter) Match(name string) bool {
+	return (len(f.Include) == 0 || matchAny(f.Include, name)) && !matchAny(f.Exclude, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
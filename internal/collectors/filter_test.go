@@ -0,0 +1,27 @@
+package collectors_test
+
+import (
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/collectors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	all, err := collectors.NewFilter(nil, []string{"lo", "veth*"})
+	require.NoError(t, err)
+	assert.True(t, all.Match("eth0"))
+	assert.False(t, all.Match("lo"))
+	assert.False(t, all.Match("veth12ab"))
+
+	some, err := collectors.NewFilter([]string{"sd*", "nvme*"}, []string{"sdb"})
+	require.NoError(t, err)
+	assert.True(t, some.Match("sda1"))
+	assert.True(t, some.Match("nvme0n1"))
+	assert.False(t, some.Match("sdb"))
+	assert.False(t, some.Match("loop0"))
+
+	_, err = collectors.NewFilter([]string{"sd["}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,48 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// GopsutilCollector reports the host memory and the CPU utilization.
+type GopsutilCollector struct{}
+
+func NewGopsutilCollector() *GopsutilCollector {
+	return &GopsutilCollector{}
+}
+
+func (c *GopsutilCollector) Name() string {
+	return "gopsutil"
+}
+
+func (c *GopsutilCollector) Collect(ctx context.Context) ([]metrics.Metrics, error) {
+	var (
+		result []metrics.Metrics
+		errs   []error
+	)
+
+	if memory, err := mem.VirtualMemoryWithContext(ctx); err != nil {
+		errs = append(errs, err)
+	} else {
+		result = append(result,
+			gauge("TotalMemory", float64(memory.Total)),
+			gauge("FreeMemory", float64(memory.Free)),
+		)
+	}
+
+	if cpuPercents, err := cpu.PercentWithContext(ctx, 0, false); err != nil {
+		errs = append(errs, err)
+	} else {
+		for i, percent := range cpuPercents {
+			result = append(result, gauge(fmt.Sprintf("CPUutilization%d", i+1), percent))
+		}
+	}
+
+	return result, errors.Join(errs...)
+}
@@ -0,0 +1,31 @@
+package collectors
+
+import (
+	"context"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/shirou/gopsutil/v3/load"
+)
+
+// LoadCollector reports the Load1, Load5 and Load15 load average gauges.
+type LoadCollector struct{}
+
+func NewLoadCollector() *LoadCollector {
+	return &LoadCollector{}
+}
+
+func (c *LoadCollector) Name() string {
+	return "load"
+}
+
+func (c *LoadCollector) Collect(ctx context.Context) ([]metrics.Metrics, error) {
+	avg, err := load.AvgWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []metrics.Metrics{
+		gauge("Load1", avg.Load1),
+		gauge("Load5", avg.Load5),
+		gauge("Load15", avg.Load15),
+	}, nil
+}
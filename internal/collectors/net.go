@@ -0,0 +1,57 @@
+package collectors
+
+import (
+	"context"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// NetIOCollector reports traffic of network interfaces with the interface label:
+// the NetBytesSent, NetBytesRecv, NetPacketsSent, NetPacketsRecv, NetErrIn, NetErrOut,
+// NetDropIn and NetDropOut counters.
+type NetIOCollector struct {
+	interfaces Filter
+	deltas     counterDeltas
+	ioCounters func(ctx context.Context) ([]net.IOCountersStat, error)
+}
+
+func NewNetIOCollector(interfaces Filter) *NetIOCollector {
+	return &NetIOCollector{
+		interfaces: interfaces,
+		ioCounters: func(ctx context.Context) ([]net.IOCountersStat, error) {
+			return net.IOCountersWithContext(ctx, true)
+		},
+	}
+}
+
+func (c *NetIOCollector) Name() string {
+	return "net"
+}
+
+func (c *NetIOCollector) Collect(ctx context.Context) ([]metrics.Metrics, error) {
+	stats, err := c.ioCounters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counters := make([]cumulativeCounter, 0, len(stats)*8)
+	for _, s := range stats {
+		if !c.interfaces.Match(s.Name) {
+			continue
+		}
+		labels := metrics.Labels{"interface": s.Name}
+		counters = append(counters,
+			cumulativeCounter{name: "NetBytesSent", labels: labels, value: s.BytesSent},
+			cumulativeCounter{name: "NetBytesRecv", labels: labels, value: s.BytesRecv},
+			cumulativeCounter{name: "NetPacketsSent", labels: labels, value: s.PacketsSent},
+			cumulativeCounter{name: "NetPacketsRecv", labels: labels, value: s.PacketsRecv},
+			cumulativeCounter{name: "NetErrIn", labels: labels, value: s.Errin},
+			cumulativeCounter{name: "NetErrOut", labels: labels, value: s.Errout},
+			cumulativeCounter{name: "NetDropIn", labels: labels, value: s.Dropin},
+			cumulativeCounter{name: "NetDropOut", labels: labels, value: s.Dropout},
+		)
+	}
+
+	return c.deltas.poll(counters), nil
+}
@@ -0,0 +1,337 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// ProcessWatch selects the processes of a watched service by one of the matchers.
+type ProcessWatch struct {
+	// Label the value of the process label of the reported metrics.
+	Label string
+	// Name matches processes by the executable name.
+	Name string
+	// PidFile matches the process with the pid written to the file.
+	PidFile string
+	// Cmdline matches processes by the command line.
+	Cmdline *regexp.Regexp
+}
+
+// ParseProcessWatch parses the watch `label=kind:value`, where kind is name, pidfile or cmdline,
+// e.g. `nginx=name:nginx`, `api=pidfile:/run/api.pid` or `worker=cmdline:python .*worker\.py`.
+func ParseProcessWatch(s string) (ProcessWatch, error) {
+	var w ProcessWatch
+
+	label, matcher, ok := strings.Cut(s, "=")
+	if !ok || label == "" {
+		return w, fmt.Errorf("process watch `%s`: expected label=kind:value", s)
+	}
+	w.Label = label
+
+	kind, value, ok := strings.Cut(matcher, ":")
+	if !ok || value == "" {
+		return w, fmt.Errorf("process watch `%s`: expected label=kind:value", s)
+	}
+
+	switch kind {
+	case "name":
+		w.Name = value
+	case "pidfile":
+		w.PidFile = value
+	case "cmdline":
+		re, err := regexp.Compile(value)
+		if err != nil {
+			return w, fmt.Errorf("process watch `%s`: %w", s, err)
+		}
+		w.Cmdline = re
+	default:
+		return w, fmt.Errorf("process watch `%s`: unknown kind `%s`, expected name, pidfile or cmdline", s, kind)
+	}
+	return w, nil
+}
+
+// processInfo identifies a running process.
+type processInfo struct {
+	pid     int32
+	created int64 // milliseconds since the epoch, tells a reused pid from the previous process
+	name    string
+	cmdline string
+}
+
+// processStats resource usage of a process.
+type processStats struct {
+	cpuSeconds float64
+	rss        uint64
+	threads    int32
+	fds        int32
+}
+
+// processSource reads processes of the system.
+type processSource interface {
+	// List returns running processes with names and command lines.
+	List(ctx context.Context) ([]processInfo, error)
+	// Get returns the running process by pid.
+	Get(ctx context.Context, pid int32) (processInfo, error)
+	Stats(ctx context.Context, pid int32) (processStats, error)
+}
+
+// processKey identifies a process across polls.
+type processKey struct {
+	pid     int32
+	created int64
+}
+
+// watchState the state of a watched service between polls.
+type watchState struct {
+	main   processKey // the earliest started matching process
+	cpu    map[processKey]float64
+	polled time.Time
+}
+
+// ProcessCollector reports resource usage of watched services with the process label:
+// the ProcessCount, ProcessCPUPercent, ProcessRSS, ProcessThreads and ProcessOpenFDs gauges
+// summed over the matching processes, and the ProcessRestarts counter incremented
+// when the main process, the earliest started one, is replaced by another one.
+//
+// ProcessCPUPercent is the CPU time used between polls by processes running at both polls,
+// relative to the wall time, so it exceeds 100 for a service using several CPUs.
+//
+// The agent process is never matched by name or command line.
+type ProcessCollector struct {
+	watches []ProcessWatch
+	source  processSource
+	// self the pid of the agent, its command line holds the watched patterns, so it never matches.
+	self int32
+
+	mu     sync.Mutex
+	states map[string]*watchState
+}
+
+func NewProcessCollector(watches []ProcessWatch) *ProcessCollector {
+	return &ProcessCollector{
+		watches: watches,
+		source:  gopsutilProcesses{},
+		self:    int32(os.Getpid()),
+		states:  make(map[string]*watchState),
+	}
+}
+
+func (c *ProcessCollector) Name() string {
+	return "process"
+}
+
+func (c *ProcessCollector) Collect(ctx context.Context) ([]metrics.Metrics, error) {
+	if len(c.watches) == 0 {
+		return nil, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var (
+		all    []processInfo
+		listed bool
+		result []metrics.Metrics
+		errs   []error
+	)
+	for _, w := range c.watches {
+		var matched []processInfo
+		if w.PidFile != "" {
+			p, err := c.pidFileProcess(ctx, w.PidFile)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("process %s: %w", w.Label, err))
+			} else if p != nil {
+				matched = append(matched, *p)
+			}
+		} else {
+			if !listed {
+				var err error
+				if all, err = c.source.List(ctx); err != nil {
+					return nil, err
+				}
+				listed = true
+			}
+			for _, p := range all {
+				if p.pid == c.self {
+					continue
+				}
+				if (w.Name != "" && p.name == w.Name) || (w.Cmdline != nil && w.Cmdline.MatchString(p.cmdline)) {
+					matched = append(matched, p)
+				}
+			}
+		}
+
+		result = append(result, c.watchMetrics(ctx, w.Label, matched)...)
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// pidFileProcess returns the process of the pid file, nil if the file
+// or the process does not exist, i.e. the service is stopped.
+func (c *ProcessCollector) pidFileProcess(ctx context.Context, path string) (*processInfo, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	pid, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("pid file %s: %w", path, err)
+	}
+
+	p, err := c.source.Get(ctx, int32(pid))
+	if err != nil {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+// watchMetrics sums the stats of the matched processes and updates the state of the service.
+func (c *ProcessCollector) watchMetrics(ctx context.Context, label string, matched []processInfo) []metrics.Metrics {
+	now := time.Now()
+	state, seen := c.states[label]
+	if !seen {
+		state = &watchState{}
+		c.states[label] = state
+	}
+
+	var (
+		total processStats
+		cpu   = make(map[processKey]float64, len(matched))
+		used  float64
+		main  processKey
+		count int
+	)
+	for _, p := range matched {
+		stats, err := c.source.Stats(ctx, p.pid)
+		if err != nil {
+			// the process exited after it was listed.
+			continue
+		}
+		count++
+
+		key := processKey{pid: p.pid, created: p.created}
+		if main.pid == 0 || key.created < main.created {
+			main = key
+		}
+
+		cpu[key] = stats.cpuSeconds
+		if prev, ok := state.cpu[key]; ok && stats.cpuSeconds >= prev {
+			used += stats.cpuSeconds - prev
+		}
+		total.rss += stats.rss
+		total.threads += stats.threads
+		total.fds += stats.fds
+	}
+
+	var cpuPercent float64
+	if elapsed := now.Sub(state.polled).Seconds(); seen && elapsed > 0 {
+		cpuPercent = used / elapsed * 100
+	}
+
+	var restarts int64
+	if main.pid != 0 {
+		if state.main.pid != 0 && state.main != main {
+			restarts = 1
+		}
+		state.main = main
+	}
+	state.cpu, state.polled = cpu, now
+
+	labels := metrics.Labels{"process": label}
+	return []metrics.Metrics{
+		labeledGauge("ProcessCount", labels, float64(count)),
+		labeledGauge("ProcessCPUPercent", labels, cpuPercent),
+		labeledGauge("ProcessRSS", labels, float64(total.rss)),
+		labeledGauge("ProcessThreads", labels, float64(total.threads)),
+		labeledGauge("ProcessOpenFDs", labels, float64(total.fds)),
+		{ID: "ProcessRestarts", MType: metrics.Counter, Labels: labels, Delta: &restarts},
+	}
+}
+
+// gopsutilProcesses reads processes with gopsutil.
+type gopsutilProcesses struct{}
+
+func (gopsutilProcesses) List(ctx context.Context) ([]processInfo, error) {
+	procs, err := process.ProcessesWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]processInfo, 0, len(procs))
+	for _, p := range procs {
+		info, err := describeProcess(ctx, p)
+		if err != nil {
+			// the process exited or is not accessible.
+			continue
+		}
+		result = append(result, info)
+	}
+	return result, nil
+}
+
+func (gopsutilProcesses) Get(ctx context.Context, pid int32) (processInfo, error) {
+	p, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return processInfo{}, err
+	}
+	return describeProcess(ctx, p)
+}
+
+func (gopsutilProcesses) Stats(ctx context.Context, pid int32) (processStats, error) {
+	var stats processStats
+
+	p, err := process.NewProcessWithContext(ctx, pid)
+	if err != nil {
+		return stats, err
+	}
+
+	times, err := p.TimesWithContext(ctx)
+	if err != nil {
+		return stats, err
+	}
+	stats.cpuSeconds = times.User + times.System
+
+	memory, err := p.MemoryInfoWithContext(ctx)
+	if err != nil {
+		return stats, err
+	}
+	stats.rss = memory.RSS
+
+	if stats.threads, err = p.NumThreadsWithContext(ctx); err != nil {
+		return stats, err
+	}
+	// descriptors of processes of other users are not readable without privileges.
+	stats.fds, _ = p.NumFDsWithContext(ctx)
+
+	return stats, nil
+}
+
+func describeProcess(ctx context.Context, p *process.Process) (processInfo, error) {
+	info := processInfo{pid: p.Pid}
+
+	var err error
+	if info.name, err = p.NameWithContext(ctx); err != nil {
+		return info, err
+	}
+	if info.created, err = p.CreateTimeWithContext(ctx); err != nil {
+		return info, err
+	}
+	// kernel threads have no command line.
+	info.cmdline, _ = p.CmdlineWithContext(ctx)
+
+	return info, nil
+}
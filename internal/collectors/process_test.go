@@ -0,0 +1,155 @@
+package collectors
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeProcess struct {
+	info  processInfo
+	stats processStats
+}
+
+type fakeProcesses map[int32]*fakeProcess
+
+func (f fakeProcesses) List(ctx context.Context) ([]processInfo, error) {
+	result := make([]processInfo, 0, len(f))
+	for _, p := range f {
+		result = append(result, p.info)
+	}
+	return result, nil
+}
+
+func (f fakeProcesses) Get(ctx context.Context, pid int32) (processInfo, error) {
+	if p, ok := f[pid]; ok {
+		return p.info, nil
+	}
+	return processInfo{}, errors.New("process not found")
+}
+
+func (f fakeProcesses) Stats(ctx context.Context, pid int32) (processStats, error) {
+	if p, ok := f[pid]; ok {
+		return p.stats, nil
+	}
+	return processStats{}, errors.New("process not found")
+}
+
+func TestParseProcessWatch(t *testing.T) {
+	w, err := ParseProcessWatch("nginx=name:nginx")
+	require.NoError(t, err)
+	assert.Equal(t, ProcessWatch{Label: "nginx", Name: "nginx"}, w)
+
+	w, err = ParseProcessWatch("api=pidfile:/run/api.pid")
+	require.NoError(t, err)
+	assert.Equal(t, "/run/api.pid", w.PidFile)
+
+	w, err = ParseProcessWatch(`worker=cmdline:python3? .*worker\.py`)
+	require.NoError(t, err)
+	assert.True(t, w.Cmdline.MatchString("/usr/bin/python3 /srv/worker.py --queue=a"))
+
+	for _, bad := range []string{"nginx", "=name:nginx", "x=name:", "x=pid:1", "x=cmdline:("} {
+		_, err := ParseProcessWatch(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestProcessCollector(t *testing.T) {
+	ctx := context.Background()
+
+	pidFile := filepath.Join(t.TempDir(), "api.pid")
+	require.NoError(t, os.WriteFile(pidFile, []byte("30\n"), 0o600))
+
+	source := fakeProcesses{
+		10: {info: processInfo{pid: 10, created: 1000, name: "nginx"}, stats: processStats{cpuSeconds: 1, rss: 100, threads: 1, fds: 5}},
+		11: {info: processInfo{pid: 11, created: 2000, name: "nginx"}, stats: processStats{cpuSeconds: 2, rss: 200, threads: 2, fds: 6}},
+		20: {info: processInfo{pid: 20, created: 1000, name: "python3", cmdline: "python3 worker.py"}, stats: processStats{rss: 50, threads: 4}},
+		30: {info: processInfo{pid: 30, created: 1000, name: "api"}, stats: processStats{rss: 70, threads: 8}},
+		// the agent, its command line holds the watch.
+		40: {info: processInfo{pid: 40, created: 500, name: "agent", cmdline: `agent --watch-processes worker=cmdline:worker\.py`}},
+	}
+
+	watch := func(s string) ProcessWatch {
+		w, err := ParseProcessWatch(s)
+		require.NoError(t, err)
+		return w
+	}
+	c := NewProcessCollector([]ProcessWatch{
+		watch("nginx=name:nginx"),
+		watch(`worker=cmdline:worker\.py`),
+		watch("api=pidfile:" + pidFile),
+	})
+	c.source = source
+	c.self = 40
+
+	batch, err := c.Collect(ctx)
+	require.NoError(t, err)
+	got := values(batch)
+	assert.Equal(t, 2.0, got[`ProcessCount{process="nginx"}`])
+	assert.Equal(t, 300.0, got[`ProcessRSS{process="nginx"}`])
+	assert.Equal(t, 3.0, got[`ProcessThreads{process="nginx"}`])
+	assert.Equal(t, 11.0, got[`ProcessOpenFDs{process="nginx"}`])
+	assert.Equal(t, 1.0, got[`ProcessCount{process="worker"}`])
+	assert.Equal(t, 8.0, got[`ProcessThreads{process="api"}`])
+	assert.Equal(t, 0.0, got[`ProcessRestarts{process="nginx"}`])
+
+	// a worker is replaced, the main process keeps running.
+	source[10].stats.cpuSeconds = 3
+	delete(source, 11)
+	source[12] = &fakeProcess{info: processInfo{pid: 12, created: 3000, name: "nginx"}}
+	// the api is restarted with another pid.
+	delete(source, 30)
+	source[31] = &fakeProcess{info: processInfo{pid: 31, created: 4000, name: "api"}}
+	require.NoError(t, os.WriteFile(pidFile, []byte("31\n"), 0o600))
+
+	batch, err = c.Collect(ctx)
+	require.NoError(t, err)
+	got = values(batch)
+	assert.Equal(t, 0.0, got[`ProcessRestarts{process="nginx"}`])
+	assert.Equal(t, 1.0, got[`ProcessRestarts{process="api"}`])
+	assert.Positive(t, got[`ProcessCPUPercent{process="nginx"}`])
+
+	// the worker is stopped and started again.
+	delete(source, 20)
+	batch, err = c.Collect(ctx)
+	require.NoError(t, err)
+	got = values(batch)
+	assert.Equal(t, 0.0, got[`ProcessCount{process="worker"}`])
+	assert.Equal(t, 0.0, got[`ProcessRestarts{process="worker"}`])
+
+	source[21] = &fakeProcess{info: processInfo{pid: 21, created: 5000, name: "python3", cmdline: "python3 worker.py"}}
+	batch, err = c.Collect(ctx)
+	require.NoError(t, err)
+	got = values(batch)
+	assert.Equal(t, 1.0, got[`ProcessRestarts{process="worker"}`])
+
+	// a missing pid file means the service is stopped.
+	require.NoError(t, os.Remove(pidFile))
+	batch, err = c.Collect(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, values(batch)[`ProcessCount{process="api"}`])
+}
+
+func TestProcessCollectorSelf(t *testing.T) {
+	c := NewProcessCollector([]ProcessWatch{{Label: "self", PidFile: selfPidFile(t)}})
+
+	batch, err := c.Collect(context.Background())
+	require.NoError(t, err)
+	got := values(batch)
+	assert.Equal(t, 1.0, got[`ProcessCount{process="self"}`])
+	assert.Positive(t, got[`ProcessRSS{process="self"}`])
+	assert.Positive(t, got[`ProcessThreads{process="self"}`])
+}
+
+func selfPidFile(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "self.pid")
+	require.NoError(t, os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())), 0o600))
+	return path
+}
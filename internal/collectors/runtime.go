@@ -0,0 +1,85 @@
+package collectors
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+)
+
+func gauge(name string, value float64) metrics.Metrics {
+	return metrics.Metrics{ID: name, MType: metrics.Gauge, Value: &value}
+}
+
+func labeledGauge(name string, labels metrics.Labels, value float64) metrics.Metrics {
+	return metrics.Metrics{ID: name, MType: metrics.Gauge, Labels: labels, Value: &value}
+}
+
+func counter(name string, delta int64) metrics.Metrics {
+	return metrics.Metrics{ID: name, MType: metrics.Counter, Delta: &delta}
+}
+
+// PollCollector reports the PollCount counter incremented on every poll and the RandomValue gauge.
+type PollCollector struct{}
+
+func NewPollCollector() *PollCollector {
+	return &PollCollector{}
+}
+
+func (c *PollCollector) Name() string {
+	return "poll"
+}
+
+func (c *PollCollector) Collect(ctx context.Context) ([]metrics.Metrics, error) {
+	return []metrics.Metrics{
+		gauge("RandomValue", float64(time.Now().UnixNano())/float64(time.Second)),
+		counter("PollCount", 1),
+	}, nil
+}
+
+// RuntimeCollector reports memory statistics of the Go runtime.
+type RuntimeCollector struct{}
+
+func NewRuntimeCollector() *RuntimeCollector {
+	return &RuntimeCollector{}
+}
+
+func (c *RuntimeCollector) Name() string {
+	return "runtime"
+}
+
+func (c *RuntimeCollector) Collect(ctx context.Context) ([]metrics.Metrics, error) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	return []metrics.Metrics{
+		gauge("Alloc", float64(mem.Alloc)),
+		gauge("BuckHashSys", float64(mem.BuckHashSys)),
+		gauge("Frees", float64(mem.Frees)),
+		gauge("GCCPUFraction", mem.GCCPUFraction),
+		gauge("GCSys", float64(mem.GCSys)),
+		gauge("HeapAlloc", float64(mem.HeapAlloc)),
+		gauge("HeapIdle", float64(mem.HeapIdle)),
+		gauge("HeapInuse", float64(mem.HeapInuse)),
+		gauge("HeapObjects", float64(mem.HeapObjects)),
+		gauge("HeapReleased", float64(mem.HeapReleased)),
+		gauge("HeapSys", float64(mem.HeapSys)),
+		gauge("LastGC", float64(mem.LastGC)),
+		gauge("Lookups", float64(mem.Lookups)),
+		gauge("MCacheInuse", float64(mem.MCacheInuse)),
+		gauge("MCacheSys", float64(mem.MCacheSys)),
+		gauge("MSpanInuse", float64(mem.MSpanInuse)),
+		gauge("MSpanSys", float64(mem.MSpanSys)),
+		gauge("Mallocs", float64(mem.Mallocs)),
+		gauge("NextGC", float64(mem.NextGC)),
+		gauge("NumForcedGC", float64(mem.NumForcedGC)),
+		gauge("NumGC", float64(mem.NumGC)),
+		gauge("OtherSys", float64(mem.OtherSys)),
+		gauge("PauseTotalNs", float64(mem.PauseTotalNs)),
+		gauge("StackInuse", float64(mem.StackInuse)),
+		gauge("StackSys", float64(mem.StackSys)),
+		gauge("Sys", float64(mem.Sys)),
+		gauge("TotalAlloc", float64(mem.TotalAlloc)),
+	}, nil
+}
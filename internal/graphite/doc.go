@@ -0,0 +1,2 @@
+// Module with a receiver of the Graphite plaintext protocol writing values as gauges to the metric storage.
+package graphite
@@ -0,0 +1,58 @@
+package graphite
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxTimestampSkew the maximum difference of a line timestamp from the server time,
+// like for the influx line protocol only current values are stored.
+const maxTimestampSkew = 10 * time.Minute
+
+// Sample a single line of the plaintext protocol.
+type Sample struct {
+	Path      string
+	Value     float64
+	Timestamp time.Time
+}
+
+// ParseLine parses a line `path value [timestamp]`, a missing or -1 timestamp is replaced by now.
+// Non-finite values and timestamps more than maxTimestampSkew away from now are rejected.
+func ParseLine(line string, now time.Time) (Sample, error) {
+	var sample Sample
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 || len(fields) > 3 {
+		return sample, fmt.Errorf("bad line `%s`: expected path, value and timestamp", line)
+	}
+
+	sample.Path = fields[0]
+	if strings.HasPrefix(sample.Path, ".") || strings.HasSuffix(sample.Path, ".") || strings.Contains(sample.Path, "..") {
+		return sample, fmt.Errorf("bad line `%s`: empty path node", line)
+	}
+
+	var err error
+	if sample.Value, err = strconv.ParseFloat(fields[1], 64); err != nil {
+		return sample, fmt.Errorf("bad line `%s`: bad value: %w", line, err)
+	}
+	if math.IsNaN(sample.Value) || math.IsInf(sample.Value, 0) {
+		return sample, fmt.Errorf("bad line `%s`: value is not finite", line)
+	}
+
+	sample.Timestamp = now
+	if len(fields) == 3 && fields[2] != "-1" {
+		ts, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil || math.IsNaN(ts) || math.IsInf(ts, 0) {
+			return sample, fmt.Errorf("bad line `%s`: bad timestamp", line)
+		}
+		if skew := ts - float64(now.UnixNano())/float64(time.Second); math.Abs(skew) > maxTimestampSkew.Seconds() {
+			return sample, fmt.Errorf("bad line `%s`: timestamp is out of range, only current values are stored", line)
+		}
+		sample.Timestamp = time.Unix(0, int64(ts*float64(time.Second)))
+	}
+
+	return sample, nil
+}
@@ -0,0 +1,40 @@
+package graphite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		line   string
+		expect Sample
+	}{
+		{line: "servers.web01.cpu 0.5 1717200000", expect: Sample{Path: "servers.web01.cpu", Value: 0.5, Timestamp: time.Unix(1717200000, 0)}},
+		{line: "servers.web01.cpu 2 -1", expect: Sample{Path: "servers.web01.cpu", Value: 2, Timestamp: now}},
+		{line: "load  1.5", expect: Sample{Path: "load", Value: 1.5, Timestamp: now}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.line, func(t *testing.T) {
+			sample, err := ParseLine(tc.line, now)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, sample)
+		})
+	}
+
+	for _, line := range []string{
+		"load", "load abc 1", "load 1 abc", "load 1 2 3", ".load 1", "a..b 1", "a. 1",
+		"load NaN", "load +Inf 1717200000", "load -inf", "load 1 NaN", "load 1 Inf",
+		// more than 10 minutes away from now.
+		"load 1 1717199000", "load 1 1717201000", "load 1 1e300",
+	} {
+		_, err := ParseLine(line, now)
+		assert.Error(t, err, line)
+	}
+}
@@ -0,0 +1,192 @@
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"github.com/screamsoul/go-metrics-tpl/pkg/logging"
+	"go.uber.org/zap"
+)
+
+const (
+	// batchSize the maximum number of gauges written to the storage at once.
+	batchSize = 100
+	// maxLineSize the maximum length of a line, longer lines are skipped.
+	maxLineSize = 64 * 1024
+)
+
+// Server receives the Graphite plaintext protocol over TCP.
+//
+// Lines of a connection are written to the storage in batches before more data is read,
+// so a slow storage slows senders down through TCP flow control. When maxConns connections
+// are open, new connections wait in the listen backlog until one of them is closed.
+// A connection without data for idleTimeout is closed, so idle senders do not hold the slots.
+type Server struct {
+	store       repositories.MetricStorage
+	templates   Templates
+	logger      *zap.Logger
+	idleTimeout time.Duration
+
+	listener net.Listener
+	slots    chan struct{}
+	mu       sync.Mutex
+	conns    map[net.Conn]struct{}
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer creates the server, a zero idleTimeout keeps idle connections open.
+func NewServer(store repositories.MetricStorage, templates Templates, maxConns int, idleTimeout time.Duration) *Server {
+	return &Server{
+		store:       store,
+		templates:   templates,
+		logger:      logging.GetLogger(),
+		idleTimeout: idleTimeout,
+		slots:       make(chan struct{}, max(maxConns, 1)),
+		conns:       make(map[net.Conn]struct{}),
+	}
+}
+
+// Listen binds the TCP address.
+func (s *Server) Listen(address string) (err error) {
+	s.listener, err = net.Listen("tcp", address)
+	return err
+}
+
+// Addr returns the bound address.
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// Run serves connections until the context is done, then closes the listener and open connections.
+func (s *Server) Run(ctx context.Context) {
+	s.wg.Add(1)
+	go s.serve(context.WithoutCancel(ctx))
+
+	<-ctx.Done()
+
+	s.mu.Lock()
+	s.closed = true
+	_ = s.listener.Close()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+}
+
+func (s *Server) serve(ctx context.Context) {
+	defer s.wg.Done()
+
+	for {
+		// a free slot is taken before accepting, so extra connections wait in the backlog.
+		s.slots <- struct{}{}
+
+		conn, err := s.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			<-s.slots
+			s.logger.Warn("graphite accept error", zap.Error(err))
+			continue
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serveConn(ctx, conn)
+	}
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+		<-s.slots
+	}()
+
+	reader := bufio.NewReaderSize(conn, maxLineSize)
+	batch := make([]metrics.Metrics, 0, batchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := s.store.BulkAdd(ctx, batch); err != nil {
+			s.logger.Error("error write graphite metrics", zap.Int("count", len(batch)), zap.Error(err))
+		}
+		batch = batch[:0]
+	}
+	defer flush()
+
+	for {
+		if s.idleTimeout > 0 && reader.Buffered() == 0 {
+			if err := conn.SetReadDeadline(time.Now().Add(s.idleTimeout)); err != nil {
+				s.logger.Warn("graphite read deadline error", zap.Error(err))
+				return
+			}
+		}
+
+		line, err := readLine(reader)
+
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			// a line cut by the timeout is incomplete, so it is dropped.
+			s.logger.Debug("close idle graphite connection", zap.String("address", conn.RemoteAddr().String()))
+			return
+		}
+
+		if line = strings.TrimSpace(line); line != "" {
+			if sample, parseErr := ParseLine(line, time.Now()); parseErr != nil {
+				s.logger.Debug("skip graphite line", zap.Error(parseErr))
+			} else {
+				batch = append(batch, s.templates.Metric(sample))
+			}
+		}
+
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.logger.Warn("graphite read error", zap.Error(err))
+			}
+			return
+		}
+
+		// the batch is written when it is full or the received data is processed.
+		if len(batch) == batchSize || reader.Buffered() == 0 {
+			flush()
+		}
+	}
+}
+
+// readLine reads a line of at most maxLineSize bytes, a longer line is skipped.
+func readLine(reader *bufio.Reader) (string, error) {
+	line, err := reader.ReadSlice('\n')
+	if !errors.Is(err, bufio.ErrBufferFull) {
+		return string(line), err
+	}
+
+	for errors.Is(err, bufio.ErrBufferFull) {
+		_, err = reader.ReadSlice('\n')
+	}
+	return "", err
+}
@@ -0,0 +1,107 @@
+package graphite_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/graphite"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	store := memory.NewMemStorage()
+
+	templates, err := graphite.ParseTemplates([]string{"servers.* .host.name*"})
+	require.NoError(t, err)
+
+	server := graphite.NewServer(store, templates, 1, time.Minute)
+	require.NoError(t, server.Listen("127.0.0.1:0"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		server.Run(ctx)
+		close(done)
+	}()
+
+	stored := func(id string, labels metrics.Labels) func() bool {
+		return func() bool {
+			m := metrics.Metrics{ID: id, MType: metrics.Gauge, Labels: labels}
+			return store.Get(ctx, &m) == nil
+		}
+	}
+
+	first, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	_, err = fmt.Fprintf(first, "servers.web01.cpu.load 0.5 %d\nbad line\n", time.Now().Unix())
+	require.NoError(t, err)
+	assert.Eventually(t, stored("cpu.load", metrics.Labels{"host": "web01"}), 5*time.Second, 10*time.Millisecond)
+
+	// the second connection waits until the first one is closed
+	second, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer second.Close()
+	_, err = second.Write([]byte("load 1\n"))
+	require.NoError(t, err)
+	assert.Never(t, stored("load", nil), 100*time.Millisecond, 10*time.Millisecond)
+
+	require.NoError(t, first.Close())
+	assert.Eventually(t, stored("load", nil), 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("graphite server did not stop")
+	}
+}
+
+func TestServerClosesIdleConnections(t *testing.T) {
+	store := memory.NewMemStorage()
+
+	server := graphite.NewServer(store, nil, 1, 100*time.Millisecond)
+	require.NoError(t, server.Listen("127.0.0.1:0"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		server.Run(ctx)
+		close(done)
+	}()
+
+	stored := func() bool {
+		m := metrics.Metrics{ID: "load", MType: metrics.Gauge}
+		return store.Get(ctx, &m) == nil
+	}
+
+	// the idle connection takes the only slot until it times out.
+	idle, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer idle.Close()
+
+	second, err := net.Dial("tcp", server.Addr().String())
+	require.NoError(t, err)
+	defer second.Close()
+	_, err = second.Write([]byte("load 1\n"))
+	require.NoError(t, err)
+	assert.Eventually(t, stored, 5*time.Second, 10*time.Millisecond)
+
+	// the idle connection is closed by the server.
+	require.NoError(t, idle.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = idle.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("graphite server did not stop")
+	}
+}
@@ -0,0 +1,149 @@
+package graphite
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+)
+
+// Reserved parts of a template.
+const (
+	namePart     = "name"
+	nameRestPart = "name*"
+)
+
+// Template maps nodes of a dotted path to the metric name and labels.
+//
+// A template is `[filter ]pattern`. The filter is a dotted glob, e.g. `servers.*`,
+// matched against the first nodes of the path, a template without a filter matches any path.
+// Each node of the pattern describes the node of the path at the same position:
+// `name` adds the node to the metric name, `name*` adds the node and all following ones,
+// an empty node skips the path node and any other identifier makes the node a label.
+// Path nodes after the end of the pattern are added to the name.
+//
+// For example `servers.* .host.name*` maps `servers.web01.cpu.load`
+// to `cpu.load{host="web01"}`.
+type Template struct {
+	filter []string
+	parts  []string
+}
+
+// ParseTemplate parses the template string.
+func ParseTemplate(s string) (Template, error) {
+	var t Template
+
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 1:
+		t.parts = strings.Split(fields[0], ".")
+	case 2:
+		t.filter = strings.Split(fields[0], ".")
+		t.parts = strings.Split(fields[1], ".")
+		for _, node := range t.filter {
+			if _, err := path.Match(node, ""); err != nil {
+				return t, fmt.Errorf("template `%s`: bad filter: %w", s, err)
+			}
+		}
+	default:
+		return t, fmt.Errorf("template `%s`: expected `[filter ]pattern`", s)
+	}
+
+	labels := make(metrics.Labels)
+	for i, part := range t.parts {
+		switch part {
+		case "", namePart:
+		case nameRestPart:
+			if i != len(t.parts)-1 {
+				return t, fmt.Errorf("template `%s`: `%s` must be the last node", s, nameRestPart)
+			}
+		default:
+			labels[part] = ""
+		}
+	}
+	if err := labels.Validate(); err != nil {
+		return t, fmt.Errorf("template `%s`: %w", s, err)
+	}
+
+	return t, nil
+}
+
+// Match reports whether the filter of the template matches the path nodes.
+func (t Template) Match(nodes []string) bool {
+	if len(t.filter) > len(nodes) {
+		return false
+	}
+	for i, pattern := range t.filter {
+		if ok, _ := path.Match(pattern, nodes[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the metric name and labels for the path nodes.
+func (t Template) Apply(nodes []string) (string, metrics.Labels) {
+	var (
+		name   []string
+		labels metrics.Labels
+	)
+
+	for i, node := range nodes {
+		if i >= len(t.parts) {
+			name = append(name, node)
+			continue
+		}
+
+		switch part := t.parts[i]; part {
+		case "":
+		case namePart:
+			name = append(name, node)
+		case nameRestPart:
+			return strings.Join(append(name, nodes[i:]...), "."), labels
+		default:
+			if labels == nil {
+				labels = make(metrics.Labels)
+			}
+			labels[part] = node
+		}
+	}
+
+	return strings.Join(name, "."), labels
+}
+
+// Templates an ordered list of templates, the first matching template is applied.
+type Templates []Template
+
+// ParseTemplates parses the list of template strings.
+func ParseTemplates(list []string) (Templates, error) {
+	templates := make(Templates, 0, len(list))
+	for _, s := range list {
+		t, err := ParseTemplate(s)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, nil
+}
+
+// Metric returns the gauge for the path, the path is used as the name
+// when no template matches or the template gives an empty name.
+func (ts Templates) Metric(sample Sample) metrics.Metrics {
+	value := sample.Value
+	m := metrics.Metrics{ID: sample.Path, MType: metrics.Gauge, Value: &value}
+
+	nodes := strings.Split(sample.Path, ".")
+	for _, t := range ts {
+		if !t.Match(nodes) {
+			continue
+		}
+		if name, labels := t.Apply(nodes); name != "" {
+			m.ID, m.Labels = name, labels
+		}
+		break
+	}
+
+	return m
+}
@@ -0,0 +1,51 @@
+package graphite
+
+import (
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplatesMetric(t *testing.T) {
+	templates, err := ParseTemplates([]string{
+		"servers.* .host.name*",
+		"apps.*.*.requests .app.env.name",
+		"region.name",
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		path   string
+		name   string
+		labels metrics.Labels
+	}{
+		{path: "servers.web01.cpu.load", name: "cpu.load", labels: metrics.Labels{"host": "web01"}},
+		{path: "apps.shop.prod.requests", name: "requests", labels: metrics.Labels{"app": "shop", "env": "prod"}},
+		{path: "eu.temp.max", name: "temp.max", labels: metrics.Labels{"region": "eu"}},
+		{path: "servers.web01", name: "servers.web01"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			m := templates.Metric(Sample{Path: tc.path, Value: 1})
+			assert.Equal(t, tc.name, m.ID)
+			assert.Equal(t, tc.labels, m.Labels)
+			assert.Equal(t, metrics.Gauge, m.MType)
+			assert.Equal(t, 1.0, *m.Value)
+		})
+	}
+
+	// without templates the path is the name
+	m := Templates(nil).Metric(Sample{Path: "servers.web01.cpu", Value: 1})
+	assert.Equal(t, "servers.web01.cpu", m.ID)
+	assert.Nil(t, m.Labels)
+}
+
+func TestParseTemplateErrors(t *testing.T) {
+	for _, s := range []string{"", "a b c", "name*.host", "host-name.name", "[ name"} {
+		_, err := ParseTemplate(s)
+		assert.Error(t, err, s)
+	}
+}
@@ -0,0 +1,127 @@
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/middlewares"
+	"github.com/screamsoul/go-metrics-tpl/internal/pb"
+	"github.com/screamsoul/go-metrics-tpl/pkg/logging"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+func metadataValue(ctx context.Context, key string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// LoggingUnaryInterceptor logs unary calls, like middlewares.LoggingMiddleware.
+func LoggingUnaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	logger := logging.GetLogger()
+
+	start := time.Now()
+
+	logger.Info("Request received", zap.String("method", info.FullMethod))
+
+	resp, err := handler(ctx, req)
+
+	logger.Info("Response sent",
+		zap.String("status", status.Code(err).String()),
+		zap.Duration("duration", time.Since(start)),
+	)
+
+	return resp, err
+}
+
+// LoggingStreamInterceptor logs streaming calls, like middlewares.LoggingMiddleware.
+func LoggingStreamInterceptor(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	logger := logging.GetLogger()
+
+	start := time.Now()
+
+	logger.Info("Request received", zap.String("method", info.FullMethod))
+
+	err := handler(srv, ss)
+
+	logger.Info("Response sent",
+		zap.String("status", status.Code(err).String()),
+		zap.Duration("duration", time.Since(start)),
+	)
+
+	return err
+}
+
+// NewHashUnaryInterceptor checks the hash of the request, like middlewares.NewHashSumHeaderMiddleware.
+//
+// Requests without the hash metadata are passed as is. Streams are guarded
+// by NewHashStreamInterceptor, since metadata is sent before the messages.
+// The key is read on every call, so it can be replaced while the server is running.
+func NewHashUnaryInterceptor(key *middlewares.HashKey) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		reqHash := metadataValue(ctx, pb.HashMetadataKey)
+		hashKey := key.Load()
+
+		msg, ok := req.(proto.Message)
+		if reqHash == "" || hashKey == "" || !ok {
+			return handler(ctx, req)
+		}
+
+		hash, err := pb.HashSum(msg, hashKey)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+
+		if reqHash != hash {
+			return nil, status.Error(codes.InvalidArgument, "The data is corrupted")
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// NewHashStreamInterceptor refuses client streams while the hash key is set: the hash metadata
+// is sent before the messages, so they cannot be signed and signed batches go through UpdateMetrics.
+func NewHashStreamInterceptor(key *middlewares.HashKey) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if info.IsClientStream && key.Load() != "" {
+			return status.Error(codes.FailedPrecondition, "Streams cannot be signed, use UpdateMetrics")
+		}
+		return handler(srv, ss)
+	}
+}
+
+// NewTrustedSubnetInterceptors allows calls only from agents inside one of the trusted subnets,
+// like middlewares.NewTrustedSubnetMiddleware. The agent address is taken from the x-real-ip metadata.
+func NewTrustedSubnetInterceptors(cidrs []string) (grpc.UnaryServerInterceptor, grpc.StreamServerInterceptor, error) {
+	subnets, err := middlewares.ParseTrustedSubnets(cidrs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unary := func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if !subnets.Allows(metadataValue(ctx, pb.RealIPMetadataKey)) {
+			return nil, status.Error(codes.PermissionDenied, "Forbidden")
+		}
+		return handler(ctx, req)
+	}
+
+	stream := func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !subnets.Allows(metadataValue(ss.Context(), pb.RealIPMetadataKey)) {
+			return status.Error(codes.PermissionDenied, "Forbidden")
+		}
+		return handler(srv, ss)
+	}
+
+	return unary, stream, nil
+}
@@ -0,0 +1,171 @@
+// Package grpcserver implements the gRPC API of the metric server on top of repositories.MetricStorage.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/middlewares"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/pb"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"github.com/screamsoul/go-metrics-tpl/pkg/logging"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// bulkChunkSize the number of streamed metrics written to the storage at once.
+const bulkChunkSize = 100
+
+type MetricsServer struct {
+	pb.UnimplementedMetricsServer
+	store  repositories.MetricStorage
+	logger *zap.Logger
+}
+
+func NewMetricsServer(metricRepo repositories.MetricStorage) *MetricsServer {
+	logger := logging.GetLogger()
+
+	return &MetricsServer{store: metricRepo, logger: logger}
+}
+
+// NewServer creates a gRPC server of the metric storage with the interceptors
+// equivalent to the http middlewares.
+func NewServer(metricRepo repositories.MetricStorage, hashKey *middlewares.HashKey, trustedSubnets []string) (*grpc.Server, error) {
+	subnetUnary, subnetStream, err := NewTrustedSubnetInterceptors(trustedSubnets)
+	if err != nil {
+		return nil, err
+	}
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			LoggingUnaryInterceptor,
+			subnetUnary,
+			NewHashUnaryInterceptor(hashKey),
+		),
+		grpc.ChainStreamInterceptor(
+			LoggingStreamInterceptor,
+			subnetStream,
+			NewHashStreamInterceptor(hashKey),
+		),
+	)
+
+	pb.RegisterMetricsServer(server, NewMetricsServer(metricRepo))
+
+	return server, nil
+}
+
+func toValidMetric(metric *pb.Metric) (metrics.Metrics, error) {
+	m, err := pb.ToMetric(metric)
+	if err != nil {
+		return m, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := m.ValidateValue(); err != nil {
+		return m, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return m, nil
+}
+
+// UpdateMetrics updates a batch of metrics.
+func (s *MetricsServer) UpdateMetrics(ctx context.Context, req *pb.UpdateMetricsRequest) (*pb.UpdateMetricsResponse, error) {
+	metricsList := make([]metrics.Metrics, 0, len(req.GetMetrics()))
+	for _, metric := range req.GetMetrics() {
+		m, err := toValidMetric(metric)
+		if err != nil {
+			return nil, err
+		}
+		metricsList = append(metricsList, m)
+	}
+
+	if len(metricsList) > 0 {
+		if err := s.store.BulkAdd(ctx, metricsList); err != nil {
+			s.logger.Error("Error update metrics", zap.Error(err))
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+	}
+
+	return &pb.UpdateMetricsResponse{Accepted: int64(len(metricsList))}, nil
+}
+
+// StreamUpdateMetrics updates metrics received over the stream in chunks.
+// The stream is refused while the hash key is set, see NewHashStreamInterceptor.
+func (s *MetricsServer) StreamUpdateMetrics(stream pb.Metrics_StreamUpdateMetricsServer) error {
+	var (
+		accepted         int64
+		metricsListChunk []metrics.Metrics
+	)
+
+	flush := func() error {
+		if len(metricsListChunk) == 0 {
+			return nil
+		}
+		if err := s.store.BulkAdd(stream.Context(), metricsListChunk); err != nil {
+			s.logger.Error("Error update metrics chunk", zap.Error(err))
+			return status.Error(codes.Internal, err.Error())
+		}
+		accepted += int64(len(metricsListChunk))
+		metricsListChunk = metricsListChunk[:0]
+		return nil
+	}
+
+	for {
+		metric, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		m, err := toValidMetric(metric)
+		if err != nil {
+			return err
+		}
+
+		metricsListChunk = append(metricsListChunk, m)
+		if len(metricsListChunk) == bulkChunkSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := flush(); err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(&pb.UpdateMetricsResponse{Accepted: accepted})
+}
+
+// GetMetric returns the current value of the metric series.
+func (s *MetricsServer) GetMetric(ctx context.Context, req *pb.GetMetricRequest) (*pb.Metric, error) {
+	mType, err := pb.ToMetricType(req.GetType())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	m := metrics.Metrics{ID: req.GetId(), MType: mType, Labels: req.GetLabels()}
+	if err := s.store.Get(ctx, &m); err != nil {
+		if errors.Is(err, repositories.ErrMetricNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		s.logger.Error("Error get metric", zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return pb.FromMetric(m), nil
+}
+
+// ListMetrics returns current values of all metric series.
+func (s *MetricsServer) ListMetrics(ctx context.Context, _ *pb.ListMetricsRequest) (*pb.ListMetricsResponse, error) {
+	metricsList, err := s.store.List(ctx, repositories.ListQuery{})
+	if err != nil {
+		s.logger.Error("error read metrics", zap.Error(err))
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return &pb.ListMetricsResponse{Metrics: pb.FromMetrics(metricsList)}, nil
+}
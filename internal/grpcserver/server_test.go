@@ -0,0 +1,188 @@
+package grpcserver_test
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/grpcserver"
+	"github.com/screamsoul/go-metrics-tpl/internal/middlewares"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/pb"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestClient(t *testing.T, hashKey string, trustedSubnets []string) pb.MetricsClient {
+	server, err := grpcserver.NewServer(memory.NewMemStorage(), middlewares.NewHashKey(hashKey), trustedSubnets)
+	require.NoError(t, err)
+
+	listener := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return pb.NewMetricsClient(conn)
+}
+
+func TestUpdateAndGetMetrics(t *testing.T) {
+	client := newTestClient(t, "", nil)
+	ctx := context.Background()
+
+	value := 1.5
+	delta := int64(2)
+	resp, err := client.UpdateMetrics(ctx, &pb.UpdateMetricsRequest{Metrics: pb.FromMetrics([]metrics.Metrics{
+		{ID: "gauge", MType: metrics.Gauge, Value: &value},
+		{ID: "counter", MType: metrics.Counter, Delta: &delta, Labels: metrics.Labels{"host": "a"}},
+		{ID: "counter", MType: metrics.Counter, Delta: &delta, Labels: metrics.Labels{"host": "a"}},
+	})})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), resp.GetAccepted())
+
+	metric, err := client.GetMetric(ctx, &pb.GetMetricRequest{
+		Id: "counter", Type: pb.Metric_COUNTER, Labels: map[string]string{"host": "a"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), metric.GetDelta())
+
+	list, err := client.ListMetrics(ctx, &pb.ListMetricsRequest{})
+	require.NoError(t, err)
+	assert.Len(t, list.GetMetrics(), 2)
+}
+
+func TestStreamUpdateMetrics(t *testing.T) {
+	client := newTestClient(t, "", nil)
+	ctx := context.Background()
+
+	stream, err := client.StreamUpdateMetrics(ctx)
+	require.NoError(t, err)
+
+	for i := 0; i < 250; i++ {
+		delta := int64(1)
+		require.NoError(t, stream.Send(pb.FromMetric(metrics.Metrics{ID: "counter", MType: metrics.Counter, Delta: &delta})))
+	}
+
+	resp, err := stream.CloseAndRecv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(250), resp.GetAccepted())
+
+	metric, err := client.GetMetric(ctx, &pb.GetMetricRequest{Id: "counter", Type: pb.Metric_COUNTER})
+	require.NoError(t, err)
+	assert.Equal(t, int64(250), metric.GetDelta())
+}
+
+func TestInvalidRequests(t *testing.T) {
+	client := newTestClient(t, "", nil)
+	ctx := context.Background()
+
+	_, err := client.UpdateMetrics(ctx, &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{{Id: "gauge", Type: pb.Metric_GAUGE}}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.UpdateMetrics(ctx, &pb.UpdateMetricsRequest{Metrics: []*pb.Metric{{Id: "metric"}}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetMetric(ctx, &pb.GetMetricRequest{Id: "missing", Type: pb.Metric_GAUGE})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestHashInterceptor(t *testing.T) {
+	client := newTestClient(t, "testKey", nil)
+
+	req := &pb.UpdateMetricsRequest{Metrics: pb.FromMetrics([]metrics.Metrics{
+		{ID: "gauge", MType: metrics.Gauge, Value: new(float64)},
+	})}
+
+	hash, err := pb.HashSum(req, "testKey")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		hash string
+		code codes.Code
+	}{
+		{name: "Valid hash", hash: hash, code: codes.OK},
+		{name: "Invalid hash", hash: "invalidHash", code: codes.InvalidArgument},
+		{name: "Missing hash", hash: "", code: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.hash != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, pb.HashMetadataKey, tt.hash)
+			}
+
+			_, err := client.UpdateMetrics(ctx, req)
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestHashStreamInterceptor(t *testing.T) {
+	ctx := context.Background()
+
+	stream, err := newTestClient(t, "testKey", nil).StreamUpdateMetrics(ctx)
+	require.NoError(t, err)
+	_, err = stream.CloseAndRecv()
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	stream, err = newTestClient(t, "", nil).StreamUpdateMetrics(ctx)
+	require.NoError(t, err)
+	_, err = stream.CloseAndRecv()
+	assert.NoError(t, err)
+}
+
+func TestTrustedSubnetInterceptors(t *testing.T) {
+	client := newTestClient(t, "", []string{"192.168.1.0/24", "fd00::/8"})
+
+	tests := []struct {
+		name   string
+		realIP string
+		code   codes.Code
+	}{
+		{name: "Inside subnet", realIP: "192.168.1.15", code: codes.OK},
+		{name: "Inside IPv6 subnet", realIP: "fd00::1", code: codes.OK},
+		{name: "Outside subnet", realIP: "10.0.0.1", code: codes.PermissionDenied},
+		{name: "Missing address", realIP: "", code: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.realIP != "" {
+				ctx = metadata.AppendToOutgoingContext(ctx, pb.RealIPMetadataKey, tt.realIP)
+			}
+
+			_, err := client.ListMetrics(ctx, &pb.ListMetricsRequest{})
+			assert.Equal(t, tt.code, status.Code(err))
+
+			stream, err := client.StreamUpdateMetrics(ctx)
+			require.NoError(t, err)
+			_, err = stream.CloseAndRecv()
+			assert.Equal(t, tt.code, status.Code(err))
+		})
+	}
+}
+
+func TestNewServerInvalidSubnet(t *testing.T) {
+	_, err := grpcserver.NewServer(memory.NewMemStorage(), middlewares.NewHashKey(""), []string{"invalid"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"go.uber.org/zap"
+)
+
+const (
+	// dashboardPageSize the number of series on a dashboard page when the limit is not set.
+	dashboardPageSize = 200
+	// dashboardRefresh the default auto-refresh interval of the dashboard in seconds.
+	dashboardRefresh = 10
+
+	sparklineWidth  = 120
+	sparklineHeight = 24
+	sparklineRange  = time.Hour
+	sparklineStep   = time.Minute
+)
+
+//go:embed templates/dashboard.html
+var templatesFS embed.FS
+
+var dashboardTemplate = template.Must(template.ParseFS(templatesFS, "templates/dashboard.html"))
+
+// dashboardSeries a row of the dashboard table.
+type dashboardSeries struct {
+	Name      string
+	Labels    string
+	Value     string
+	Sparkline string
+}
+
+// dashboardGroup the series of one metric type.
+type dashboardGroup struct {
+	Type   metrics.MetricType
+	Series []dashboardSeries
+}
+
+// dashboardPage the data of the dashboard template.
+type dashboardPage struct {
+	Groups          []dashboardGroup
+	Types           []metrics.MetricType
+	Type            metrics.MetricType
+	Search          string
+	Refresh         int
+	RefreshOptions  []int
+	Sparklines      bool
+	SparklineWidth  int
+	SparklineHeight int
+	NextPage        string
+	UpdatedAt       time.Time
+}
+
+// acceptsJSON reports whether the client asks for the JSON representation.
+func acceptsJSON(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// dashboardValue formats the current value of the metric for the dashboard.
+func dashboardValue(m metrics.Metrics) string {
+	if m.MType == metrics.Histogram && m.Histogram != nil {
+		return fmt.Sprintf("count=%d sum=%g", m.Histogram.Count, m.Histogram.Sum)
+	}
+	value, err := m.GetValue()
+	if err != nil {
+		return "?"
+	}
+	return value
+}
+
+// sparkline returns the points of the svg polyline drawing the values.
+func sparkline(points []metrics.Point) string {
+	if len(points) < 2 {
+		return ""
+	}
+
+	low, high := points[0].Value, points[0].Value
+	for _, p := range points {
+		low, high = min(low, p.Value), max(high, p.Value)
+	}
+
+	var sb strings.Builder
+	for i, p := range points {
+		x := float64(i) * sparklineWidth / float64(len(points)-1)
+		y := float64(sparklineHeight) / 2
+		if high > low {
+			y = sparklineHeight - (p.Value-low)/(high-low)*sparklineHeight
+		}
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+		fmt.Fprintf(&sb, "%.1f,%.1f", x, y)
+	}
+	return sb.String()
+}
+
+// newDashboardPage builds the dashboard query and the page settings from the url query parameters:
+// the list parameters, `q` searching by a part of the name and `refresh` in seconds.
+func newDashboardPage(params url.Values) (repositories.ListQuery, dashboardPage, error) {
+	page := dashboardPage{
+		Types:           []metrics.MetricType{metrics.Gauge, metrics.Counter, metrics.Histogram},
+		Search:          params.Get("q"),
+		Refresh:         dashboardRefresh,
+		RefreshOptions:  []int{0, 5, 10, 30, 60},
+		SparklineWidth:  sparklineWidth,
+		SparklineHeight: sparklineHeight,
+		UpdatedAt:       time.Now(),
+	}
+
+	query, err := newListQuery(params)
+	if err != nil {
+		return query, page, err
+	}
+	page.Type = query.Type
+
+	if page.Search != "" {
+		if query.Regex != nil {
+			return query, page, errors.New("q and regex cannot be used together")
+		}
+		query.Regex = regexp.MustCompile("(?i)" + regexp.QuoteMeta(page.Search))
+	}
+	if query.Limit == 0 {
+		query.Limit = dashboardPageSize
+	}
+
+	if value := params.Get("refresh"); value != "" {
+		if page.Refresh, err = strconv.Atoi(value); err != nil || page.Refresh < 0 {
+			return query, page, errors.New("refresh must be a number of seconds")
+		}
+	}
+
+	return query, page, nil
+}
+
+// Dashboard handler, renders the html page with current metrics grouped by type.
+//
+// The page accepts the same parameters as the JSON list, the sparklines of the last hour
+// are drawn for gauges and counters when the storage keeps the history.
+func (ms *MetricServer) Dashboard(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+
+	query, page, err := newDashboardPage(params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := query.Limit
+	query.Limit++
+
+	metricsList, err := ms.store.List(r.Context(), query)
+	if err != nil {
+		ms.logger.Error("error read metrics", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if len(metricsList) > limit {
+		metricsList = metricsList[:limit]
+
+		next := url.Values{}
+		for k, v := range params {
+			next[k] = v
+		}
+		next.Set("cursor", repositories.NewCursor(metricsList[limit-1]).String())
+		page.NextPage = "/?" + next.Encode()
+	}
+
+	hs, hasHistory := ms.store.(repositories.HistoryStorage)
+	page.Sparklines = hasHistory
+
+	// the sparklines of all rows are read with one request.
+	sparklines := make(map[int]string)
+	if page.Sparklines {
+		var (
+			rows   []int
+			series []metrics.Metrics
+		)
+		for i, m := range metricsList {
+			if m.MType != metrics.Histogram {
+				rows = append(rows, i)
+				series = append(series, m)
+			}
+		}
+
+		history, err := repositories.HistoryBatch(r.Context(), hs, repositories.HistoryBatchQuery{
+			Metrics: series,
+			From:    page.UpdatedAt.Add(-sparklineRange),
+			To:      page.UpdatedAt,
+			Step:    sparklineStep,
+		})
+		switch {
+		case errors.Is(err, repositories.ErrHistoryNotSupported):
+			page.Sparklines = false
+		case err != nil:
+			ms.logger.Warn("error read metrics history", zap.Error(err))
+		default:
+			for i, points := range history {
+				sparklines[rows[i]] = sparkline(points)
+			}
+		}
+	}
+
+	groups := make(map[metrics.MetricType]*dashboardGroup)
+	for i, m := range metricsList {
+		row := dashboardSeries{
+			Name:      m.ID,
+			Labels:    m.Labels.String(),
+			Value:     dashboardValue(m),
+			Sparkline: sparklines[i],
+		}
+
+		group, ok := groups[m.MType]
+		if !ok {
+			group = &dashboardGroup{Type: m.MType}
+			groups[m.MType] = group
+		}
+		group.Series = append(group.Series, row)
+	}
+
+	for _, mType := range page.Types {
+		if group, ok := groups[mType]; ok {
+			page.Groups = append(page.Groups, *group)
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := dashboardTemplate.Execute(w, page); err != nil {
+		ms.logger.Error("Error writing response", zap.Error(err))
+	}
+}
@@ -0,0 +1,130 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/screamsoul/go-metrics-tpl/internal/handlers"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories/memory"
+	"github.com/screamsoul/go-metrics-tpl/internal/routers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// dashboardHistoryStub adds a fixed history to the memory storage.
+type dashboardHistoryStub struct {
+	*memory.MemStorage
+}
+
+func (s *dashboardHistoryStub) History(ctx context.Context, query repositories.HistoryQuery) ([]metrics.Point, error) {
+	return []metrics.Point{
+		{Timestamp: query.From, Value: 1},
+		{Timestamp: query.From.Add(query.Step), Value: 3},
+		{Timestamp: query.From.Add(2 * query.Step), Value: 2},
+	}, nil
+}
+
+// dashboardBatchHistoryStub reads the history of all series with one call.
+type dashboardBatchHistoryStub struct {
+	dashboardHistoryStub
+	calls int
+}
+
+func (s *dashboardBatchHistoryStub) HistoryBatch(ctx context.Context, query repositories.HistoryBatchQuery) ([][]metrics.Point, error) {
+	s.calls++
+
+	result := make([][]metrics.Point, len(query.Metrics))
+	for i, m := range query.Metrics {
+		points, err := s.History(ctx, repositories.HistoryQuery{Metric: m, From: query.From, To: query.To, Step: query.Step})
+		if err != nil {
+			return nil, err
+		}
+		result[i] = points
+	}
+	return result, nil
+}
+
+func newDashboardServer(t *testing.T, store repositories.MetricStorage) *httptest.Server {
+	ctx := context.Background()
+	for i, value := range []float64{1, 3, 2} {
+		delta := int64(i + 1)
+		require.NoError(t, store.BulkAdd(ctx, []metrics.Metrics{
+			{ID: "Alloc", MType: metrics.Gauge, Value: &value, Labels: metrics.Labels{"host": "a"}},
+			{ID: "PollCount", MType: metrics.Counter, Delta: &delta},
+		}))
+	}
+
+	server := httptest.NewServer(routers.NewMetricRouter(handlers.NewMetricServer(store)))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDashboard(t *testing.T) {
+	server := newDashboardServer(t, &dashboardHistoryStub{memory.NewMemStorage()})
+
+	resp, err := resty.New().R().SetHeader("Accept", "text/html").Get(server.URL + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode(), string(resp.Body()))
+	assert.Equal(t, "text/html; charset=utf-8", resp.Header().Get("Content-Type"))
+
+	body := string(resp.Body())
+	assert.Contains(t, body, `<meta http-equiv="refresh" content="10">`)
+	assert.Contains(t, body, `<input type="search" name="q"`)
+	assert.Contains(t, body, "<h2>gauge</h2>")
+	assert.Contains(t, body, "<h2>counter</h2>")
+	assert.Contains(t, body, `{host=&#34;a&#34;}`)
+	assert.Contains(t, body, `<td class="value">6</td>`)
+	assert.Contains(t, body, `<polyline points="0.0,24.0 60.0,0.0 120.0,12.0"/>`)
+	assert.NotContains(t, body, "Next page")
+
+	resp, err = resty.New().R().Get(server.URL + "/?q=alloc&refresh=0&limit=1")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode(), string(resp.Body()))
+
+	body = string(resp.Body())
+	assert.NotContains(t, body, "http-equiv")
+	assert.Contains(t, body, "Alloc")
+	assert.NotContains(t, body, "PollCount")
+	assert.NotContains(t, body, "Next page")
+
+	resp, err = resty.New().R().Get(server.URL + "/?limit=1")
+	require.NoError(t, err)
+	assert.Contains(t, string(resp.Body()), "Next page")
+
+	for _, query := range []string{"refresh=-1", "q=a&regex=b", "type=fake"} {
+		resp, err := resty.New().R().Get(server.URL + "/?" + query)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode(), query)
+	}
+}
+
+func TestDashboardWithoutHistory(t *testing.T) {
+	server := newDashboardServer(t, memory.NewMemStorage())
+
+	resp, err := resty.New().R().Get(server.URL + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	body := string(resp.Body())
+	assert.Contains(t, body, "Alloc")
+	assert.NotContains(t, body, "<svg")
+	assert.NotContains(t, body, "Last hour")
+}
+
+func TestDashboardReadsHistoryInOneBatch(t *testing.T) {
+	store := &dashboardBatchHistoryStub{dashboardHistoryStub: dashboardHistoryStub{memory.NewMemStorage()}}
+	server := newDashboardServer(t, store)
+
+	resp, err := resty.New().R().Get(server.URL + "/")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+
+	assert.Equal(t, 1, store.calls)
+	assert.Equal(t, 2, strings.Count(string(resp.Body()), `<polyline points="0.0,24.0 60.0,0.0 120.0,12.0"/>`))
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"go.uber.org/zap"
+)
+
+// DeleteResult the response of the bulk delete.
+type DeleteResult struct {
+	Deleted int64 `json:"deleted"`
+}
+
+// newSeriesMetric builds the metric series from the path values, url query parameters are treated as labels.
+func newSeriesMetric(r *http.Request, params url.Values) (*metrics.Metrics, error) {
+	metricObj, err := metrics.NewMetric(
+		r.PathValue("metric_type"),
+		r.PathValue("metric_name"),
+		"",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, values := range params {
+		if metricObj.Labels == nil {
+			metricObj.Labels = make(metrics.Labels)
+		}
+		metricObj.Labels[name] = values[len(values)-1]
+	}
+
+	if err := metricObj.Labels.Validate(); err != nil {
+		return nil, err
+	}
+	return metricObj, nil
+}
+
+// writeStorageError writes 404 for a missing series and 500 for other storage errors.
+func (ms *MetricServer) writeStorageError(w http.ResponseWriter, err error, msg string) {
+	if errors.Is(err, repositories.ErrMetricNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	ms.logger.Error(msg, zap.Error(err))
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+// DeleteMetric handler, deletes the metric series by type, name and labels from the query.
+func (ms *MetricServer) DeleteMetric(w http.ResponseWriter, r *http.Request) {
+	metricObj, err := newSeriesMetric(r, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := ms.store.Delete(r.Context(), *metricObj); err != nil {
+		ms.writeStorageError(w, err, "error delete metric")
+		return
+	}
+}
+
+// DeleteMetricsByPrefix handler, deletes all series whose name starts with the `prefix` query parameter.
+func (ms *MetricServer) DeleteMetricsByPrefix(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	if prefix == "" {
+		http.Error(w, "prefix is required", http.StatusBadRequest)
+		return
+	}
+
+	deleted, err := ms.store.DeleteByPrefix(r.Context(), prefix)
+	if err != nil {
+		ms.logger.Error("error delete metrics", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(DeleteResult{Deleted: deleted}); err != nil {
+		ms.logger.Error("Error writing response", zap.Error(err))
+	}
+}
+
+// ResetMetric handler, sets the counter series by name and labels from the query to zero.
+func (ms *MetricServer) ResetMetric(w http.ResponseWriter, r *http.Request) {
+	metricObj, err := newSeriesMetric(r, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if metricObj.MType != metrics.Counter {
+		http.Error(w, "only counters can be reset", http.StatusBadRequest)
+		return
+	}
+
+	if err := ms.store.Reset(r.Context(), *metricObj); err != nil {
+		ms.writeStorageError(w, err, "error reset metric")
+		return
+	}
+}
@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/gojuno/minimock/v3"
+	"github.com/screamsoul/go-metrics-tpl/internal/handlers"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"github.com/screamsoul/go-metrics-tpl/internal/routers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeleteMetric(t *testing.T) {
+	store := NewMetricStorageMock(minimock.NewController(t))
+	store.DeleteMock.Set(func(ctx context.Context, m metrics.Metrics) error {
+		switch m.ID {
+		case "missing":
+			return repositories.ErrMetricNotFound
+		case "broken":
+			return errors.New("some err")
+		}
+		assert.Equal(t, `Alloc{host="a"}`, m.SeriesKey())
+		assert.Equal(t, metrics.Gauge, m.MType)
+		return nil
+	})
+
+	server := httptest.NewServer(routers.NewMetricRouter(handlers.NewMetricServer(store)))
+	defer server.Close()
+
+	testCases := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{name: "ok", path: "/value/gauge/Alloc?host=a", status: http.StatusOK},
+		{name: "not found", path: "/value/gauge/missing", status: http.StatusNotFound},
+		{name: "bad type", path: "/value/fake/Alloc", status: http.StatusBadRequest},
+		{name: "storage error", path: "/value/gauge/broken", status: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := resty.New().R().Delete(server.URL + tc.path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.status, resp.StatusCode(), string(resp.Body()))
+		})
+	}
+}
+
+func TestDeleteMetricsByPrefix(t *testing.T) {
+	store := NewMetricStorageMock(minimock.NewController(t))
+	store.DeleteByPrefixMock.Set(func(ctx context.Context, prefix string) (int64, error) {
+		if prefix == "broken" {
+			return 0, errors.New("some err")
+		}
+		return 3, nil
+	})
+
+	server := httptest.NewServer(routers.NewMetricRouter(handlers.NewMetricServer(store)))
+	defer server.Close()
+
+	resp, err := resty.New().R().Delete(server.URL + "/value/?prefix=cpu_")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.JSONEq(t, `{"deleted":3}`, string(resp.Body()))
+
+	resp, err = resty.New().R().Delete(server.URL + "/value/")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+
+	resp, err = resty.New().R().Delete(server.URL + "/value/?prefix=broken")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode())
+}
+
+func TestResetMetric(t *testing.T) {
+	store := NewMetricStorageMock(minimock.NewController(t))
+	store.ResetMock.Set(func(ctx context.Context, m metrics.Metrics) error {
+		if m.ID == "missing" {
+			return repositories.ErrMetricNotFound
+		}
+		return nil
+	})
+
+	server := httptest.NewServer(routers.NewMetricRouter(handlers.NewMetricServer(store)))
+	defer server.Close()
+
+	testCases := []struct {
+		name   string
+		path   string
+		status int
+	}{
+		{name: "ok", path: "/reset/counter/PollCount", status: http.StatusOK},
+		{name: "not found", path: "/reset/counter/missing", status: http.StatusNotFound},
+		{name: "gauge", path: "/reset/gauge/Alloc", status: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := resty.New().R().Post(server.URL + tc.path)
+			require.NoError(t, err)
+			assert.Equal(t, tc.status, resp.StatusCode(), string(resp.Body()))
+		})
+	}
+}
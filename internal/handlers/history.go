@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"go.uber.org/zap"
+)
+
+// defaultHistoryRange the range of history returned when `from` is not set.
+const defaultHistoryRange = time.Hour
+
+// parseHistoryTime parses the time as RFC3339 or unix seconds.
+func parseHistoryTime(value string) (time.Time, error) {
+	if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// parseHistoryStep parses the step as a duration (`1m`) or a number of seconds.
+func parseHistoryStep(value string) (time.Duration, error) {
+	if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(sec) * time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// newHistoryQuery builds the history query from the metric and the url query parameters,
+// parameters other than from, to and step are treated as metric labels.
+func newHistoryQuery(metric metrics.Metrics, params url.Values) (query repositories.HistoryQuery, err error) {
+	query.To = time.Now()
+
+	for name, values := range params {
+		value := values[len(values)-1]
+
+		switch name {
+		case "from":
+			if query.From, err = parseHistoryTime(value); err != nil {
+				return query, fmt.Errorf("bad from: %w", err)
+			}
+		case "to":
+			if query.To, err = parseHistoryTime(value); err != nil {
+				return query, fmt.Errorf("bad to: %w", err)
+			}
+		case "step":
+			if query.Step, err = parseHistoryStep(value); err != nil {
+				return query, fmt.Errorf("bad step: %w", err)
+			}
+		default:
+			if metric.Labels == nil {
+				metric.Labels = make(metrics.Labels)
+			}
+			metric.Labels[name] = value
+		}
+	}
+
+	if query.From.IsZero() {
+		query.From = query.To.Add(-defaultHistoryRange)
+	}
+	if !query.From.Before(query.To) {
+		return query, errors.New("from must be before to")
+	}
+	if query.Step < 0 {
+		return query, errors.New("step must be positive")
+	}
+	if err := metric.Labels.Validate(); err != nil {
+		return query, err
+	}
+
+	query.Metric = metric
+	return query, nil
+}
+
+// GetMetricHistory handler, returns the points of a metric series in the requested range.
+func (ms *MetricServer) GetMetricHistory(w http.ResponseWriter, r *http.Request) {
+	metricObj, err := metrics.NewMetric(
+		r.PathValue("metric_type"),
+		r.PathValue("metric_name"),
+		"",
+	)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if metricObj.MType == metrics.Histogram {
+		http.Error(w, "history is not available for histograms", http.StatusBadRequest)
+		return
+	}
+
+	query, err := newHistoryQuery(*metricObj, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hs, ok := ms.store.(repositories.HistoryStorage)
+	if !ok {
+		http.Error(w, repositories.ErrHistoryNotSupported.Error(), http.StatusNotImplemented)
+		return
+	}
+
+	points, err := hs.History(r.Context(), query)
+	if errors.Is(err, repositories.ErrHistoryNotSupported) {
+		http.Error(w, err.Error(), http.StatusNotImplemented)
+		return
+	}
+	if err != nil {
+		ms.logger.Error("error read metric history", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if points == nil {
+		points = []metrics.Point{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(points); err != nil {
+		ms.logger.Error("Error writing response", zap.Error(err))
+	}
+}
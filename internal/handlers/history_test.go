@@ -0,0 +1,108 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/gojuno/minimock/v3"
+	"github.com/screamsoul/go-metrics-tpl/internal/handlers"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"github.com/screamsoul/go-metrics-tpl/internal/routers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// historyStorageStub adds the history support to the metric storage mock.
+type historyStorageStub struct {
+	*MetricStorageMock
+	history func(ctx context.Context, query repositories.HistoryQuery) ([]metrics.Point, error)
+}
+
+func (s *historyStorageStub) History(ctx context.Context, query repositories.HistoryQuery) ([]metrics.Point, error) {
+	return s.history(ctx, query)
+}
+
+func TestGetMetricHistory(t *testing.T) {
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	var lastQuery repositories.HistoryQuery
+	store := &historyStorageStub{
+		MetricStorageMock: NewMetricStorageMock(minimock.NewController(t)),
+		history: func(ctx context.Context, query repositories.HistoryQuery) ([]metrics.Point, error) {
+			lastQuery = query
+			if query.Metric.ID == "broken" {
+				return nil, errors.New("some err")
+			}
+			return []metrics.Point{{Timestamp: from, Value: 1.5}}, nil
+		},
+	}
+
+	server := httptest.NewServer(routers.NewMetricRouter(handlers.NewMetricServer(store)))
+	defer server.Close()
+
+	testCases := []struct {
+		name   string
+		path   string
+		status int
+		check  func(t *testing.T, body []byte)
+	}{
+		{
+			name:   "ok",
+			path:   "/history/gauge/Alloc?from=2024-06-01T00:00:00Z&to=1717203600&step=1m&host=a",
+			status: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				var points []metrics.Point
+				require.NoError(t, json.Unmarshal(body, &points))
+				assert.Equal(t, []metrics.Point{{Timestamp: from, Value: 1.5}}, points)
+
+				assert.Equal(t, from, lastQuery.From.UTC())
+				assert.Equal(t, from.Add(time.Hour), lastQuery.To.UTC())
+				assert.Equal(t, time.Minute, lastQuery.Step)
+				assert.Equal(t, `Alloc{host="a"}`, lastQuery.Metric.SeriesKey())
+			},
+		},
+		{
+			name:   "default range",
+			path:   "/history/counter/PollCount",
+			status: http.StatusOK,
+			check: func(t *testing.T, body []byte) {
+				assert.Equal(t, time.Hour, lastQuery.To.Sub(lastQuery.From))
+				assert.Zero(t, lastQuery.Step)
+			},
+		},
+		{name: "bad type", path: "/history/fake/Alloc", status: http.StatusBadRequest},
+		{name: "histogram", path: "/history/histogram/latency", status: http.StatusBadRequest},
+		{name: "bad step", path: "/history/gauge/Alloc?step=abc", status: http.StatusBadRequest},
+		{name: "bad range", path: "/history/gauge/Alloc?from=200&to=100", status: http.StatusBadRequest},
+		{name: "storage error", path: "/history/gauge/broken", status: http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := resty.New().R().Get(server.URL + tc.path)
+			require.NoError(t, err)
+			require.Equal(t, tc.status, resp.StatusCode(), string(resp.Body()))
+			if tc.check != nil {
+				tc.check(t, resp.Body())
+			}
+		})
+	}
+}
+
+func TestGetMetricHistoryNotSupported(t *testing.T) {
+	store := NewMetricStorageMock(minimock.NewController(t))
+
+	server := httptest.NewServer(routers.NewMetricRouter(handlers.NewMetricServer(store)))
+	defer server.Close()
+
+	resp, err := resty.New().R().Get(server.URL + "/history/gauge/Alloc")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNotImplemented, resp.StatusCode())
+}
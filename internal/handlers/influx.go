@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/influx"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxInfluxLineSize the maximum length of a line protocol line.
+	maxInfluxLineSize = 1024 * 1024
+	// maxInfluxLineErrors the maximum number of rejected lines listed in the response.
+	maxInfluxLineErrors = 100
+	// maxInfluxTimestampSkew the maximum difference of a line timestamp from the server time.
+	maxInfluxTimestampSkew = 10 * time.Minute
+)
+
+// InfluxLineError a rejected line of the line protocol body.
+type InfluxLineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// InfluxWriteError the response of a partial write.
+type InfluxWriteError struct {
+	Error string            `json:"error"`
+	Lines []InfluxLineError `json:"lines"`
+}
+
+// WriteInflux handler, accepts metrics in the InfluxDB line protocol.
+//
+// Every field becomes a metric `measurement_field` with tags as labels: float and boolean
+// fields are gauges, string fields are skipped. Integer fields are counters, their values are
+// added to the stored ones, so cumulative integer fields (e.g. of Telegraf) must be sent
+// with `integers=gauge`, which stores them as gauges.
+// The storage keeps only current values, so lines with timestamps more than
+// maxInfluxTimestampSkew away from the server time are rejected.
+// Valid lines are written even if other lines are rejected, in that case 400 is returned
+// with the list of rejected lines, otherwise 204.
+func (ms *MetricServer) WriteInflux(w http.ResponseWriter, r *http.Request) {
+	precision, err := influx.Precision(r.URL.Query().Get("precision"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	integerType, err := influx.IntegerType(r.URL.Query().Get("integers"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var (
+		chunk    []metrics.Metrics
+		rejected []InfluxLineError
+		total    int
+		now      = time.Now()
+	)
+
+	flush := func() error {
+		if len(chunk) == 0 {
+			return nil
+		}
+		err := ms.store.BulkAdd(r.Context(), chunk)
+		chunk = chunk[:0]
+		return err
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 64*1024), maxInfluxLineSize)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		total++
+
+		point, err := influx.ParseLine(line, precision, now)
+		if err == nil && (point.Timestamp.Before(now.Add(-maxInfluxTimestampSkew)) || point.Timestamp.After(now.Add(maxInfluxTimestampSkew))) {
+			err = fmt.Errorf("timestamp %s is out of range, only current values are stored", point.Timestamp.UTC().Format(time.RFC3339))
+		}
+		var lineMetrics []metrics.Metrics
+		if err == nil {
+			lineMetrics, err = point.Metrics(integerType)
+		}
+		if err != nil {
+			rejected = append(rejected, InfluxLineError{Line: lineNumber, Error: err.Error()})
+			continue
+		}
+
+		chunk = append(chunk, lineMetrics...)
+		if len(chunk) >= 100 {
+			if err := flush(); err != nil {
+				ms.logger.Error("Error update metrics chunk", zap.Error(err))
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := flush(); err != nil {
+		ms.logger.Error("Error update metrics chunk", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if len(rejected) == 0 {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	response := InfluxWriteError{
+		Error: fmt.Sprintf("partial write: %d of %d lines rejected", len(rejected), total),
+		Lines: rejected[:min(len(rejected), maxInfluxLineErrors)],
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		ms.logger.Error("Error writing response", zap.Error(err))
+	}
+}
@@ -0,0 +1,97 @@
+package handlers_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/screamsoul/go-metrics-tpl/internal/handlers"
+	"github.com/screamsoul/go-metrics-tpl/internal/middlewares"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories/memory"
+	"github.com/screamsoul/go-metrics-tpl/internal/routers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteInflux(t *testing.T) {
+	store := memory.NewMemStorage()
+
+	server := httptest.NewServer(routers.NewMetricRouter(
+		handlers.NewMetricServer(store),
+		middlewares.GzipDecompressMiddleware,
+	))
+	defer server.Close()
+
+	ctx := context.Background()
+
+	resp, err := resty.New().R().
+		SetBody(fmt.Sprintf("# comment\ncpu,host=a usage=0.5,count=2i\n\nmem free=10 %d\n", time.Now().Unix())).
+		Post(server.URL + "/write?precision=s")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNoContent, resp.StatusCode(), string(resp.Body()))
+
+	usage := metrics.Metrics{ID: "cpu_usage", MType: metrics.Gauge, Labels: metrics.Labels{"host": "a"}}
+	require.NoError(t, store.Get(ctx, &usage))
+	assert.Equal(t, 0.5, *usage.Value)
+
+	var body bytes.Buffer
+	zw := gzip.NewWriter(&body)
+	_, err = zw.Write([]byte("cpu,host=a count=3i\ncpu usage\nmem free=abc\n"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	resp, err = resty.New().R().
+		SetHeader("Content-Encoding", "gzip").
+		SetBody(body.Bytes()).
+		Post(server.URL + "/write")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode(), string(resp.Body()))
+
+	var writeErr handlers.InfluxWriteError
+	require.NoError(t, json.Unmarshal(resp.Body(), &writeErr))
+	assert.Equal(t, "partial write: 2 of 3 lines rejected", writeErr.Error)
+	require.Len(t, writeErr.Lines, 2)
+	assert.Equal(t, 2, writeErr.Lines[0].Line)
+	assert.Equal(t, 3, writeErr.Lines[1].Line)
+
+	// the valid line of the partial write is stored
+	count := metrics.Metrics{ID: "cpu_count", MType: metrics.Counter, Labels: metrics.Labels{"host": "a"}}
+	require.NoError(t, store.Get(ctx, &count))
+	assert.Equal(t, int64(5), *count.Delta)
+
+	resp, err = resty.New().R().SetBody("mem free=1").Post(server.URL + "/write?precision=h")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+
+	resp, err = resty.New().R().SetBody("mem free=1").Post(server.URL + "/write?integers=histogram")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+
+	// a point of the past is not stored as the current value.
+	resp, err = resty.New().R().SetBody("mem free=20 1717200000").Post(server.URL + "/write?precision=s")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusBadRequest, resp.StatusCode(), string(resp.Body()))
+	assert.Contains(t, string(resp.Body()), "out of range")
+
+	free := metrics.Metrics{ID: "mem_free", MType: metrics.Gauge}
+	require.NoError(t, store.Get(ctx, &free))
+	assert.Equal(t, 10.0, *free.Value)
+
+	// cumulative integer fields are stored as current values.
+	for _, body := range []string{"net bytes=100i", "net bytes=150i"} {
+		resp, err = resty.New().R().SetBody(body).Post(server.URL + "/write?integers=gauge")
+		require.NoError(t, err)
+		require.Equal(t, http.StatusNoContent, resp.StatusCode(), string(resp.Body()))
+	}
+	bytesSent := metrics.Metrics{ID: "net_bytes", MType: metrics.Gauge}
+	require.NoError(t, store.Get(ctx, &bytesSent))
+	assert.Equal(t, 150.0, *bytesSent.Value)
+}
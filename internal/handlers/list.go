@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+)
+
+// NextCursorHeader the header with the cursor of the next page of the metrics list.
+const NextCursorHeader = "X-Next-Cursor"
+
+// newListQuery builds the list query from the url query parameters:
+// type, prefix, regex, sort (`name` or `-name`), limit and cursor.
+func newListQuery(params url.Values) (query repositories.ListQuery, err error) {
+	if value := params.Get("type"); value != "" {
+		query.Type = metrics.MetricType(value)
+		if !query.Type.IsValid() {
+			return query, fmt.Errorf("bad type `%s`", value)
+		}
+	}
+
+	query.Prefix = params.Get("prefix")
+
+	if value := params.Get("regex"); value != "" {
+		if query.Regex, err = regexp.Compile(value); err != nil {
+			return query, fmt.Errorf("bad regex: %w", err)
+		}
+	}
+
+	switch params.Get("sort") {
+	case "", "name":
+	case "-name":
+		query.Desc = true
+	default:
+		return query, errors.New("sort must be `name` or `-name`")
+	}
+
+	if value := params.Get("limit"); value != "" {
+		if query.Limit, err = strconv.Atoi(value); err != nil || query.Limit <= 0 {
+			return query, errors.New("limit must be a positive number")
+		}
+	}
+
+	if value := params.Get("cursor"); value != "" {
+		if query.After, err = repositories.ParseCursor(value); err != nil {
+			return query, err
+		}
+	}
+
+	return query, nil
+}
@@ -0,0 +1,70 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/gojuno/minimock/v3"
+	"github.com/screamsoul/go-metrics-tpl/internal/handlers"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"github.com/screamsoul/go-metrics-tpl/internal/routers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListMetricsQuery(t *testing.T) {
+	var value = 1.5
+	list := []metrics.Metrics{
+		{ID: "cpu_a", MType: metrics.Gauge, Value: &value},
+		{ID: "cpu_b", MType: metrics.Gauge, Value: &value},
+		{ID: "cpu_c", MType: metrics.Gauge, Value: &value},
+	}
+
+	var lastQuery repositories.ListQuery
+	store := NewMetricStorageMock(minimock.NewController(t))
+	store.ListMock.Set(func(ctx context.Context, query repositories.ListQuery) ([]metrics.Metrics, error) {
+		lastQuery = query
+		if query.Limit > 0 && query.Limit < len(list) {
+			return list[:query.Limit], nil
+		}
+		return list, nil
+	})
+
+	server := httptest.NewServer(routers.NewMetricRouter(handlers.NewMetricServer(store)))
+	defer server.Close()
+
+	cursor := repositories.NewCursor(metrics.Metrics{ID: "cpu", MType: metrics.Gauge}).String()
+
+	resp, err := resty.New().R().SetHeader("Accept", "application/json").Get(server.URL + "/?type=gauge&prefix=cpu_&regex=^cpu&sort=-name&limit=2&cursor=" + cursor)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode(), string(resp.Body()))
+	assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+	assert.JSONEq(t, `[
+		{"id": "cpu_a", "type": "gauge", "value": 1.5},
+		{"id": "cpu_b", "type": "gauge", "value": 1.5}
+	]`, string(resp.Body()))
+	assert.Equal(t, repositories.NewCursor(list[1]).String(), resp.Header().Get(handlers.NextCursorHeader))
+
+	assert.Equal(t, metrics.Gauge, lastQuery.Type)
+	assert.Equal(t, "cpu_", lastQuery.Prefix)
+	assert.Equal(t, "^cpu", lastQuery.Regex.String())
+	assert.True(t, lastQuery.Desc)
+	assert.Equal(t, 3, lastQuery.Limit)
+	assert.Equal(t, &repositories.Cursor{Key: "cpu", MType: metrics.Gauge}, lastQuery.After)
+
+	// the last page has no next cursor
+	resp, err = resty.New().R().SetHeader("Accept", "application/json").Get(server.URL + "/?limit=3")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Empty(t, resp.Header().Get(handlers.NextCursorHeader))
+
+	for _, query := range []string{"type=fake", "regex=(", "sort=value", "limit=0", "limit=abc", "cursor=!!"} {
+		resp, err := resty.New().R().SetHeader("Accept", "application/json").Get(server.URL + "/?" + query)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode(), query)
+	}
+}
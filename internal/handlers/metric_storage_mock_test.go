@@ -10,6 +10,7 @@ import (
 
 	"github.com/gojuno/minimock/v3"
 	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	mm_repositories "github.com/screamsoul/go-metrics-tpl/internal/repositories"
 )
 
 // MetricStorageMock implements repositories.MetricStorage
@@ -29,14 +30,26 @@ type MetricStorageMock struct {
 	beforeBulkAddCounter uint64
 	BulkAddMock          mMetricStorageMockBulkAdd
 
+	funcDelete          func(ctx context.Context, m metrics.Metrics) (err error)
+	inspectFuncDelete   func(ctx context.Context, m metrics.Metrics)
+	afterDeleteCounter  uint64
+	beforeDeleteCounter uint64
+	DeleteMock          mMetricStorageMockDelete
+
+	funcDeleteByPrefix          func(ctx context.Context, prefix string) (i1 int64, err error)
+	inspectFuncDeleteByPrefix   func(ctx context.Context, prefix string)
+	afterDeleteByPrefixCounter  uint64
+	beforeDeleteByPrefixCounter uint64
+	DeleteByPrefixMock          mMetricStorageMockDeleteByPrefix
+
 	funcGet          func(ctx context.Context, m *metrics.Metrics) (err error)
 	inspectFuncGet   func(ctx context.Context, m *metrics.Metrics)
 	afterGetCounter  uint64
 	beforeGetCounter uint64
 	GetMock          mMetricStorageMockGet
 
-	funcList          func(ctx context.Context) (ma1 []metrics.Metrics, err error)
-	inspectFuncList   func(ctx context.Context)
+	funcList          func(ctx context.Context, query mm_repositories.ListQuery) (ma1 []metrics.Metrics, err error)
+	inspectFuncList   func(ctx context.Context, query mm_repositories.ListQuery)
 	afterListCounter  uint64
 	beforeListCounter uint64
 	ListMock          mMetricStorageMockList
@@ -46,6 +59,12 @@ type MetricStorageMock struct {
 	afterPingCounter  uint64
 	beforePingCounter uint64
 	PingMock          mMetricStorageMockPing
+
+	funcReset          func(ctx context.Context, m metrics.Metrics) (err error)
+	inspectFuncReset   func(ctx context.Context, m metrics.Metrics)
+	afterResetCounter  uint64
+	beforeResetCounter uint64
+	ResetMock          mMetricStorageMockReset
 }
 
 // NewMetricStorageMock returns a mock for repositories.MetricStorage
@@ -62,6 +81,12 @@ func NewMetricStorageMock(t minimock.Tester) *MetricStorageMock {
 	m.BulkAddMock = mMetricStorageMockBulkAdd{mock: m}
 	m.BulkAddMock.callArgs = []*MetricStorageMockBulkAddParams{}
 
+	m.DeleteMock = mMetricStorageMockDelete{mock: m}
+	m.DeleteMock.callArgs = []*MetricStorageMockDeleteParams{}
+
+	m.DeleteByPrefixMock = mMetricStorageMockDeleteByPrefix{mock: m}
+	m.DeleteByPrefixMock.callArgs = []*MetricStorageMockDeleteByPrefixParams{}
+
 	m.GetMock = mMetricStorageMockGet{mock: m}
 	m.GetMock.callArgs = []*MetricStorageMockGetParams{}
 
@@ -71,6 +96,9 @@ func NewMetricStorageMock(t minimock.Tester) *MetricStorageMock {
 	m.PingMock = mMetricStorageMockPing{mock: m}
 	m.PingMock.callArgs = []*MetricStorageMockPingParams{}
 
+	m.ResetMock = mMetricStorageMockReset{mock: m}
+	m.ResetMock.callArgs = []*MetricStorageMockResetParams{}
+
 	t.Cleanup(m.MinimockFinish)
 
 	return m
@@ -215,11 +243,10 @@ func (mmAdd *MetricStorageMock) Add(ctx context.Context, m metrics.Metrics) (err
 		}
 
 		mm_results := mmAdd.AddMock.defaultExpectation.results
-		if mm_results != nil {
-			return (*mm_results).err
+		if mm_results == nil {
+			mmAdd.t.Fatal("No results are set for the MetricStorageMock.Add")
 		}
-		mmAdd.t.Fatal("No results are set for the MetricStorageMock.Add")
-
+		return (*mm_results).err
 	}
 	if mmAdd.funcAdd != nil {
 		return mmAdd.funcAdd(ctx, m)
@@ -432,11 +459,10 @@ func (mmBulkAdd *MetricStorageMock) BulkAdd(ctx context.Context, m []metrics.Met
 		}
 
 		mm_results := mmBulkAdd.BulkAddMock.defaultExpectation.results
-		if mm_results != nil {
-			return (*mm_results).err
+		if mm_results == nil {
+			mmBulkAdd.t.Fatal("No results are set for the MetricStorageMock.BulkAdd")
 		}
-		mmBulkAdd.t.Fatal("No results are set for the MetricStorageMock.BulkAdd")
-
+		return (*mm_results).err
 	}
 	if mmBulkAdd.funcBulkAdd != nil {
 		return mmBulkAdd.funcBulkAdd(ctx, m)
@@ -510,6 +536,439 @@ func (m *MetricStorageMock) MinimockBulkAddInspect() {
 	}
 }
 
+type mMetricStorageMockDelete struct {
+	mock               *MetricStorageMock
+	defaultExpectation *MetricStorageMockDeleteExpectation
+	expectations       []*MetricStorageMockDeleteExpectation
+
+	callArgs []*MetricStorageMockDeleteParams
+	mutex    sync.RWMutex
+}
+
+// MetricStorageMockDeleteExpectation specifies expectation struct of the MetricStorage.Delete
+type MetricStorageMockDeleteExpectation struct {
+	mock    *MetricStorageMock
+	params  *MetricStorageMockDeleteParams
+	results *MetricStorageMockDeleteResults
+	Counter uint64
+}
+
+// MetricStorageMockDeleteParams contains parameters of the MetricStorage.Delete
+type MetricStorageMockDeleteParams struct {
+	ctx context.Context
+	m   metrics.Metrics
+}
+
+// MetricStorageMockDeleteResults contains results of the MetricStorage.Delete
+type MetricStorageMockDeleteResults struct {
+	err error
+}
+
+// Expect sets up expected params for MetricStorage.Delete
+func (mmDelete *mMetricStorageMockDelete) Expect(ctx context.Context, m metrics.Metrics) *mMetricStorageMockDelete {
+	if mmDelete.mock.funcDelete != nil {
+		mmDelete.mock.t.Fatalf("MetricStorageMock.Delete mock is already set by Set")
+	}
+
+	if mmDelete.defaultExpectation == nil {
+		mmDelete.defaultExpectation = &MetricStorageMockDeleteExpectation{}
+	}
+
+	mmDelete.defaultExpectation.params = &MetricStorageMockDeleteParams{ctx, m}
+	for _, e := range mmDelete.expectations {
+		if minimock.Equal(e.params, mmDelete.defaultExpectation.params) {
+			mmDelete.mock.t.Fatalf("Expectation set by When has same params: %#v", *mmDelete.defaultExpectation.params)
+		}
+	}
+
+	return mmDelete
+}
+
+// Inspect accepts an inspector function that has same arguments as the MetricStorage.Delete
+func (mmDelete *mMetricStorageMockDelete) Inspect(f func(ctx context.Context, m metrics.Metrics)) *mMetricStorageMockDelete {
+	if mmDelete.mock.inspectFuncDelete != nil {
+		mmDelete.mock.t.Fatalf("Inspect function is already set for MetricStorageMock.Delete")
+	}
+
+	mmDelete.mock.inspectFuncDelete = f
+
+	return mmDelete
+}
+
+// Return sets up results that will be returned by MetricStorage.Delete
+func (mmDelete *mMetricStorageMockDelete) Return(err error) *MetricStorageMock {
+	if mmDelete.mock.funcDelete != nil {
+		mmDelete.mock.t.Fatalf("MetricStorageMock.Delete mock is already set by Set")
+	}
+
+	if mmDelete.defaultExpectation == nil {
+		mmDelete.defaultExpectation = &MetricStorageMockDeleteExpectation{mock: mmDelete.mock}
+	}
+	mmDelete.defaultExpectation.results = &MetricStorageMockDeleteResults{err}
+	return mmDelete.mock
+}
+
+// Set uses given function f to mock the MetricStorage.Delete method
+func (mmDelete *mMetricStorageMockDelete) Set(f func(ctx context.Context, m metrics.Metrics) (err error)) *MetricStorageMock {
+	if mmDelete.defaultExpectation != nil {
+		mmDelete.mock.t.Fatalf("Default expectation is already set for the MetricStorage.Delete method")
+	}
+
+	if len(mmDelete.expectations) > 0 {
+		mmDelete.mock.t.Fatalf("Some expectations are already set for the MetricStorage.Delete method")
+	}
+
+	mmDelete.mock.funcDelete = f
+	return mmDelete.mock
+}
+
+// When sets expectation for the MetricStorage.Delete which will trigger the result defined by the following
+// Then helper
+func (mmDelete *mMetricStorageMockDelete) When(ctx context.Context, m metrics.Metrics) *MetricStorageMockDeleteExpectation {
+	if mmDelete.mock.funcDelete != nil {
+		mmDelete.mock.t.Fatalf("MetricStorageMock.Delete mock is already set by Set")
+	}
+
+	expectation := &MetricStorageMockDeleteExpectation{
+		mock:   mmDelete.mock,
+		params: &MetricStorageMockDeleteParams{ctx, m},
+	}
+	mmDelete.expectations = append(mmDelete.expectations, expectation)
+	return expectation
+}
+
+// Then sets up MetricStorage.Delete return parameters for the expectation previously defined by the When method
+func (e *MetricStorageMockDeleteExpectation) Then(err error) *MetricStorageMock {
+	e.results = &MetricStorageMockDeleteResults{err}
+	return e.mock
+}
+
+// Delete implements repositories.MetricStorage
+func (mmDelete *MetricStorageMock) Delete(ctx context.Context, m metrics.Metrics) (err error) {
+	mm_atomic.AddUint64(&mmDelete.beforeDeleteCounter, 1)
+	defer mm_atomic.AddUint64(&mmDelete.afterDeleteCounter, 1)
+
+	if mmDelete.inspectFuncDelete != nil {
+		mmDelete.inspectFuncDelete(ctx, m)
+	}
+
+	mm_params := MetricStorageMockDeleteParams{ctx, m}
+
+	// Record call args
+	mmDelete.DeleteMock.mutex.Lock()
+	mmDelete.DeleteMock.callArgs = append(mmDelete.DeleteMock.callArgs, &mm_params)
+	mmDelete.DeleteMock.mutex.Unlock()
+
+	for _, e := range mmDelete.DeleteMock.expectations {
+		if minimock.Equal(*e.params, mm_params) {
+			mm_atomic.AddUint64(&e.Counter, 1)
+			return e.results.err
+		}
+	}
+
+	if mmDelete.DeleteMock.defaultExpectation != nil {
+		mm_atomic.AddUint64(&mmDelete.DeleteMock.defaultExpectation.Counter, 1)
+		mm_want := mmDelete.DeleteMock.defaultExpectation.params
+		mm_got := MetricStorageMockDeleteParams{ctx, m}
+		if mm_want != nil && !minimock.Equal(*mm_want, mm_got) {
+			mmDelete.t.Errorf("MetricStorageMock.Delete got unexpected parameters, want: %#v, got: %#v%s\n", *mm_want, mm_got, minimock.Diff(*mm_want, mm_got))
+		}
+
+		mm_results := mmDelete.DeleteMock.defaultExpectation.results
+		if mm_results == nil {
+			mmDelete.t.Fatal("No results are set for the MetricStorageMock.Delete")
+		}
+		return (*mm_results).err
+	}
+	if mmDelete.funcDelete != nil {
+		return mmDelete.funcDelete(ctx, m)
+	}
+	mmDelete.t.Fatalf("Unexpected call to MetricStorageMock.Delete. %v %v", ctx, m)
+	return
+}
+
+// DeleteAfterCounter returns a count of finished MetricStorageMock.Delete invocations
+func (mmDelete *MetricStorageMock) DeleteAfterCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmDelete.afterDeleteCounter)
+}
+
+// DeleteBeforeCounter returns a count of MetricStorageMock.Delete invocations
+func (mmDelete *MetricStorageMock) DeleteBeforeCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmDelete.beforeDeleteCounter)
+}
+
+// Calls returns a list of arguments used in each call to MetricStorageMock.Delete.
+// The list is in the same order as the calls were made (i.e. recent calls have a higher index)
+func (mmDelete *mMetricStorageMockDelete) Calls() []*MetricStorageMockDeleteParams {
+	mmDelete.mutex.RLock()
+
+	argCopy := make([]*MetricStorageMockDeleteParams, len(mmDelete.callArgs))
+	copy(argCopy, mmDelete.callArgs)
+
+	mmDelete.mutex.RUnlock()
+
+	return argCopy
+}
+
+// MinimockDeleteDone returns true if the count of the Delete invocations corresponds
+// the number of defined expectations
+func (m *MetricStorageMock) MinimockDeleteDone() bool {
+	for _, e := range m.DeleteMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			return false
+		}
+	}
+
+	// if default expectation was set then invocations count should be greater than zero
+	if m.DeleteMock.defaultExpectation != nil && mm_atomic.LoadUint64(&m.afterDeleteCounter) < 1 {
+		return false
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcDelete != nil && mm_atomic.LoadUint64(&m.afterDeleteCounter) < 1 {
+		return false
+	}
+	return true
+}
+
+// MinimockDeleteInspect logs each unmet expectation
+func (m *MetricStorageMock) MinimockDeleteInspect() {
+	for _, e := range m.DeleteMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			m.t.Errorf("Expected call to MetricStorageMock.Delete with params: %#v", *e.params)
+		}
+	}
+
+	// if default expectation was set then invocations count should be greater than zero
+	if m.DeleteMock.defaultExpectation != nil && mm_atomic.LoadUint64(&m.afterDeleteCounter) < 1 {
+		if m.DeleteMock.defaultExpectation.params == nil {
+			m.t.Error("Expected call to MetricStorageMock.Delete")
+		} else {
+			m.t.Errorf("Expected call to MetricStorageMock.Delete with params: %#v", *m.DeleteMock.defaultExpectation.params)
+		}
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcDelete != nil && mm_atomic.LoadUint64(&m.afterDeleteCounter) < 1 {
+		m.t.Error("Expected call to MetricStorageMock.Delete")
+	}
+}
+
+type mMetricStorageMockDeleteByPrefix struct {
+	mock               *MetricStorageMock
+	defaultExpectation *MetricStorageMockDeleteByPrefixExpectation
+	expectations       []*MetricStorageMockDeleteByPrefixExpectation
+
+	callArgs []*MetricStorageMockDeleteByPrefixParams
+	mutex    sync.RWMutex
+}
+
+// MetricStorageMockDeleteByPrefixExpectation specifies expectation struct of the MetricStorage.DeleteByPrefix
+type MetricStorageMockDeleteByPrefixExpectation struct {
+	mock    *MetricStorageMock
+	params  *MetricStorageMockDeleteByPrefixParams
+	results *MetricStorageMockDeleteByPrefixResults
+	Counter uint64
+}
+
+// MetricStorageMockDeleteByPrefixParams contains parameters of the MetricStorage.DeleteByPrefix
+type MetricStorageMockDeleteByPrefixParams struct {
+	ctx    context.Context
+	prefix string
+}
+
+// MetricStorageMockDeleteByPrefixResults contains results of the MetricStorage.DeleteByPrefix
+type MetricStorageMockDeleteByPrefixResults struct {
+	i1  int64
+	err error
+}
+
+// Expect sets up expected params for MetricStorage.DeleteByPrefix
+func (mmDeleteByPrefix *mMetricStorageMockDeleteByPrefix) Expect(ctx context.Context, prefix string) *mMetricStorageMockDeleteByPrefix {
+	if mmDeleteByPrefix.mock.funcDeleteByPrefix != nil {
+		mmDeleteByPrefix.mock.t.Fatalf("MetricStorageMock.DeleteByPrefix mock is already set by Set")
+	}
+
+	if mmDeleteByPrefix.defaultExpectation == nil {
+		mmDeleteByPrefix.defaultExpectation = &MetricStorageMockDeleteByPrefixExpectation{}
+	}
+
+	mmDeleteByPrefix.defaultExpectation.params = &MetricStorageMockDeleteByPrefixParams{ctx, prefix}
+	for _, e := range mmDeleteByPrefix.expectations {
+		if minimock.Equal(e.params, mmDeleteByPrefix.defaultExpectation.params) {
+			mmDeleteByPrefix.mock.t.Fatalf("Expectation set by When has same params: %#v", *mmDeleteByPrefix.defaultExpectation.params)
+		}
+	}
+
+	return mmDeleteByPrefix
+}
+
+// Inspect accepts an inspector function that has same arguments as the MetricStorage.DeleteByPrefix
+func (mmDeleteByPrefix *mMetricStorageMockDeleteByPrefix) Inspect(f func(ctx context.Context, prefix string)) *mMetricStorageMockDeleteByPrefix {
+	if mmDeleteByPrefix.mock.inspectFuncDeleteByPrefix != nil {
+		mmDeleteByPrefix.mock.t.Fatalf("Inspect function is already set for MetricStorageMock.DeleteByPrefix")
+	}
+
+	mmDeleteByPrefix.mock.inspectFuncDeleteByPrefix = f
+
+	return mmDeleteByPrefix
+}
+
+// Return sets up results that will be returned by MetricStorage.DeleteByPrefix
+func (mmDeleteByPrefix *mMetricStorageMockDeleteByPrefix) Return(i1 int64, err error) *MetricStorageMock {
+	if mmDeleteByPrefix.mock.funcDeleteByPrefix != nil {
+		mmDeleteByPrefix.mock.t.Fatalf("MetricStorageMock.DeleteByPrefix mock is already set by Set")
+	}
+
+	if mmDeleteByPrefix.defaultExpectation == nil {
+		mmDeleteByPrefix.defaultExpectation = &MetricStorageMockDeleteByPrefixExpectation{mock: mmDeleteByPrefix.mock}
+	}
+	mmDeleteByPrefix.defaultExpectation.results = &MetricStorageMockDeleteByPrefixResults{i1, err}
+	return mmDeleteByPrefix.mock
+}
+
+// Set uses given function f to mock the MetricStorage.DeleteByPrefix method
+func (mmDeleteByPrefix *mMetricStorageMockDeleteByPrefix) Set(f func(ctx context.Context, prefix string) (i1 int64, err error)) *MetricStorageMock {
+	if mmDeleteByPrefix.defaultExpectation != nil {
+		mmDeleteByPrefix.mock.t.Fatalf("Default expectation is already set for the MetricStorage.DeleteByPrefix method")
+	}
+
+	if len(mmDeleteByPrefix.expectations) > 0 {
+		mmDeleteByPrefix.mock.t.Fatalf("Some expectations are already set for the MetricStorage.DeleteByPrefix method")
+	}
+
+	mmDeleteByPrefix.mock.funcDeleteByPrefix = f
+	return mmDeleteByPrefix.mock
+}
+
+// When sets expectation for the MetricStorage.DeleteByPrefix which will trigger the result defined by the following
+// Then helper
+func (mmDeleteByPrefix *mMetricStorageMockDeleteByPrefix) When(ctx context.Context, prefix string) *MetricStorageMockDeleteByPrefixExpectation {
+	if mmDeleteByPrefix.mock.funcDeleteByPrefix != nil {
+		mmDeleteByPrefix.mock.t.Fatalf("MetricStorageMock.DeleteByPrefix mock is already set by Set")
+	}
+
+	expectation := &MetricStorageMockDeleteByPrefixExpectation{
+		mock:   mmDeleteByPrefix.mock,
+		params: &MetricStorageMockDeleteByPrefixParams{ctx, prefix},
+	}
+	mmDeleteByPrefix.expectations = append(mmDeleteByPrefix.expectations, expectation)
+	return expectation
+}
+
+// Then sets up MetricStorage.DeleteByPrefix return parameters for the expectation previously defined by the When method
+func (e *MetricStorageMockDeleteByPrefixExpectation) Then(i1 int64, err error) *MetricStorageMock {
+	e.results = &MetricStorageMockDeleteByPrefixResults{i1, err}
+	return e.mock
+}
+
+// DeleteByPrefix implements repositories.MetricStorage
+func (mmDeleteByPrefix *MetricStorageMock) DeleteByPrefix(ctx context.Context, prefix string) (i1 int64, err error) {
+	mm_atomic.AddUint64(&mmDeleteByPrefix.beforeDeleteByPrefixCounter, 1)
+	defer mm_atomic.AddUint64(&mmDeleteByPrefix.afterDeleteByPrefixCounter, 1)
+
+	if mmDeleteByPrefix.inspectFuncDeleteByPrefix != nil {
+		mmDeleteByPrefix.inspectFuncDeleteByPrefix(ctx, prefix)
+	}
+
+	mm_params := MetricStorageMockDeleteByPrefixParams{ctx, prefix}
+
+	// Record call args
+	mmDeleteByPrefix.DeleteByPrefixMock.mutex.Lock()
+	mmDeleteByPrefix.DeleteByPrefixMock.callArgs = append(mmDeleteByPrefix.DeleteByPrefixMock.callArgs, &mm_params)
+	mmDeleteByPrefix.DeleteByPrefixMock.mutex.Unlock()
+
+	for _, e := range mmDeleteByPrefix.DeleteByPrefixMock.expectations {
+		if minimock.Equal(*e.params, mm_params) {
+			mm_atomic.AddUint64(&e.Counter, 1)
+			return e.results.i1, e.results.err
+		}
+	}
+
+	if mmDeleteByPrefix.DeleteByPrefixMock.defaultExpectation != nil {
+		mm_atomic.AddUint64(&mmDeleteByPrefix.DeleteByPrefixMock.defaultExpectation.Counter, 1)
+		mm_want := mmDeleteByPrefix.DeleteByPrefixMock.defaultExpectation.params
+		mm_got := MetricStorageMockDeleteByPrefixParams{ctx, prefix}
+		if mm_want != nil && !minimock.Equal(*mm_want, mm_got) {
+			mmDeleteByPrefix.t.Errorf("MetricStorageMock.DeleteByPrefix got unexpected parameters, want: %#v, got: %#v%s\n", *mm_want, mm_got, minimock.Diff(*mm_want, mm_got))
+		}
+
+		mm_results := mmDeleteByPrefix.DeleteByPrefixMock.defaultExpectation.results
+		if mm_results == nil {
+			mmDeleteByPrefix.t.Fatal("No results are set for the MetricStorageMock.DeleteByPrefix")
+		}
+		return (*mm_results).i1, (*mm_results).err
+	}
+	if mmDeleteByPrefix.funcDeleteByPrefix != nil {
+		return mmDeleteByPrefix.funcDeleteByPrefix(ctx, prefix)
+	}
+	mmDeleteByPrefix.t.Fatalf("Unexpected call to MetricStorageMock.DeleteByPrefix. %v %v", ctx, prefix)
+	return
+}
+
+// DeleteByPrefixAfterCounter returns a count of finished MetricStorageMock.DeleteByPrefix invocations
+func (mmDeleteByPrefix *MetricStorageMock) DeleteByPrefixAfterCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmDeleteByPrefix.afterDeleteByPrefixCounter)
+}
+
+// DeleteByPrefixBeforeCounter returns a count of MetricStorageMock.DeleteByPrefix invocations
+func (mmDeleteByPrefix *MetricStorageMock) DeleteByPrefixBeforeCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmDeleteByPrefix.beforeDeleteByPrefixCounter)
+}
+
+// Calls returns a list of arguments used in each call to MetricStorageMock.DeleteByPrefix.
+// The list is in the same order as the calls were made (i.e. recent calls have a higher index)
+func (mmDeleteByPrefix *mMetricStorageMockDeleteByPrefix) Calls() []*MetricStorageMockDeleteByPrefixParams {
+	mmDeleteByPrefix.mutex.RLock()
+
+	argCopy := make([]*MetricStorageMockDeleteByPrefixParams, len(mmDeleteByPrefix.callArgs))
+	copy(argCopy, mmDeleteByPrefix.callArgs)
+
+	mmDeleteByPrefix.mutex.RUnlock()
+
+	return argCopy
+}
+
+// MinimockDeleteByPrefixDone returns true if the count of the DeleteByPrefix invocations corresponds
+// the number of defined expectations
+func (m *MetricStorageMock) MinimockDeleteByPrefixDone() bool {
+	for _, e := range m.DeleteByPrefixMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			return false
+		}
+	}
+
+	// if default expectation was set then invocations count should be greater than zero
+	if m.DeleteByPrefixMock.defaultExpectation != nil && mm_atomic.LoadUint64(&m.afterDeleteByPrefixCounter) < 1 {
+		return false
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcDeleteByPrefix != nil && mm_atomic.LoadUint64(&m.afterDeleteByPrefixCounter) < 1 {
+		return false
+	}
+	return true
+}
+
+// MinimockDeleteByPrefixInspect logs each unmet expectation
+func (m *MetricStorageMock) MinimockDeleteByPrefixInspect() {
+	for _, e := range m.DeleteByPrefixMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			m.t.Errorf("Expected call to MetricStorageMock.DeleteByPrefix with params: %#v", *e.params)
+		}
+	}
+
+	// if default expectation was set then invocations count should be greater than zero
+	if m.DeleteByPrefixMock.defaultExpectation != nil && mm_atomic.LoadUint64(&m.afterDeleteByPrefixCounter) < 1 {
+		if m.DeleteByPrefixMock.defaultExpectation.params == nil {
+			m.t.Error("Expected call to MetricStorageMock.DeleteByPrefix")
+		} else {
+			m.t.Errorf("Expected call to MetricStorageMock.DeleteByPrefix with params: %#v", *m.DeleteByPrefixMock.defaultExpectation.params)
+		}
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcDeleteByPrefix != nil && mm_atomic.LoadUint64(&m.afterDeleteByPrefixCounter) < 1 {
+		m.t.Error("Expected call to MetricStorageMock.DeleteByPrefix")
+	}
+}
+
 type mMetricStorageMockGet struct {
 	mock               *MetricStorageMock
 	defaultExpectation *MetricStorageMockGetExpectation
@@ -649,11 +1108,10 @@ func (mmGet *MetricStorageMock) Get(ctx context.Context, m *metrics.Metrics) (er
 		}
 
 		mm_results := mmGet.GetMock.defaultExpectation.results
-		if mm_results != nil {
-			return (*mm_results).err
+		if mm_results == nil {
+			mmGet.t.Fatal("No results are set for the MetricStorageMock.Get")
 		}
-		mmGet.t.Fatal("No results are set for the MetricStorageMock.Get")
-
+		return (*mm_results).err
 	}
 	if mmGet.funcGet != nil {
 		return mmGet.funcGet(ctx, m)
@@ -746,7 +1204,8 @@ type MetricStorageMockListExpectation struct {
 
 // MetricStorageMockListParams contains parameters of the MetricStorage.List
 type MetricStorageMockListParams struct {
-	ctx context.Context
+	ctx   context.Context
+	query mm_repositories.ListQuery
 }
 
 // MetricStorageMockListResults contains results of the MetricStorage.List
@@ -756,7 +1215,7 @@ type MetricStorageMockListResults struct {
 }
 
 // Expect sets up expected params for MetricStorage.List
-func (mmList *mMetricStorageMockList) Expect(ctx context.Context) *mMetricStorageMockList {
+func (mmList *mMetricStorageMockList) Expect(ctx context.Context, query mm_repositories.ListQuery) *mMetricStorageMockList {
 	if mmList.mock.funcList != nil {
 		mmList.mock.t.Fatalf("MetricStorageMock.List mock is already set by Set")
 	}
@@ -765,7 +1224,7 @@ func (mmList *mMetricStorageMockList) Expect(ctx context.Context) *mMetricStorag
 		mmList.defaultExpectation = &MetricStorageMockListExpectation{}
 	}
 
-	mmList.defaultExpectation.params = &MetricStorageMockListParams{ctx}
+	mmList.defaultExpectation.params = &MetricStorageMockListParams{ctx, query}
 	for _, e := range mmList.expectations {
 		if minimock.Equal(e.params, mmList.defaultExpectation.params) {
 			mmList.mock.t.Fatalf("Expectation set by When has same params: %#v", *mmList.defaultExpectation.params)
@@ -776,7 +1235,7 @@ func (mmList *mMetricStorageMockList) Expect(ctx context.Context) *mMetricStorag
 }
 
 // Inspect accepts an inspector function that has same arguments as the MetricStorage.List
-func (mmList *mMetricStorageMockList) Inspect(f func(ctx context.Context)) *mMetricStorageMockList {
+func (mmList *mMetricStorageMockList) Inspect(f func(ctx context.Context, query mm_repositories.ListQuery)) *mMetricStorageMockList {
 	if mmList.mock.inspectFuncList != nil {
 		mmList.mock.t.Fatalf("Inspect function is already set for MetricStorageMock.List")
 	}
@@ -800,7 +1259,7 @@ func (mmList *mMetricStorageMockList) Return(ma1 []metrics.Metrics, err error) *
 }
 
 // Set uses given function f to mock the MetricStorage.List method
-func (mmList *mMetricStorageMockList) Set(f func(ctx context.Context) (ma1 []metrics.Metrics, err error)) *MetricStorageMock {
+func (mmList *mMetricStorageMockList) Set(f func(ctx context.Context, query mm_repositories.ListQuery) (ma1 []metrics.Metrics, err error)) *MetricStorageMock {
 	if mmList.defaultExpectation != nil {
 		mmList.mock.t.Fatalf("Default expectation is already set for the MetricStorage.List method")
 	}
@@ -815,14 +1274,14 @@ func (mmList *mMetricStorageMockList) Set(f func(ctx context.Context) (ma1 []met
 
 // When sets expectation for the MetricStorage.List which will trigger the result defined by the following
 // Then helper
-func (mmList *mMetricStorageMockList) When(ctx context.Context) *MetricStorageMockListExpectation {
+func (mmList *mMetricStorageMockList) When(ctx context.Context, query mm_repositories.ListQuery) *MetricStorageMockListExpectation {
 	if mmList.mock.funcList != nil {
 		mmList.mock.t.Fatalf("MetricStorageMock.List mock is already set by Set")
 	}
 
 	expectation := &MetricStorageMockListExpectation{
 		mock:   mmList.mock,
-		params: &MetricStorageMockListParams{ctx},
+		params: &MetricStorageMockListParams{ctx, query},
 	}
 	mmList.expectations = append(mmList.expectations, expectation)
 	return expectation
@@ -835,15 +1294,15 @@ func (e *MetricStorageMockListExpectation) Then(ma1 []metrics.Metrics, err error
 }
 
 // List implements repositories.MetricStorage
-func (mmList *MetricStorageMock) List(ctx context.Context) (ma1 []metrics.Metrics, err error) {
+func (mmList *MetricStorageMock) List(ctx context.Context, query mm_repositories.ListQuery) (ma1 []metrics.Metrics, err error) {
 	mm_atomic.AddUint64(&mmList.beforeListCounter, 1)
 	defer mm_atomic.AddUint64(&mmList.afterListCounter, 1)
 
 	if mmList.inspectFuncList != nil {
-		mmList.inspectFuncList(ctx)
+		mmList.inspectFuncList(ctx, query)
 	}
 
-	mm_params := MetricStorageMockListParams{ctx}
+	mm_params := MetricStorageMockListParams{ctx, query}
 
 	// Record call args
 	mmList.ListMock.mutex.Lock()
@@ -860,22 +1319,21 @@ func (mmList *MetricStorageMock) List(ctx context.Context) (ma1 []metrics.Metric
 	if mmList.ListMock.defaultExpectation != nil {
 		mm_atomic.AddUint64(&mmList.ListMock.defaultExpectation.Counter, 1)
 		mm_want := mmList.ListMock.defaultExpectation.params
-		mm_got := MetricStorageMockListParams{ctx}
+		mm_got := MetricStorageMockListParams{ctx, query}
 		if mm_want != nil && !minimock.Equal(*mm_want, mm_got) {
 			mmList.t.Errorf("MetricStorageMock.List got unexpected parameters, want: %#v, got: %#v%s\n", *mm_want, mm_got, minimock.Diff(*mm_want, mm_got))
 		}
 
 		mm_results := mmList.ListMock.defaultExpectation.results
-		if mm_results != nil {
-			return (*mm_results).ma1, (*mm_results).err
+		if mm_results == nil {
+			mmList.t.Fatal("No results are set for the MetricStorageMock.List")
 		}
-		mmList.t.Fatal("No results are set for the MetricStorageMock.List")
-
+		return (*mm_results).ma1, (*mm_results).err
 	}
 	if mmList.funcList != nil {
-		return mmList.funcList(ctx)
+		return mmList.funcList(ctx, query)
 	}
-	mmList.t.Fatalf("Unexpected call to MetricStorageMock.List. %v", ctx)
+	mmList.t.Fatalf("Unexpected call to MetricStorageMock.List. %v %v", ctx, query)
 	return
 }
 
@@ -1082,11 +1540,10 @@ func (mmPing *MetricStorageMock) Ping(ctx context.Context) (b1 bool) {
 		}
 
 		mm_results := mmPing.PingMock.defaultExpectation.results
-		if mm_results != nil {
-			return (*mm_results).b1
+		if mm_results == nil {
+			mmPing.t.Fatal("No results are set for the MetricStorageMock.Ping")
 		}
-		mmPing.t.Fatal("No results are set for the MetricStorageMock.Ping")
-
+		return (*mm_results).b1
 	}
 	if mmPing.funcPing != nil {
 		return mmPing.funcPing(ctx)
@@ -1160,6 +1617,222 @@ func (m *MetricStorageMock) MinimockPingInspect() {
 	}
 }
 
+type mMetricStorageMockReset struct {
+	mock               *MetricStorageMock
+	defaultExpectation *MetricStorageMockResetExpectation
+	expectations       []*MetricStorageMockResetExpectation
+
+	callArgs []*MetricStorageMockResetParams
+	mutex    sync.RWMutex
+}
+
+// MetricStorageMockResetExpectation specifies expectation struct of the MetricStorage.Reset
+type MetricStorageMockResetExpectation struct {
+	mock    *MetricStorageMock
+	params  *MetricStorageMockResetParams
+	results *MetricStorageMockResetResults
+	Counter uint64
+}
+
+// MetricStorageMockResetParams contains parameters of the MetricStorage.Reset
+type MetricStorageMockResetParams struct {
+	ctx context.Context
+	m   metrics.Metrics
+}
+
+// MetricStorageMockResetResults contains results of the MetricStorage.Reset
+type MetricStorageMockResetResults struct {
+	err error
+}
+
+// Expect sets up expected params for MetricStorage.Reset
+func (mmReset *mMetricStorageMockReset) Expect(ctx context.Context, m metrics.Metrics) *mMetricStorageMockReset {
+	if mmReset.mock.funcReset != nil {
+		mmReset.mock.t.Fatalf("MetricStorageMock.Reset mock is already set by Set")
+	}
+
+	if mmReset.defaultExpectation == nil {
+		mmReset.defaultExpectation = &MetricStorageMockResetExpectation{}
+	}
+
+	mmReset.defaultExpectation.params = &MetricStorageMockResetParams{ctx, m}
+	for _, e := range mmReset.expectations {
+		if minimock.Equal(e.params, mmReset.defaultExpectation.params) {
+			mmReset.mock.t.Fatalf("Expectation set by When has same params: %#v", *mmReset.defaultExpectation.params)
+		}
+	}
+
+	return mmReset
+}
+
+// Inspect accepts an inspector function that has same arguments as the MetricStorage.Reset
+func (mmReset *mMetricStorageMockReset) Inspect(f func(ctx context.Context, m metrics.Metrics)) *mMetricStorageMockReset {
+	if mmReset.mock.inspectFuncReset != nil {
+		mmReset.mock.t.Fatalf("Inspect function is already set for MetricStorageMock.Reset")
+	}
+
+	mmReset.mock.inspectFuncReset = f
+
+	return mmReset
+}
+
+// Return sets up results that will be returned by MetricStorage.Reset
+func (mmReset *mMetricStorageMockReset) Return(err error) *MetricStorageMock {
+	if mmReset.mock.funcReset != nil {
+		mmReset.mock.t.Fatalf("MetricStorageMock.Reset mock is already set by Set")
+	}
+
+	if mmReset.defaultExpectation == nil {
+		mmReset.defaultExpectation = &MetricStorageMockResetExpectation{mock: mmReset.mock}
+	}
+	mmReset.defaultExpectation.results = &MetricStorageMockResetResults{err}
+	return mmReset.mock
+}
+
+// Set uses given function f to mock the MetricStorage.Reset method
+func (mmReset *mMetricStorageMockReset) Set(f func(ctx context.Context, m metrics.Metrics) (err error)) *MetricStorageMock {
+	if mmReset.defaultExpectation != nil {
+		mmReset.mock.t.Fatalf("Default expectation is already set for the MetricStorage.Reset method")
+	}
+
+	if len(mmReset.expectations) > 0 {
+		mmReset.mock.t.Fatalf("Some expectations are already set for the MetricStorage.Reset method")
+	}
+
+	mmReset.mock.funcReset = f
+	return mmReset.mock
+}
+
+// When sets expectation for the MetricStorage.Reset which will trigger the result defined by the following
+// Then helper
+func (mmReset *mMetricStorageMockReset) When(ctx context.Context, m metrics.Metrics) *MetricStorageMockResetExpectation {
+	if mmReset.mock.funcReset != nil {
+		mmReset.mock.t.Fatalf("MetricStorageMock.Reset mock is already set by Set")
+	}
+
+	expectation := &MetricStorageMockResetExpectation{
+		mock:   mmReset.mock,
+		params: &MetricStorageMockResetParams{ctx, m},
+	}
+	mmReset.expectations = append(mmReset.expectations, expectation)
+	return expectation
+}
+
+// Then sets up MetricStorage.Reset return parameters for the expectation previously defined by the When method
+func (e *MetricStorageMockResetExpectation) Then(err error) *MetricStorageMock {
+	e.results = &MetricStorageMockResetResults{err}
+	return e.mock
+}
+
+// Reset implements repositories.MetricStorage
+func (mmReset *MetricStorageMock) Reset(ctx context.Context, m metrics.Metrics) (err error) {
+	mm_atomic.AddUint64(&mmReset.beforeResetCounter, 1)
+	defer mm_atomic.AddUint64(&mmReset.afterResetCounter, 1)
+
+	if mmReset.inspectFuncReset != nil {
+		mmReset.inspectFuncReset(ctx, m)
+	}
+
+	mm_params := MetricStorageMockResetParams{ctx, m}
+
+	// Record call args
+	mmReset.ResetMock.mutex.Lock()
+	mmReset.ResetMock.callArgs = append(mmReset.ResetMock.callArgs, &mm_params)
+	mmReset.ResetMock.mutex.Unlock()
+
+	for _, e := range mmReset.ResetMock.expectations {
+		if minimock.Equal(*e.params, mm_params) {
+			mm_atomic.AddUint64(&e.Counter, 1)
+			return e.results.err
+		}
+	}
+
+	if mmReset.ResetMock.defaultExpectation != nil {
+		mm_atomic.AddUint64(&mmReset.ResetMock.defaultExpectation.Counter, 1)
+		mm_want := mmReset.ResetMock.defaultExpectation.params
+		mm_got := MetricStorageMockResetParams{ctx, m}
+		if mm_want != nil && !minimock.Equal(*mm_want, mm_got) {
+			mmReset.t.Errorf("MetricStorageMock.Reset got unexpected parameters, want: %#v, got: %#v%s\n", *mm_want, mm_got, minimock.Diff(*mm_want, mm_got))
+		}
+
+		mm_results := mmReset.ResetMock.defaultExpectation.results
+		if mm_results == nil {
+			mmReset.t.Fatal("No results are set for the MetricStorageMock.Reset")
+		}
+		return (*mm_results).err
+	}
+	if mmReset.funcReset != nil {
+		return mmReset.funcReset(ctx, m)
+	}
+	mmReset.t.Fatalf("Unexpected call to MetricStorageMock.Reset. %v %v", ctx, m)
+	return
+}
+
+// ResetAfterCounter returns a count of finished MetricStorageMock.Reset invocations
+func (mmReset *MetricStorageMock) ResetAfterCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmReset.afterResetCounter)
+}
+
+// ResetBeforeCounter returns a count of MetricStorageMock.Reset invocations
+func (mmReset *MetricStorageMock) ResetBeforeCounter() uint64 {
+	return mm_atomic.LoadUint64(&mmReset.beforeResetCounter)
+}
+
+// Calls returns a list of arguments used in each call to MetricStorageMock.Reset.
+// The list is in the same order as the calls were made (i.e. recent calls have a higher index)
+func (mmReset *mMetricStorageMockReset) Calls() []*MetricStorageMockResetParams {
+	mmReset.mutex.RLock()
+
+	argCopy := make([]*MetricStorageMockResetParams, len(mmReset.callArgs))
+	copy(argCopy, mmReset.callArgs)
+
+	mmReset.mutex.RUnlock()
+
+	return argCopy
+}
+
+// MinimockResetDone returns true if the count of the Reset invocations corresponds
+// the number of defined expectations
+func (m *MetricStorageMock) MinimockResetDone() bool {
+	for _, e := range m.ResetMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			return false
+		}
+	}
+
+	// if default expectation was set then invocations count should be greater than zero
+	if m.ResetMock.defaultExpectation != nil && mm_atomic.LoadUint64(&m.afterResetCounter) < 1 {
+		return false
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcReset != nil && mm_atomic.LoadUint64(&m.afterResetCounter) < 1 {
+		return false
+	}
+	return true
+}
+
+// MinimockResetInspect logs each unmet expectation
+func (m *MetricStorageMock) MinimockResetInspect() {
+	for _, e := range m.ResetMock.expectations {
+		if mm_atomic.LoadUint64(&e.Counter) < 1 {
+			m.t.Errorf("Expected call to MetricStorageMock.Reset with params: %#v", *e.params)
+		}
+	}
+
+	// if default expectation was set then invocations count should be greater than zero
+	if m.ResetMock.defaultExpectation != nil && mm_atomic.LoadUint64(&m.afterResetCounter) < 1 {
+		if m.ResetMock.defaultExpectation.params == nil {
+			m.t.Error("Expected call to MetricStorageMock.Reset")
+		} else {
+			m.t.Errorf("Expected call to MetricStorageMock.Reset with params: %#v", *m.ResetMock.defaultExpectation.params)
+		}
+	}
+	// if func was set then invocations count should be greater than zero
+	if m.funcReset != nil && mm_atomic.LoadUint64(&m.afterResetCounter) < 1 {
+		m.t.Error("Expected call to MetricStorageMock.Reset")
+	}
+}
+
 // MinimockFinish checks that all mocked methods have been called the expected number of times
 func (m *MetricStorageMock) MinimockFinish() {
 	m.finishOnce.Do(func() {
@@ -1168,11 +1841,17 @@ func (m *MetricStorageMock) MinimockFinish() {
 
 			m.MinimockBulkAddInspect()
 
+			m.MinimockDeleteInspect()
+
+			m.MinimockDeleteByPrefixInspect()
+
 			m.MinimockGetInspect()
 
 			m.MinimockListInspect()
 
 			m.MinimockPingInspect()
+
+			m.MinimockResetInspect()
 			m.t.FailNow()
 		}
 	})
@@ -1199,7 +1878,10 @@ func (m *MetricStorageMock) minimockDone() bool {
 	return done &&
 		m.MinimockAddDone() &&
 		m.MinimockBulkAddDone() &&
+		m.MinimockDeleteDone() &&
+		m.MinimockDeleteByPrefixDone() &&
 		m.MinimockGetDone() &&
 		m.MinimockListDone() &&
-		m.MinimockPingDone()
+		m.MinimockPingDone() &&
+		m.MinimockResetDone()
 }
@@ -129,11 +129,18 @@ func (ms *MetricServer) GetMetricValue(w http.ResponseWriter, r *http.Request) {
 
 	err = ms.store.Get(r.Context(), metricObj)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		ms.writeStorageError(w, err, "error get metric")
 		return
 	}
 
-	if _, err := w.Write([]byte(metricObj.GetValue())); err != nil {
+	value, err := metricObj.GetValue()
+	if err != nil {
+		ms.logger.Error("Error encoding metric value", zap.Error(err))
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := w.Write([]byte(value)); err != nil {
 		ms.logger.Error("Error writing response", zap.Error(err))
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
@@ -150,7 +157,7 @@ func (ms *MetricServer) GetMetricJSON(w http.ResponseWriter, r *http.Request) {
 
 	err := ms.store.Get(r.Context(), &metricObj)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+		ms.writeStorageError(w, err, "error get metric")
 		return
 	}
 
@@ -162,10 +169,30 @@ func (ms *MetricServer) GetMetricJSON(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// ListMetrics handler, returns all current metrics
+// ListMetrics handler, returns current metrics matching the query parameters.
+//
+// The list is sorted by name, with the limit set the cursor of the next page
+// is returned in the X-Next-Cursor header while there are more metrics.
+// Clients not asking for application/json get the html dashboard.
 func (ms *MetricServer) ListMetrics(w http.ResponseWriter, r *http.Request) {
+	if !acceptsJSON(r) {
+		ms.Dashboard(w, r)
+		return
+	}
 
-	metrics, err := ms.store.List(r.Context())
+	query, err := newListQuery(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := query.Limit
+	if limit > 0 {
+		// one more metric shows whether there is a next page.
+		query.Limit++
+	}
+
+	metricsList, err := ms.store.List(r.Context(), query)
 
 	if err != nil {
 		ms.logger.Error("error read metrics", zap.Error(err))
@@ -173,9 +200,14 @@ func (ms *MetricServer) ListMetrics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-Type", "text/html")
+	if limit > 0 && len(metricsList) > limit {
+		metricsList = metricsList[:limit]
+		w.Header().Set(NextCursorHeader, repositories.NewCursor(metricsList[limit-1]).String())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 
-	if err := json.NewEncoder(w).Encode(metrics); err != nil {
+	if err := json.NewEncoder(w).Encode(metricsList); err != nil {
 		ms.logger.Error("Error writing response", zap.Error(err))
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
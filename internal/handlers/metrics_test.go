@@ -3,7 +3,6 @@ package handlers_test
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -13,6 +12,7 @@ import (
 	"github.com/gojuno/minimock/v3"
 	"github.com/screamsoul/go-metrics-tpl/internal/handlers"
 	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
 	"github.com/screamsoul/go-metrics-tpl/internal/routers"
 	"github.com/stretchr/testify/suite"
 )
@@ -98,6 +98,24 @@ func (s *MetricRouterSuite) TestUpdateRouter() {
 			method: "GET",
 			status: http.StatusMethodNotAllowed,
 		},
+		{
+			name: "update histogram ok",
+			body: map[string]interface{}{
+				"type": "histogram", "id": "someMetric1",
+				"histogram": map[string]interface{}{"bounds": []float64{1}, "counts": []int{1, 0}, "sum": 0.5, "count": 1},
+			},
+			method: "POST",
+			status: http.StatusOK,
+		},
+		{
+			name: "update histogram invalid counts",
+			body: map[string]interface{}{
+				"type": "histogram", "id": "someMetric1",
+				"histogram": map[string]interface{}{"bounds": []float64{1}, "counts": []int{1}, "sum": 0.5, "count": 1},
+			},
+			method: "POST",
+			status: http.StatusBadRequest,
+		},
 		{
 			name:   "update bad type",
 			body:   map[string]interface{}{"type": "asd", "delta": 1, "id": "someMetric1"},
@@ -170,6 +188,16 @@ func (s *MetricRouterSuite) TestUpdateBulkRouter() {
 			status: http.StatusOK,
 			mock:   mockBulkOk,
 		},
+		{
+			name: "update batch with labels",
+			body: []map[string]interface{}{
+				{"type": "counter", "delta": 1, "id": "someMetric1", "labels": map[string]string{"host": "a"}},
+				{"type": "gauge", "value": 1.2, "id": "someMetric2", "labels": map[string]string{"host": "b", "region": "eu"}},
+			},
+			method: "POST",
+			status: http.StatusOK,
+			mock:   mockBulkOk,
+		},
 		{
 			name:   "update batch",
 			body:   map[string]interface{}{"type": "counter", "delta": 1, "id": "someMetric1"},
@@ -243,6 +271,11 @@ func (s *MetricRouterSuite) TestUpdateFromPath() {
 			path:   "/update/gauge/sume_metric1/1.2",
 			status: http.StatusOK,
 		},
+		{
+			name:   "histogram observation ok",
+			path:   "/update/histogram/sume_metric1/0.2",
+			status: http.StatusOK,
+		},
 		{
 			name:   "bad type",
 			path:   "/update/sume_gauge/sume_metric1/1.2",
@@ -274,7 +307,7 @@ func (s *MetricRouterSuite) TestGetMetricJSON() {
 		if m.MType == metrics.Counter && m.ID == "someMetric1" {
 			m.Delta = &intValue
 		} else {
-			return errors.New("not found")
+			return repositories.ErrMetricNotFound
 		}
 
 		return nil
@@ -323,6 +356,51 @@ func (s *MetricRouterSuite) TestGetMetricJSON() {
 
 }
 
+func (s *MetricRouterSuite) TestGetMetricJSONLabels() {
+
+	s.mockDB.GetMock.Set(func(ctx context.Context, m *metrics.Metrics) (err error) {
+		var intValue int64 = 5
+		if m.SeriesKey() == `someMetric1{host="a"}` {
+			m.Delta = &intValue
+			return nil
+		}
+		return repositories.ErrMetricNotFound
+	})
+
+	var testTable = []struct {
+		name           string
+		body           map[string]interface{}
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "value with labels ok",
+			body:           map[string]interface{}{"type": "counter", "id": "someMetric1", "labels": map[string]string{"host": "a"}},
+			expectedStatus: http.StatusOK,
+			expectedBody:   `{"type": "counter", "id": "someMetric1", "delta": 5, "labels": {"host": "a"}}`,
+		},
+		{
+			name:           "value with other labels not found",
+			body:           map[string]interface{}{"type": "counter", "id": "someMetric1", "labels": map[string]string{"host": "b"}},
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "bad label name",
+			body:           map[string]interface{}{"type": "counter", "id": "someMetric1", "labels": map[string]string{"": "a"}},
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+	for _, v := range testTable {
+		s.Suite.Run(v.name, func() {
+			resp := s.serverRequest("POST", "/value/", v.body, http.Header{"Content-Type": {"application/json"}})
+			s.Require().Equal(v.expectedStatus, resp.StatusCode(), fmt.Sprintf("Resp body: %s", string(resp.Body())))
+			if v.expectedBody != "" {
+				s.JSONEq(v.expectedBody, string(resp.Body()))
+			}
+		})
+	}
+}
+
 func (s *MetricRouterSuite) TestGetMetric() {
 
 	s.mockDB.GetMock.Set(func(ctx context.Context, m *metrics.Metrics) (err error) {
@@ -330,7 +408,7 @@ func (s *MetricRouterSuite) TestGetMetric() {
 		if m.MType == metrics.Counter && m.ID == "someMetric1" {
 			m.Delta = &intValue
 		} else {
-			return errors.New("not found")
+			return repositories.ErrMetricNotFound
 		}
 
 		return nil
@@ -411,7 +489,7 @@ func (s *MetricRouterSuite) TestListMetrics() {
 	for _, v := range testTable {
 		s.Suite.Run(v.name, func() {
 			v.mock()
-			resp := s.serverRequest(v.method, "/", nil)
+			resp := s.serverRequest(v.method, "/", nil, http.Header{"Accept": {"application/json"}})
 			s.Require().Equal(v.status, resp.StatusCode())
 			if v.status == http.StatusOK {
 				var mList []metrics.Metrics
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"github.com/screamsoul/go-metrics-tpl/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// PrometheusContentType content type of the Prometheus text exposition format.
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var (
+	promInvalidNameChars  = regexp.MustCompile(`[^a-zA-Z0-9_:]`)
+	promInvalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+	promLabelEscaper      = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	promHelpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+// promMetricName converts the metric ID into a valid Prometheus metric name.
+func promMetricName(name string) string {
+	name = promInvalidNameChars.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// promLabelName converts the label name into a valid Prometheus label name.
+func promLabelName(name string) string {
+	name = promInvalidLabelChars.ReplaceAllString(name, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// promType maps the metric type to the Prometheus metric type.
+func promType(mType metrics.MetricType) string {
+	switch mType {
+	case metrics.Counter:
+		return "counter"
+	case metrics.Histogram:
+		return "histogram"
+	default:
+		return "gauge"
+	}
+}
+
+// promLabels renders labels in the `{name="value",...}` form with sorted names,
+// extra pairs (like `le`) are appended after the metric labels.
+func promLabels(labels metrics.Labels, extra ...string) string {
+	if len(labels) == 0 && len(extra) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, 0, len(labels)+len(extra))
+	for _, k := range labels.Keys() {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, promLabelName(k), promLabelEscaper.Replace(labels[k])))
+	}
+	sort.Strings(pairs)
+	pairs = append(pairs, extra...)
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// writePromHistogram writes the cumulative buckets, sum and count samples of the histogram.
+func writePromHistogram(w io.Writer, name string, labels metrics.Labels, h *metrics.HistogramValue) {
+	var cumulative uint64
+	for i, bound := range h.Bounds {
+		cumulative += h.Counts[i]
+		le := fmt.Sprintf(`le="%s"`, promValue(bound))
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, promLabels(labels, le), cumulative)
+	}
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, promLabels(labels, `le="+Inf"`), h.Count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, promLabels(labels), promValue(h.Sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, promLabels(labels), h.Count)
+}
+
+// promValue formats the sample value as Prometheus expects.
+func promValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// promFamily a group of samples with the same metric name and type.
+type promFamily struct {
+	name    string
+	source  string
+	mType   metrics.MetricType
+	samples []metrics.Metrics
+	series  map[string]bool
+}
+
+// WritePrometheus writes metrics to w in the Prometheus text exposition format.
+// Families and samples are written in a stable order.
+//
+// Metric IDs which are converted to the same name form one family. A metric of another
+// type than the family or with the same labels as a metric already in the family is
+// skipped with a warning, the metrics are taken in the order of ID and type.
+func WritePrometheus(w io.Writer, metricsList []metrics.Metrics) error {
+	logger := logging.GetLogger()
+
+	sorted := slices.Clone(metricsList)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].ID != sorted[j].ID {
+			return sorted[i].ID < sorted[j].ID
+		}
+		return sorted[i].MType < sorted[j].MType
+	})
+
+	families := make(map[string]*promFamily)
+
+	for _, m := range sorted {
+		name := promMetricName(m.ID)
+
+		family, ok := families[name]
+		if !ok {
+			family = &promFamily{name: name, source: m.ID, mType: m.MType, series: make(map[string]bool)}
+			families[name] = family
+		}
+
+		if family.mType != m.MType {
+			logger.Warn(
+				"skip prometheus metric, the name is taken by another type",
+				zap.String("id", m.ID), zap.String("type", string(m.MType)),
+				zap.String("name", name), zap.String("name_type", string(family.mType)),
+			)
+			continue
+		}
+
+		labels := promLabels(m.Labels)
+		if family.series[labels] {
+			logger.Warn(
+				"skip prometheus metric, the series is taken by another metric",
+				zap.String("id", m.ID), zap.String("type", string(m.MType)),
+				zap.String("name", name), zap.String("labels", labels),
+			)
+			continue
+		}
+		family.series[labels] = true
+		family.samples = append(family.samples, m)
+	}
+
+	keys := make([]string, 0, len(families))
+	for k := range families {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	bw := bufio.NewWriter(w)
+
+	for _, k := range keys {
+		family := families[k]
+
+		sort.Slice(family.samples, func(i, j int) bool {
+			return promLabels(family.samples[i].Labels) < promLabels(family.samples[j].Labels)
+		})
+
+		fmt.Fprintf(bw, "# HELP %s Metric %s.\n", family.name, promHelpEscaper.Replace(family.source))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", family.name, promType(family.mType))
+
+		for _, m := range family.samples {
+			var value float64
+			switch m.MType {
+			case metrics.Gauge:
+				if m.Value == nil {
+					continue
+				}
+				value = *m.Value
+			case metrics.Counter:
+				if m.Delta == nil {
+					continue
+				}
+				value = float64(*m.Delta)
+			case metrics.Histogram:
+				if m.Histogram != nil {
+					writePromHistogram(bw, family.name, m.Labels, m.Histogram)
+				}
+				continue
+			}
+			fmt.Fprintf(bw, "%s%s %s\n", family.name, promLabels(m.Labels), promValue(value))
+		}
+	}
+
+	return bw.Flush()
+}
+
+// PrometheusMetrics handler, returns all current metrics in the Prometheus text format.
+func (ms *MetricServer) PrometheusMetrics(w http.ResponseWriter, r *http.Request) {
+	metricsList, err := ms.store.List(r.Context(), repositories.ListQuery{})
+	if err != nil {
+		ms.logger.Error("error read metrics", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", PrometheusContentType)
+
+	if err := WritePrometheus(w, metricsList); err != nil {
+		ms.logger.Error("Error writing response", zap.Error(err))
+	}
+}
@@ -0,0 +1,154 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gojuno/minimock/v3"
+	"github.com/screamsoul/go-metrics-tpl/internal/handlers"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	var (
+		alloc   = 1.5
+		fsAvail = 42.0
+		polls   = int64(7)
+		reqA    = int64(3)
+		reqB    = int64(4)
+	)
+
+	metricsList := []metrics.Metrics{
+		{ID: "PollCount", MType: metrics.Counter, Delta: &polls},
+		{ID: "http.requests", MType: metrics.Counter, Delta: &reqB, Labels: metrics.Labels{"host": "b"}},
+		{ID: "Alloc", MType: metrics.Gauge, Value: &alloc},
+		{ID: "http.requests", MType: metrics.Counter, Delta: &reqA, Labels: metrics.Labels{"host": "a", "path": `/x"y`}},
+		{ID: "1fs-avail", MType: metrics.Gauge, Value: &fsAvail},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, handlers.WritePrometheus(&buf, metricsList))
+
+	expected := `# HELP Alloc Metric Alloc.
+# TYPE Alloc gauge
+Alloc 1.5
+# HELP PollCount Metric PollCount.
+# TYPE PollCount counter
+PollCount 7
+# HELP _1fs_avail Metric 1fs-avail.
+# TYPE _1fs_avail gauge
+_1fs_avail 42
+# HELP http_requests Metric http.requests.
+# TYPE http_requests counter
+http_requests{host="a",path="/x\"y"} 3
+http_requests{host="b"} 4
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWritePrometheusHistogram(t *testing.T) {
+	h := metrics.NewHistogramValue([]float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(0.5)
+	h.Observe(2)
+
+	metricsList := []metrics.Metrics{
+		{ID: "latency", MType: metrics.Histogram, Histogram: h, Labels: metrics.Labels{"host": "a"}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, handlers.WritePrometheus(&buf, metricsList))
+
+	expected := `# HELP latency Metric latency.
+# TYPE latency histogram
+latency_bucket{host="a",le="0.1"} 1
+latency_bucket{host="a",le="1"} 2
+latency_bucket{host="a",le="+Inf"} 3
+latency_sum{host="a"} 2.55
+latency_count{host="a"} 3
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestWritePrometheusNameConflicts(t *testing.T) {
+	var (
+		dotted  = 1.0
+		snake   = 2.0
+		host    = 3.0
+		gauge   = 4.0
+		counter = int64(5)
+	)
+
+	metricsList := []metrics.Metrics{
+		{ID: "a_b", MType: metrics.Gauge, Value: &snake},
+		{ID: "a_b", MType: metrics.Gauge, Value: &host, Labels: metrics.Labels{"host": "a"}},
+		{ID: "a.b", MType: metrics.Gauge, Value: &dotted},
+		{ID: "jobs", MType: metrics.Gauge, Value: &gauge},
+		{ID: "jobs", MType: metrics.Counter, Delta: &counter},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, handlers.WritePrometheus(&buf, metricsList))
+
+	expected := `# HELP a_b Metric a.b.
+# TYPE a_b gauge
+a_b 1
+a_b{host="a"} 3
+# HELP jobs Metric jobs.
+# TYPE jobs counter
+jobs 5
+`
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestPrometheusMetrics(t *testing.T) {
+	mc := minimock.NewController(t)
+	mockDB := NewMetricStorageMock(mc)
+	server := handlers.NewMetricServer(mockDB)
+
+	var value = 1.0
+
+	testCases := []struct {
+		name   string
+		mock   func()
+		status int
+	}{
+		{
+			name: "ok",
+			mock: func() {
+				mockDB.ListMock.Return([]metrics.Metrics{{ID: "a", MType: metrics.Gauge, Value: &value}}, nil)
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "storage error",
+			mock: func() {
+				mockDB.ListMock.Return(nil, fmt.Errorf("some err"))
+			},
+			status: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.mock()
+
+			req := httptest.NewRequest("GET", "/metrics", nil).WithContext(context.Background())
+			rr := httptest.NewRecorder()
+
+			server.PrometheusMetrics(rr, req)
+
+			assert.Equal(t, tc.status, rr.Code)
+			if tc.status == http.StatusOK {
+				assert.Equal(t, handlers.PrometheusContentType, rr.Header().Get("Content-Type"))
+				assert.Contains(t, rr.Body.String(), "# TYPE a gauge\na 1\n")
+			}
+		})
+	}
+}
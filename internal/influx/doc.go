@@ -0,0 +1,2 @@
+// Module with a parser of the InfluxDB line protocol and its mapping to metrics.
+package influx
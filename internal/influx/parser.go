@@ -0,0 +1,238 @@
+package influx
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+)
+
+// Point a single line of the line protocol.
+type Point struct {
+	Measurement string
+	Tags        metrics.Labels
+	// Fields values are float64, int64, uint64, bool or string.
+	Fields    map[string]any
+	Timestamp time.Time
+}
+
+// Precision returns the unit of timestamps for the `precision` parameter: ns (default), us, ms or s.
+func Precision(value string) (time.Duration, error) {
+	switch value {
+	case "", "ns", "n":
+		return time.Nanosecond, nil
+	case "us", "u":
+		return time.Microsecond, nil
+	case "ms":
+		return time.Millisecond, nil
+	case "s":
+		return time.Second, nil
+	}
+	return 0, fmt.Errorf("bad precision `%s`", value)
+}
+
+// IntegerType returns the metric type of integer fields for the `integers` parameter:
+// counter (default) adds the values to the stored ones, gauge stores them as current values,
+// e.g. for cumulative integer fields of Telegraf.
+func IntegerType(value string) (metrics.MetricType, error) {
+	switch metrics.MetricType(value) {
+	case "", metrics.Counter:
+		return metrics.Counter, nil
+	case metrics.Gauge:
+		return metrics.Gauge, nil
+	}
+	return "", fmt.Errorf("bad integers type `%s`", value)
+}
+
+// ParseLine parses a line `measurement[,tag=value...] field=value[,field=value...] [timestamp]`.
+// A line without a timestamp gets the now time.
+func ParseLine(line string, precision time.Duration, now time.Time) (Point, error) {
+	var point Point
+
+	sections := split(line, ' ', true)
+	if len(sections) < 2 || len(sections) > 3 {
+		return point, errors.New("expected measurement, fields and optional timestamp")
+	}
+
+	key := split(sections[0], ',', false)
+	point.Measurement = unescape(key[0], ", ")
+	if point.Measurement == "" {
+		return point, errors.New("missing measurement")
+	}
+
+	for _, tag := range key[1:] {
+		parts := split(tag, '=', false)
+		if len(parts) != 2 || parts[0] == "" {
+			return point, fmt.Errorf("bad tag `%s`", tag)
+		}
+		if point.Tags == nil {
+			point.Tags = make(metrics.Labels)
+		}
+		point.Tags[unescape(parts[0], ",= ")] = unescape(parts[1], ",= ")
+	}
+	if err := point.Tags.Validate(); err != nil {
+		return point, err
+	}
+
+	point.Fields = make(map[string]any)
+	for _, field := range split(sections[1], ',', true) {
+		name, value, ok := cutUnescaped(field, '=')
+		if !ok || name == "" {
+			return point, fmt.Errorf("bad field `%s`", field)
+		}
+		parsed, err := parseFieldValue(value)
+		if err != nil {
+			return point, fmt.Errorf("field `%s`: %w", name, err)
+		}
+		point.Fields[unescape(name, ",= ")] = parsed
+	}
+
+	point.Timestamp = now
+	if len(sections) == 3 {
+		ts, err := strconv.ParseInt(sections[2], 10, 64)
+		if err != nil {
+			return point, fmt.Errorf("bad timestamp `%s`", sections[2])
+		}
+		unit := int64(precision)
+		if ts > math.MaxInt64/unit || ts < math.MinInt64/unit {
+			return point, fmt.Errorf("timestamp `%s` is out of range", sections[2])
+		}
+		point.Timestamp = time.Unix(0, ts*unit)
+	}
+
+	return point, nil
+}
+
+// Metrics maps the point to metrics named `measurement_field` with tags as labels:
+// integer fields become metrics of integerType (see IntegerType), float and boolean fields
+// become gauges, strings are skipped.
+func (p Point) Metrics(integerType metrics.MetricType) ([]metrics.Metrics, error) {
+	result := make([]metrics.Metrics, 0, len(p.Fields))
+	for name, value := range p.Fields {
+		m := metrics.Metrics{ID: p.Measurement + "_" + name, Labels: p.Tags}
+
+		switch v := value.(type) {
+		case int64:
+			if integerType == metrics.Gauge {
+				gauge := float64(v)
+				m.MType, m.Value = metrics.Gauge, &gauge
+				break
+			}
+			m.MType, m.Delta = metrics.Counter, &v
+		case uint64:
+			if integerType == metrics.Gauge {
+				gauge := float64(v)
+				m.MType, m.Value = metrics.Gauge, &gauge
+				break
+			}
+			if v > math.MaxInt64 {
+				return nil, fmt.Errorf("field `%s`: value %d overflows the counter", name, v)
+			}
+			delta := int64(v)
+			m.MType, m.Delta = metrics.Counter, &delta
+		case float64:
+			m.MType, m.Value = metrics.Gauge, &v
+		case bool:
+			gauge := 0.0
+			if v {
+				gauge = 1
+			}
+			m.MType, m.Value = metrics.Gauge, &gauge
+		default:
+			continue
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+func parseFieldValue(value string) (any, error) {
+	if value == "" {
+		return nil, errors.New("missing value")
+	}
+
+	if value[0] == '"' {
+		if len(value) < 2 || value[len(value)-1] != '"' {
+			return nil, errors.New("unterminated string")
+		}
+		return unescape(value[1:len(value)-1], `"\`), nil
+	}
+
+	switch value {
+	case "t", "T", "true", "True", "TRUE":
+		return true, nil
+	case "f", "F", "false", "False", "FALSE":
+		return false, nil
+	}
+
+	switch value[len(value)-1] {
+	case 'i':
+		return strconv.ParseInt(value[:len(value)-1], 10, 64)
+	case 'u':
+		return strconv.ParseUint(value[:len(value)-1], 10, 64)
+	}
+
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil, fmt.Errorf("value `%s` is not finite", value)
+	}
+	return v, nil
+}
+
+// split splits s by sep not escaped with a backslash,
+// with quoted set separators inside double quoted strings are kept.
+func split(s string, sep byte, quoted bool) []string {
+	var (
+		parts    []string
+		start    int
+		inQuotes bool
+	)
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case quoted && s[i] == '"':
+			inQuotes = !inQuotes
+		case s[i] == sep && !inQuotes:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// cutUnescaped cuts s around the first sep not escaped with a backslash.
+func cutUnescaped(s string, sep byte) (before, after string, found bool) {
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case sep:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+// unescape removes backslashes before the escaped characters, other backslashes are kept.
+func unescape(s, escaped string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(escaped, s[i+1]) >= 0 {
+			i++
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
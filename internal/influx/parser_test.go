@@ -0,0 +1,142 @@
+package influx
+
+import (
+	"testing"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name      string
+		line      string
+		precision time.Duration
+		expect    Point
+	}{
+		{
+			name: "all field types",
+			line: `cpu,host=a,region=eu usage=0.5,count=3i,total=7u,up=t,note="a b" 1717200000000000000`,
+			expect: Point{
+				Measurement: "cpu",
+				Tags:        metrics.Labels{"host": "a", "region": "eu"},
+				Fields: map[string]any{
+					"usage": 0.5, "count": int64(3), "total": uint64(7), "up": true, "note": "a b",
+				},
+				Timestamp: time.Unix(1717200000, 0),
+			},
+		},
+		{
+			name:   "no tags and timestamp",
+			line:   `mem free=42`,
+			expect: Point{Measurement: "mem", Fields: map[string]any{"free": 42.0}, Timestamp: now},
+		},
+		{
+			name: "escapes",
+			line: `disk\ io,path=/var\,log,dev=sd\=a read\ bytes=1i,msg="say \"hi\", ok"`,
+			expect: Point{
+				Measurement: "disk io",
+				Tags:        metrics.Labels{"path": "/var,log", "dev": "sd=a"},
+				Fields:      map[string]any{"read bytes": int64(1), "msg": `say "hi", ok`},
+				Timestamp:   now,
+			},
+		},
+		{
+			name:      "precision",
+			line:      `mem free=1 1717200000`,
+			precision: time.Second,
+			expect:    Point{Measurement: "mem", Fields: map[string]any{"free": 1.0}, Timestamp: time.Unix(1717200000, 0)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			precision := tc.precision
+			if precision == 0 {
+				precision = time.Nanosecond
+			}
+			point, err := ParseLine(tc.line, precision, now)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, point)
+		})
+	}
+
+	for _, line := range []string{
+		"cpu", ",host=a usage=1", "cpu,host usage=1", "cpu usage", "cpu usage=abc", `cpu note="abc`,
+		"cpu usage=1 abc", "cpu usage=1 1 2", "cpu,bad-tag=a usage=1", "cpu usage=1x",
+		"cpu usage=NaN", "cpu usage=+Inf", "cpu usage=-inf", "cpu usage=1e400",
+	} {
+		_, err := ParseLine(line, time.Nanosecond, now)
+		assert.Error(t, err, line)
+	}
+
+	// the timestamp overflows in nanoseconds.
+	_, err := ParseLine("cpu usage=1 9300000000000", time.Second, now)
+	assert.ErrorContains(t, err, "out of range")
+	_, err = ParseLine("cpu usage=1 -9300000000", time.Second, now)
+	assert.ErrorContains(t, err, "out of range")
+}
+
+func TestPointMetrics(t *testing.T) {
+	point := Point{
+		Measurement: "cpu",
+		Tags:        metrics.Labels{"host": "a"},
+		Fields:      map[string]any{"usage": 0.5, "count": int64(3), "total": uint64(7), "up": false, "note": "x"},
+	}
+
+	list, err := point.Metrics(metrics.Counter)
+	require.NoError(t, err)
+
+	got := make(map[string]metrics.Metrics)
+	for _, m := range list {
+		got[m.ID] = m
+	}
+	require.Len(t, got, 4)
+	assert.Equal(t, metrics.Gauge, got["cpu_usage"].MType)
+	assert.Equal(t, 0.5, *got["cpu_usage"].Value)
+	assert.Equal(t, int64(3), *got["cpu_count"].Delta)
+	assert.Equal(t, int64(7), *got["cpu_total"].Delta)
+	assert.Equal(t, 0.0, *got["cpu_up"].Value)
+	assert.Equal(t, metrics.Labels{"host": "a"}, got["cpu_count"].Labels)
+
+	point.Fields = map[string]any{"total": uint64(1 << 63)}
+	_, err = point.Metrics(metrics.Counter)
+	assert.Error(t, err)
+
+	// integer fields are stored as current values.
+	point.Fields = map[string]any{"count": int64(3), "total": uint64(1 << 63)}
+	list, err = point.Metrics(metrics.Gauge)
+	require.NoError(t, err)
+	for _, m := range list {
+		assert.Equal(t, metrics.Gauge, m.MType, m.ID)
+		assert.NotNil(t, m.Value, m.ID)
+	}
+}
+
+func TestIntegerType(t *testing.T) {
+	for value, expect := range map[string]metrics.MetricType{"": metrics.Counter, "counter": metrics.Counter, "gauge": metrics.Gauge} {
+		integerType, err := IntegerType(value)
+		require.NoError(t, err)
+		assert.Equal(t, expect, integerType)
+	}
+
+	_, err := IntegerType("histogram")
+	assert.Error(t, err)
+}
+
+func TestPrecision(t *testing.T) {
+	for value, expect := range map[string]time.Duration{
+		"": time.Nanosecond, "ns": time.Nanosecond, "us": time.Microsecond, "ms": time.Millisecond, "s": time.Second,
+	} {
+		precision, err := Precision(value)
+		require.NoError(t, err)
+		assert.Equal(t, expect, precision)
+	}
+
+	_, err := Precision("h")
+	assert.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// AdminTimestampHeader the header with the unix time in seconds the admin request is signed at.
+	AdminTimestampHeader = "X-Admin-Timestamp"
+	// AdminSignatureHeader the header with the signature of the admin request, see AdminSignature.
+	AdminSignatureHeader = "X-Admin-Signature"
+	// AdminMaxClockSkew how far the signing time of an admin request may be from the server time.
+	AdminMaxClockSkew = 5 * time.Minute
+)
+
+// AdminSignature returns the signature of an admin request: the hex sha256 of the method,
+// the request URI, the timestamp and the body followed by the key.
+func AdminSignature(method, requestURI, timestamp string, body []byte, hashKey string) string {
+	h := sha256.New()
+
+	h.Write([]byte(method + " " + requestURI + "\n" + timestamp + "\n"))
+	h.Write(body)
+	h.Write([]byte(hashKey))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// NewAdminMiddleware guards the routes which change the server state or remove stored data.
+//
+// The client address is the remote address of the connection. Only if the connection comes
+// from one of the trusted proxies, the address is taken from the X-Real-IP header.
+// If trusted subnets are given, the client address must be inside one of them. If the hash key
+// is set, the request must carry the time it is signed at in the X-Admin-Timestamp header and
+// its signature in the X-Admin-Signature header, see AdminSignature. Requests signed more than
+// AdminMaxClockSkew away from the server time are rejected, so a captured request can not be
+// replayed later. If neither is configured, only requests from the loopback interface are
+// allowed. Rejected requests get 403.
+func NewAdminMiddleware(key *HashKey, cidrs, proxies []string) (func(next http.Handler) http.Handler, error) {
+	subnets, err := ParseTrustedSubnets(cidrs)
+	if err != nil {
+		return nil, err
+	}
+	trustedProxies, err := ParseTrustedSubnets(proxies)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hashKey := key.Load()
+			ip := clientIP(r, trustedProxies)
+
+			if len(subnets) == 0 && hashKey == "" && !isLoopback(ip) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			if !subnets.Allows(ip) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			if hashKey != "" {
+				ok, err := checkAdminSignature(r, hashKey, time.Now())
+				if err != nil {
+					http.Error(w, "", http.StatusInternalServerError)
+					return
+				}
+				if !ok {
+					http.Error(w, "Forbidden", http.StatusForbidden)
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
+
+// checkAdminSignature reports whether the request is signed with the key recently enough.
+// The body is consumed by hashing, so it is restored for the next handlers.
+func checkAdminSignature(r *http.Request, hashKey string, now time.Time) (bool, error) {
+	timestamp := r.Header.Get(AdminTimestampHeader)
+	signedAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	if skew := now.Sub(time.Unix(signedAt, 0)); skew > AdminMaxClockSkew || skew < -AdminMaxClockSkew {
+		return false, nil
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return false, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	signature := AdminSignature(r.Method, r.URL.RequestURI(), timestamp, body, hashKey)
+
+	return subtle.ConstantTimeCompare([]byte(signature), []byte(r.Header.Get(AdminSignatureHeader))) == 1, nil
+}
+
+// clientIP returns the address of the client: the X-Real-IP header if the connection
+// comes from a trusted proxy, the remote address otherwise.
+func clientIP(r *http.Request, trustedProxies TrustedSubnets) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return ""
+	}
+	if realIP := r.Header.Get(RealIPHeader); realIP != "" && len(trustedProxies) > 0 && trustedProxies.Allows(host) {
+		return realIP
+	}
+	return host
+}
+
+// isLoopback reports whether the address is a loopback one.
+func isLoopback(ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.IsLoopback()
+}
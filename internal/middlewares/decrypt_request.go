@@ -0,0 +1,74 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"io"
+	"net/http"
+
+	"github.com/screamsoul/go-metrics-tpl/pkg/encryption"
+)
+
+// decryptedKey the context key marking requests decrypted by NewDecryptMiddleware.
+type decryptedKey struct{}
+
+// NewDecryptMiddleware decrypts request bodies encrypted by the agent with the server's public key.
+//
+// Requests without the encryption header are passed as is, routes which accept only
+// encrypted requests are guarded with NewRequireEncryptionMiddleware. Encrypted requests
+// which cannot be decrypted, including any if privateKey is nil, are rejected.
+func NewDecryptMiddleware(privateKey *rsa.PrivateKey) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme := r.Header.Get(encryption.HeaderName)
+
+			if scheme == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if privateKey == nil || scheme != encryption.Scheme {
+				http.Error(w, "Unsupported encryption", http.StatusBadRequest)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, "", http.StatusInternalServerError)
+				return
+			}
+
+			plaintext, err := encryption.Decrypt(privateKey, body)
+			if err != nil {
+				http.Error(w, "The data cannot be decrypted", http.StatusBadRequest)
+				return
+			}
+
+			r.Body = io.NopCloser(bytes.NewReader(plaintext))
+			r.ContentLength = int64(len(plaintext))
+			r.Header.Del(encryption.HeaderName)
+
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), decryptedKey{}, true)))
+		})
+	}
+}
+
+// NewRequireEncryptionMiddleware rejects requests which were not decrypted by NewDecryptMiddleware,
+// so that a plaintext body cannot bypass the encryption. If privateKey is nil, all requests are passed.
+func NewRequireEncryptionMiddleware(privateKey *rsa.PrivateKey) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if privateKey == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if decrypted, _ := r.Context().Value(decryptedKey{}).(bool); !decrypted {
+				http.Error(w, "The data must be encrypted", http.StatusBadRequest)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -1,36 +1,58 @@
 package middlewares
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"fmt"
 	"io"
 	"net/http"
+	"sync/atomic"
 )
 
+// HashKey the key of the body hash, which can be replaced while the server is running.
+type HashKey struct {
+	key atomic.Pointer[string]
+}
+
+func NewHashKey(hashKey string) *HashKey {
+	k := &HashKey{}
+	k.Store(hashKey)
+	return k
+}
+
+// Load returns the current key.
+func (k *HashKey) Load() string {
+	return *k.key.Load()
+}
+
+// Store replaces the key for the next requests.
+func (k *HashKey) Store(hashKey string) {
+	k.key.Store(&hashKey)
+}
+
 func NewHashSumHeaderMiddleware(hashKey string) func(next http.Handler) http.Handler {
+	return NewReloadableHashSumHeaderMiddleware(NewHashKey(hashKey))
+}
+
+// NewReloadableHashSumHeaderMiddleware checks the body hash with the current value of the key.
+func NewReloadableHashSumHeaderMiddleware(key *HashKey) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			bodyHash := r.Header.Get("HashSHA256")
+			hashKey := key.Load()
 
 			if bodyHash == "" || hashKey == "" {
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			h := sha256.New()
-
-			buf := make([]byte, 4096) // 4KB buffer
-
-			if _, err := io.CopyBuffer(h, r.Body, buf); err != nil {
+			ok, err := checkBodyHash(r, bodyHash, hashKey)
+			if err != nil {
 				http.Error(w, "", http.StatusInternalServerError)
 				return
 			}
 
-			h.Write([]byte(hashKey))
-
-			dst := h.Sum(nil)
-
-			if bodyHash != fmt.Sprintf("%x", dst) {
+			if !ok {
 				http.Error(w, "The data is corrupted", http.StatusBadRequest)
 				return
 			}
@@ -40,3 +62,22 @@ func NewHashSumHeaderMiddleware(hashKey string) func(next http.Handler) http.Han
 		})
 	}
 }
+
+// checkBodyHash reports whether bodyHash is the hash of the request body with the key.
+// The body is consumed by hashing, so it is restored for the next handlers.
+func checkBodyHash(r *http.Request, bodyHash, hashKey string) (bool, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return false, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	h := sha256.New()
+
+	h.Write(body)
+	h.Write([]byte(hashKey))
+
+	dst := h.Sum(nil)
+
+	return bodyHash == fmt.Sprintf("%x", dst), nil
+}
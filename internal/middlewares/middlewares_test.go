@@ -3,12 +3,18 @@ package middlewares
 import (
 	"bytes"
 	"compress/gzip"
+	"crypto/rand"
+	"crypto/rsa"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
+	"github.com/screamsoul/go-metrics-tpl/pkg/encryption"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestGzipDecompressMiddleware(t *testing.T) {
@@ -222,3 +228,390 @@ func TestNewHashSumHeaderMiddleware(t *testing.T) {
 		})
 	}
 }
+
+func TestNewDecryptMiddleware(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	encrypt := func(key *rsa.PrivateKey, body string) string {
+		message, err := encryption.Encrypt(&key.PublicKey, []byte(body))
+		require.NoError(t, err)
+		return string(message)
+	}
+
+	testCase := []struct {
+		name           string
+		privateKey     *rsa.PrivateKey
+		requestBody    string
+		scheme         string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			name:           "Encrypted body",
+			privateKey:     privateKey,
+			requestBody:    encrypt(privateKey, "testBody"),
+			scheme:         encryption.Scheme,
+			expectedStatus: http.StatusOK,
+			expectedBody:   "testBody",
+		},
+		{
+			name:           "Plain body",
+			privateKey:     privateKey,
+			requestBody:    "testBody",
+			expectedStatus: http.StatusOK,
+			expectedBody:   "testBody",
+		},
+		{
+			name:           "Encrypted with another key",
+			privateKey:     privateKey,
+			requestBody:    encrypt(otherKey, "testBody"),
+			scheme:         encryption.Scheme,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Garbage body",
+			privateKey:     privateKey,
+			requestBody:    "testBody",
+			scheme:         encryption.Scheme,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown scheme",
+			privateKey:     privateKey,
+			requestBody:    encrypt(privateKey, "testBody"),
+			scheme:         "rot13",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Missing private key",
+			requestBody:    encrypt(privateKey, "testBody"),
+			scheme:         encryption.Scheme,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBufferString(tc.requestBody))
+			if tc.scheme != "" {
+				req.Header.Set(encryption.HeaderName, tc.scheme)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler := NewDecryptMiddleware(tc.privateKey)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				_, err = w.Write(body)
+				assert.NoError(t, err)
+			}))
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, tc.expectedBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestNewRequireEncryptionMiddleware(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	encrypted, err := encryption.Encrypt(&privateKey.PublicKey, []byte("testBody"))
+	require.NoError(t, err)
+
+	testCase := []struct {
+		name           string
+		privateKey     *rsa.PrivateKey
+		requestBody    string
+		scheme         string
+		expectedStatus int
+	}{
+		{
+			name:           "Encrypted body",
+			privateKey:     privateKey,
+			requestBody:    string(encrypted),
+			scheme:         encryption.Scheme,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Plain body",
+			privateKey:     privateKey,
+			requestBody:    "testBody",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Plain body without key",
+			requestBody:    "testBody",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewBufferString(tc.requestBody))
+			if tc.scheme != "" {
+				req.Header.Set(encryption.HeaderName, tc.scheme)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler := NewDecryptMiddleware(tc.privateKey)(
+				NewRequireEncryptionMiddleware(tc.privateKey)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})),
+			)
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestNewTrustedSubnetMiddleware(t *testing.T) {
+	testCase := []struct {
+		name           string
+		subnets        []string
+		realIP         string
+		expectedStatus int
+	}{
+		{
+			name:           "No subnets",
+			realIP:         "",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Inside subnet",
+			subnets:        []string{"192.168.1.0/24"},
+			realIP:         "192.168.1.15",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Outside subnet",
+			subnets:        []string{"192.168.1.0/24"},
+			realIP:         "192.168.2.15",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Inside second subnet",
+			subnets:        []string{"192.168.1.0/24", "10.0.0.0/8"},
+			realIP:         "10.1.2.3",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Inside IPv6 subnet",
+			subnets:        []string{"192.168.1.0/24", "fd00::/8"},
+			realIP:         "fd12:3456::1",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Outside IPv6 subnet",
+			subnets:        []string{"fd00::/8"},
+			realIP:         "2001:db8::1",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Missing header",
+			subnets:        []string{"192.168.1.0/24"},
+			realIP:         "",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Invalid header",
+			subnets:        []string{"192.168.1.0/24"},
+			realIP:         "not-an-ip",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			middleware, err := NewTrustedSubnetMiddleware(tc.subnets)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/updates/", nil)
+			if tc.realIP != "" {
+				req.Header.Set(RealIPHeader, tc.realIP)
+			}
+
+			rr := httptest.NewRecorder()
+
+			handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestNewTrustedSubnetMiddleware_InvalidCIDR(t *testing.T) {
+	_, err := NewTrustedSubnetMiddleware([]string{"192.168.1.0/33"})
+	assert.Error(t, err)
+}
+
+func TestNewAdminMiddleware(t *testing.T) {
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-2*AdminMaxClockSkew).Unix(), 10)
+
+	sign := func(method, uri, timestamp, key string) string {
+		return AdminSignature(method, uri, timestamp, []byte("testBody"), key)
+	}
+
+	testCase := []struct {
+		name           string
+		hashKey        string
+		subnets        []string
+		proxies        []string
+		remoteAddr     string
+		realIP         string
+		timestamp      string
+		signature      string
+		expectedStatus int
+	}{
+		{
+			name:           "Loopback without protection",
+			remoteAddr:     "127.0.0.1:5000",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Remote without protection",
+			remoteAddr:     "192.0.2.1:5000",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Remote with spoofed loopback X-Real-IP",
+			remoteAddr:     "192.0.2.1:5000",
+			realIP:         "127.0.0.1",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Signed",
+			hashKey:        "testKey",
+			remoteAddr:     "192.0.2.1:5000",
+			timestamp:      now,
+			signature:      sign(http.MethodPost, "/admin/reload", now, "testKey"),
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Unsigned",
+			hashKey:        "testKey",
+			remoteAddr:     "127.0.0.1:5000",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Signed with another key",
+			hashKey:        "testKey",
+			remoteAddr:     "192.0.2.1:5000",
+			timestamp:      now,
+			signature:      sign(http.MethodPost, "/admin/reload", now, "otherKey"),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Signed for another route",
+			hashKey:        "testKey",
+			remoteAddr:     "192.0.2.1:5000",
+			timestamp:      now,
+			signature:      sign(http.MethodDelete, "/value/", now, "testKey"),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Signed too long ago",
+			hashKey:        "testKey",
+			remoteAddr:     "192.0.2.1:5000",
+			timestamp:      old,
+			signature:      sign(http.MethodPost, "/admin/reload", old, "testKey"),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Signature without timestamp",
+			hashKey:        "testKey",
+			remoteAddr:     "192.0.2.1:5000",
+			signature:      sign(http.MethodPost, "/admin/reload", "", "testKey"),
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Inside subnet",
+			subnets:        []string{"10.0.0.0/8"},
+			remoteAddr:     "10.1.2.3:5000",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Outside subnet",
+			subnets:        []string{"10.0.0.0/8"},
+			remoteAddr:     "192.168.1.1:5000",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "X-Real-IP from untrusted connection",
+			subnets:        []string{"10.0.0.0/8"},
+			remoteAddr:     "192.0.2.1:5000",
+			realIP:         "10.1.2.3",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "X-Real-IP from trusted proxy",
+			subnets:        []string{"10.0.0.0/8"},
+			proxies:        []string{"192.0.2.0/24"},
+			remoteAddr:     "192.0.2.1:5000",
+			realIP:         "10.1.2.3",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Outside subnet behind trusted proxy",
+			subnets:        []string{"10.0.0.0/8"},
+			proxies:        []string{"10.0.0.0/8"},
+			remoteAddr:     "10.0.0.1:5000",
+			realIP:         "192.168.1.1",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "Inside subnet unsigned",
+			hashKey:        "testKey",
+			subnets:        []string{"10.0.0.0/8"},
+			remoteAddr:     "10.1.2.3:5000",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCase {
+		t.Run(tc.name, func(t *testing.T) {
+			middleware, err := NewAdminMiddleware(NewHashKey(tc.hashKey), tc.subnets, tc.proxies)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/reload", bytes.NewBufferString("testBody"))
+			req.RemoteAddr = tc.remoteAddr
+			if tc.realIP != "" {
+				req.Header.Set(RealIPHeader, tc.realIP)
+			}
+			if tc.timestamp != "" {
+				req.Header.Set(AdminTimestampHeader, tc.timestamp)
+			}
+			if tc.signature != "" {
+				req.Header.Set(AdminSignatureHeader, tc.signature)
+			}
+
+			rr := httptest.NewRecorder()
+
+			middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, "testBody", string(body))
+			})).ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+		})
+	}
+}
+
+func TestNewAdminMiddleware_InvalidCIDR(t *testing.T) {
+	_, err := NewAdminMiddleware(NewHashKey(""), nil, []string{"192.168.1.0/33"})
+	assert.Error(t, err)
+}
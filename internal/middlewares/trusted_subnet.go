@@ -0,0 +1,69 @@
+package middlewares
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// RealIPHeader the header with the address of the agent which sends the request.
+const RealIPHeader = "X-Real-IP"
+
+// TrustedSubnets a list of subnets agents are allowed to report from.
+type TrustedSubnets []*net.IPNet
+
+// ParseTrustedSubnets parses IPv4 and IPv6 CIDRs.
+func ParseTrustedSubnets(cidrs []string) (TrustedSubnets, error) {
+	subnets := make(TrustedSubnets, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, subnet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted subnet: %w", err)
+		}
+		subnets = append(subnets, subnet)
+	}
+	return subnets, nil
+}
+
+// Allows reports whether the address is inside one of the subnets.
+// An empty list allows any address, even a missing one.
+func (s TrustedSubnets) Allows(realIP string) bool {
+	if len(s) == 0 {
+		return true
+	}
+
+	ip := net.ParseIP(realIP)
+	if ip == nil {
+		return false
+	}
+
+	for _, subnet := range s {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewTrustedSubnetMiddleware allows requests only from agents inside one of the trusted subnets.
+//
+// The agent address is taken from the X-Real-IP header, requests without it or from
+// outside the subnets get 403. Both IPv4 and IPv6 CIDRs are supported.
+// If no subnets are given, all requests are allowed.
+func NewTrustedSubnetMiddleware(cidrs []string) (func(next http.Handler) http.Handler, error) {
+	subnets, err := ParseTrustedSubnets(cidrs)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !subnets.Allows(r.Header.Get(RealIPHeader)) {
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+)
+
+// DefaultHistogramBounds bucket upper bounds used when a histogram is created from a single observation.
+var DefaultHistogramBounds = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// HistogramValue a histogram of observations with configurable bucket bounds.
+//
+// Counts[i] is the number of observations in (Bounds[i-1], Bounds[i]],
+// the last element of Counts holds observations greater than the last bound.
+type HistogramValue struct {
+	Bounds []float64 `json:"bounds"`
+	Counts []uint64  `json:"counts"`
+	Sum    float64   `json:"sum"`
+	Count  uint64    `json:"count"`
+}
+
+// NewHistogramValue creates an empty histogram with the bucket bounds.
+func NewHistogramValue(bounds []float64) *HistogramValue {
+	return &HistogramValue{
+		Bounds: slices.Clone(bounds),
+		Counts: make([]uint64, len(bounds)+1),
+	}
+}
+
+// Observe adds one observation to the histogram.
+func (h *HistogramValue) Observe(v float64) {
+	i := sort.SearchFloat64s(h.Bounds, v)
+	h.Counts[i]++
+	h.Sum += v
+	h.Count++
+}
+
+// Validate checks that bounds are strictly increasing, counts match them
+// and the bounds and the sum are finite, so the histogram can be encoded to json.
+func (h *HistogramValue) Validate() error {
+	if err := h.checkCounts(); err != nil {
+		return err
+	}
+	if !isFinite(h.Sum) || slices.ContainsFunc(h.Bounds, func(b float64) bool { return !isFinite(b) }) {
+		return errors.New("histogram bounds and sum must be finite")
+	}
+	for i := 1; i < len(h.Bounds); i++ {
+		if h.Bounds[i] <= h.Bounds[i-1] {
+			return errors.New("histogram bounds must be strictly increasing")
+		}
+	}
+	var total uint64
+	for _, c := range h.Counts {
+		total += c
+	}
+	if total != h.Count {
+		return fmt.Errorf("histogram count %d does not match the sum of bucket counts %d", h.Count, total)
+	}
+	return nil
+}
+
+// Merge adds observations of the other histogram, bucket bounds must be equal.
+func (h *HistogramValue) Merge(other *HistogramValue) error {
+	if err := h.checkCompatible(other); err != nil {
+		return err
+	}
+	for i := range h.Counts {
+		h.Counts[i] += other.Counts[i]
+	}
+	h.Sum += other.Sum
+	h.Count += other.Count
+	return nil
+}
+
+// Sub removes observations of the other histogram, e.g. the ones already reported.
+// Bucket bounds must be equal and no bucket of the other histogram may have more observations.
+func (h *HistogramValue) Sub(other *HistogramValue) error {
+	if err := h.checkCompatible(other); err != nil {
+		return err
+	}
+	for i := range h.Counts {
+		if other.Counts[i] > h.Counts[i] {
+			return errors.New("histogram has fewer observations than the subtracted one")
+		}
+	}
+	for i := range h.Counts {
+		h.Counts[i] -= other.Counts[i]
+	}
+	h.Sum -= other.Sum
+	h.Count -= other.Count
+	return nil
+}
+
+// checkCounts checks that there is a count for every bucket.
+func (h *HistogramValue) checkCounts() error {
+	if len(h.Counts) != len(h.Bounds)+1 {
+		return fmt.Errorf("histogram must have %d counts for %d bounds, got %d", len(h.Bounds)+1, len(h.Bounds), len(h.Counts))
+	}
+	return nil
+}
+
+// checkCompatible checks that the histograms have the same buckets.
+func (h *HistogramValue) checkCompatible(other *HistogramValue) error {
+	if !slices.Equal(h.Bounds, other.Bounds) {
+		return errors.New("histogram bounds mismatch")
+	}
+	if err := h.checkCounts(); err != nil {
+		return err
+	}
+	return other.checkCounts()
+}
+
+func isFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+// Clone returns a deep copy of the histogram.
+func (h *HistogramValue) Clone() *HistogramValue {
+	return &HistogramValue{
+		Bounds: slices.Clone(h.Bounds),
+		Counts: slices.Clone(h.Counts),
+		Sum:    h.Sum,
+		Count:  h.Count,
+	}
+}
+
+// Value implements driver.Valuer, the histogram is stored as a json object.
+func (h *HistogramValue) Value() (driver.Value, error) {
+	if h == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner.
+func (h *HistogramValue) Scan(src any) error {
+	switch v := src.(type) {
+	case []byte:
+		return json.Unmarshal(v, h)
+	case string:
+		return json.Unmarshal([]byte(v), h)
+	default:
+		return fmt.Errorf("unsupported histogram type: %T", src)
+	}
+}
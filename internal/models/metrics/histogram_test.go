@@ -0,0 +1,130 @@
+package metrics_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistogramObserve(t *testing.T) {
+	h := metrics.NewHistogramValue([]float64{1, 5})
+
+	for _, v := range []float64{0.5, 1, 3, 5, 7} {
+		h.Observe(v)
+	}
+
+	assert.Equal(t, []uint64{2, 2, 1}, h.Counts)
+	assert.Equal(t, uint64(5), h.Count)
+	assert.Equal(t, 16.5, h.Sum)
+	assert.NoError(t, h.Validate())
+}
+
+func TestHistogramValidate(t *testing.T) {
+	testCases := []struct {
+		name      string
+		histogram metrics.HistogramValue
+		wantErr   bool
+	}{
+		{
+			name:      "valid",
+			histogram: metrics.HistogramValue{Bounds: []float64{1, 2}, Counts: []uint64{1, 0, 1}, Count: 2, Sum: 3},
+		},
+		{
+			name:      "counts length mismatch",
+			histogram: metrics.HistogramValue{Bounds: []float64{1, 2}, Counts: []uint64{1, 0}, Count: 1},
+			wantErr:   true,
+		},
+		{
+			name:      "unordered bounds",
+			histogram: metrics.HistogramValue{Bounds: []float64{2, 1}, Counts: []uint64{0, 0, 0}},
+			wantErr:   true,
+		},
+		{
+			name:      "count mismatch",
+			histogram: metrics.HistogramValue{Bounds: []float64{1}, Counts: []uint64{1, 1}, Count: 3},
+			wantErr:   true,
+		},
+		{
+			name:      "infinite sum",
+			histogram: metrics.HistogramValue{Bounds: []float64{1}, Counts: []uint64{1, 0}, Count: 1, Sum: math.Inf(1)},
+			wantErr:   true,
+		},
+		{
+			name:      "nan bound",
+			histogram: metrics.HistogramValue{Bounds: []float64{math.NaN()}, Counts: []uint64{0, 0}},
+			wantErr:   true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.histogram.Validate()
+			if tc.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestHistogramMerge(t *testing.T) {
+	a := metrics.NewHistogramValue([]float64{1})
+	a.Observe(0.5)
+	b := metrics.NewHistogramValue([]float64{1})
+	b.Observe(2)
+
+	require.NoError(t, a.Merge(b))
+	assert.Equal(t, []uint64{1, 1}, a.Counts)
+	assert.Equal(t, uint64(2), a.Count)
+
+	assert.Error(t, a.Merge(metrics.NewHistogramValue([]float64{2})))
+	assert.Error(t, a.Merge(&metrics.HistogramValue{Bounds: []float64{1}, Counts: []uint64{1}, Count: 1}))
+}
+
+func TestHistogramSub(t *testing.T) {
+	a := metrics.NewHistogramValue([]float64{1})
+	a.Observe(0.5)
+	a.Observe(2)
+	b := metrics.NewHistogramValue([]float64{1})
+	b.Observe(0.5)
+
+	require.NoError(t, a.Sub(b))
+	assert.Equal(t, []uint64{0, 1}, a.Counts)
+	assert.Equal(t, uint64(1), a.Count)
+	assert.Equal(t, 2.0, a.Sum)
+
+	assert.Error(t, a.Sub(b))
+	assert.Error(t, a.Sub(metrics.NewHistogramValue([]float64{2})))
+	assert.Error(t, a.Sub(&metrics.HistogramValue{Bounds: []float64{1}, Counts: []uint64{0}}))
+}
+
+func TestNewMetricHistogram(t *testing.T) {
+	m, err := metrics.NewMetric("histogram", "latency", "0.3")
+	require.NoError(t, err)
+	require.NotNil(t, m.Histogram)
+	assert.Equal(t, uint64(1), m.Histogram.Count)
+	assert.Equal(t, 0.3, m.Histogram.Sum)
+	assert.NoError(t, m.ValidateValue())
+
+	_, err = metrics.NewMetric("histogram", "latency", "bad")
+	assert.Error(t, err)
+	_, err = metrics.NewMetric("histogram", "latency", "NaN")
+	assert.Error(t, err)
+}
+
+func TestGetValueHistogram(t *testing.T) {
+	m := metrics.Metrics{ID: "latency", MType: metrics.Histogram, Histogram: metrics.NewHistogramValue([]float64{1})}
+	m.Histogram.Observe(0.5)
+
+	value, err := m.GetValue()
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"bounds":[1],"counts":[1,0],"sum":0.5,"count":1}`, value)
+
+	m.Histogram.Sum = math.Inf(1)
+	_, err = m.GetValue()
+	assert.Error(t, err)
+}
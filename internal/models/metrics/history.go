@@ -0,0 +1,41 @@
+package metrics
+
+import "time"
+
+// Point a single value of a metric series at the moment of time.
+type Point struct {
+	Timestamp time.Time `json:"ts" db:"ts"`
+	Value     float64   `json:"value" db:"value"`
+}
+
+// Rollup aggregated samples of a metric series within the bucket starting at Timestamp.
+type Rollup struct {
+	Timestamp time.Time `json:"ts" db:"bucket"`
+	Min       float64   `json:"min" db:"min"`
+	Max       float64   `json:"max" db:"max"`
+	Sum       float64   `json:"sum" db:"sum"`
+	Count     int64     `json:"count" db:"count"`
+	Last      float64   `json:"last" db:"last"`
+}
+
+// NewRollup creates a rollup of the bucket with a single value.
+func NewRollup(bucket time.Time, value float64) Rollup {
+	return Rollup{Timestamp: bucket, Min: value, Max: value, Sum: value, Count: 1, Last: value}
+}
+
+// Avg returns the average value of the aggregated samples.
+func (r Rollup) Avg() float64 {
+	if r.Count == 0 {
+		return 0
+	}
+	return r.Sum / float64(r.Count)
+}
+
+// Merge adds the other rollup to this one, the other rollup must contain newer samples.
+func (r *Rollup) Merge(other Rollup) {
+	r.Min = min(r.Min, other.Min)
+	r.Max = max(r.Max, other.Max)
+	r.Sum += other.Sum
+	r.Count += other.Count
+	r.Last = other.Last
+}
@@ -0,0 +1,97 @@
+package metrics
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Labels a set of key-value pairs (tags) identifying a metric series along with its name.
+type Labels map[string]string
+
+// Keys returns the label names in sorted order.
+func (l Labels) Keys() []string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// String returns the canonical representation of labels: `{a="1",b="2"}` with sorted names.
+func (l Labels) String() string {
+	if len(l) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, k := range l.Keys() {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(k)
+		sb.WriteByte('=')
+		sb.WriteString(strconv.Quote(l[k]))
+	}
+	sb.WriteByte('}')
+
+	return sb.String()
+}
+
+// Validate checks that all label names are non-empty identifiers.
+func (l Labels) Validate() error {
+	for k := range l {
+		if !labelNameRe.MatchString(k) {
+			return fmt.Errorf("label name `%s` is not valid", k)
+		}
+	}
+	return nil
+}
+
+// Value implements driver.Valuer, labels are stored as a json object.
+func (l Labels) Value() (driver.Value, error) {
+	if len(l) == 0 {
+		return "{}", nil
+	}
+	data, err := json.Marshal(map[string]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner, empty json objects are scanned as nil labels.
+func (l *Labels) Scan(src any) error {
+	var data []byte
+
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported labels type: %T", src)
+	}
+
+	var m map[string]string
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	if len(m) == 0 {
+		*l = nil
+		return nil
+	}
+	*l = m
+	return nil
+}
@@ -0,0 +1,84 @@
+package metrics_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesKey(t *testing.T) {
+	testCases := []struct {
+		name   string
+		metric metrics.Metrics
+		expect string
+	}{
+		{
+			name:   "without labels",
+			metric: metrics.Metrics{ID: "Alloc"},
+			expect: "Alloc",
+		},
+		{
+			name:   "sorted labels",
+			metric: metrics.Metrics{ID: "Alloc", Labels: metrics.Labels{"service": "api", "host": "a"}},
+			expect: `Alloc{host="a",service="api"}`,
+		},
+		{
+			name:   "escaped values",
+			metric: metrics.Metrics{ID: "Alloc", Labels: metrics.Labels{"path": `/a"b`}},
+			expect: `Alloc{path="/a\"b"}`,
+		},
+		{
+			name:   "braces in the name",
+			metric: metrics.Metrics{ID: `foo{a="1"}`},
+			expect: `foo\{a="1"}`,
+		},
+		{
+			name:   "backslash in the name",
+			metric: metrics.Metrics{ID: `foo\`, Labels: metrics.Labels{"a": "1"}},
+			expect: `foo\\{a="1"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expect, tc.metric.SeriesKey())
+		})
+	}
+
+	// a name with braces does not collide with a labelled series.
+	named := metrics.Metrics{ID: `foo{a="1"}`}
+	labelled := metrics.Metrics{ID: "foo", Labels: metrics.Labels{"a": "1"}}
+	assert.NotEqual(t, named.SeriesKey(), labelled.SeriesKey())
+}
+
+func TestUnmarshalLabels(t *testing.T) {
+	var m metrics.Metrics
+	err := json.Unmarshal([]byte(`{"id":"a","type":"gauge","value":1,"labels":{"host":"h1"}}`), &m)
+	require.NoError(t, err)
+	assert.Equal(t, metrics.Labels{"host": "h1"}, m.Labels)
+
+	err = json.Unmarshal([]byte(`{"id":"a","type":"gauge","value":1,"labels":{"bad name":"h1"}}`), &m)
+	assert.Error(t, err)
+}
+
+func TestLabelsScan(t *testing.T) {
+	var l metrics.Labels
+
+	require.NoError(t, l.Scan([]byte(`{"host":"h1"}`)))
+	assert.Equal(t, metrics.Labels{"host": "h1"}, l)
+
+	require.NoError(t, l.Scan("{}"))
+	assert.Nil(t, l)
+
+	require.NoError(t, l.Scan(nil))
+	assert.Nil(t, l)
+
+	assert.Error(t, l.Scan(1))
+
+	v, err := metrics.Labels(nil).Value()
+	require.NoError(t, err)
+	assert.Equal(t, "{}", v)
+}
@@ -5,17 +5,19 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 type MetricType string
 
 const (
-	Gauge   MetricType = "gauge"
-	Counter MetricType = "counter"
+	Gauge     MetricType = "gauge"
+	Counter   MetricType = "counter"
+	Histogram MetricType = "histogram"
 )
 
 func (mt MetricType) IsValid() bool {
-	if mt == Gauge || mt == Counter {
+	if mt == Gauge || mt == Counter || mt == Histogram {
 		return true
 	}
 	return false
@@ -24,10 +26,12 @@ func (mt MetricType) IsValid() bool {
 
 // Metrics a structure for storing information about metrics.
 type Metrics struct {
-	ID    string     `json:"id" db:"name"`                         // имя метрики
-	MType MetricType `json:"type" db:"m_type"`                     // параметр, принимающий значение gauge или counter
-	Delta *int64     `json:"delta,omitempty" db:"delta,omitempty"` // значение метрики в случае передачи counter
-	Value *float64   `json:"value,omitempty" db:"value,omitempty"` // значение метрики в случае передачи gauge
+	ID        string          `json:"id" db:"name"`                         // имя метрики
+	MType     MetricType      `json:"type" db:"m_type"`                     // параметр, принимающий значение gauge, counter или histogram
+	Delta     *int64          `json:"delta,omitempty" db:"delta,omitempty"` // значение метрики в случае передачи counter
+	Value     *float64        `json:"value,omitempty" db:"value,omitempty"` // значение метрики в случае передачи gauge
+	Histogram *HistogramValue `json:"histogram,omitempty" db:"histogram"`   // значение метрики в случае передачи histogram
+	Labels    Labels          `json:"labels,omitempty" db:"labels"`         // метки (теги) серии метрики
 }
 
 func NewMetric(metricType, metricName, metricValue string) (*Metrics, error) {
@@ -58,19 +62,46 @@ func NewMetric(metricType, metricName, metricValue string) (*Metrics, error) {
 			return nil, fmt.Errorf("failed to parse metric delta as int64: %w", err)
 		}
 		metrics.Delta = &delta
+	case Histogram:
+		value, err := strconv.ParseFloat(metricValue, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse histogram observation as float64: %w", err)
+		}
+		if !isFinite(value) {
+			return nil, fmt.Errorf("histogram observation %v is not finite", value)
+		}
+		metrics.Histogram = NewHistogramValue(DefaultHistogramBounds)
+		metrics.Histogram.Observe(value)
 	}
 
 	return metrics, nil
 }
 
-func (m *Metrics) GetValue() (val string) {
+// GetValue returns the value as text, a histogram is returned as a json object.
+func (m *Metrics) GetValue() (string, error) {
 	switch m.MType {
 	case Gauge:
-		val = fmt.Sprint(*m.Value)
+		return fmt.Sprint(*m.Value), nil
 	case Counter:
-		val = fmt.Sprint(*m.Delta)
+		return fmt.Sprint(*m.Delta), nil
+	case Histogram:
+		data, err := json.Marshal(m.Histogram)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode histogram: %w", err)
+		}
+		return string(data), nil
 	}
-	return
+	return "", nil
+}
+
+// seriesKeyEscaper escapes the ID in the series key, so the labels start at the first unescaped `{`.
+var seriesKeyEscaper = strings.NewReplacer(`\`, `\\`, `{`, `\{`)
+
+// SeriesKey returns the canonical series identifier built from the name and sorted labels.
+// For a metric without labels the key is equal to the ID, unless the ID contains `{` or `\`:
+// they are escaped with `\`, so `foo{a="1"}` named metric and `foo` with the label a=1 are different series.
+func (m *Metrics) SeriesKey() string {
+	return seriesKeyEscaper.Replace(m.ID) + m.Labels.String()
 }
 
 // ValidateType checks the validity of the metric type.
@@ -92,6 +123,11 @@ func (m *Metrics) ValidateValue() error {
 		if m.Delta == nil {
 			return fmt.Errorf("metric type `%s` must be set Delta filed", m.MType)
 		}
+	case Histogram:
+		if m.Histogram == nil {
+			return fmt.Errorf("metric type `%s` must be set Histogram filed", m.MType)
+		}
+		return m.Histogram.Validate()
 	}
 	return nil
 }
@@ -112,5 +148,9 @@ func (m *Metrics) UnmarshalJSON(data []byte) error {
 
 	m.MType = MetricType(aux.MType)
 
-	return m.ValidateType()
+	if err := m.ValidateType(); err != nil {
+		return err
+	}
+
+	return m.Labels.Validate()
 }
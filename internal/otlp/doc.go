@@ -0,0 +1,2 @@
+// Module with an OTLP/HTTP receiver mapping OpenTelemetry metrics to the project metric model.
+package otlp
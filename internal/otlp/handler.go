@@ -0,0 +1,118 @@
+package otlp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	protobufContentType = "application/x-protobuf"
+	jsonContentType     = "application/json"
+
+	// maxBodySize the maximum size of a decompressed request body.
+	maxBodySize = 16 * 1024 * 1024
+)
+
+// codec encodes and decodes OTLP messages in the encoding of the request.
+type codec struct {
+	contentType string
+	marshal     func(proto.Message) ([]byte, error)
+	unmarshal   func([]byte, proto.Message) error
+}
+
+var (
+	protobufCodec = codec{
+		contentType: protobufContentType,
+		marshal:     proto.Marshal,
+		unmarshal:   proto.Unmarshal,
+	}
+	jsonCodec = codec{
+		contentType: jsonContentType,
+		marshal:     protojson.Marshal,
+		unmarshal:   protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal,
+	}
+)
+
+// MetricsHandler the OTLP/HTTP handler of POST /v1/metrics.
+//
+// The request is ExportMetricsServiceRequest in the protobuf (application/x-protobuf)
+// or JSON (application/json) encoding, the response uses the same encoding.
+// Rejected data points are reported in partial_success with status 200,
+// a bad request gets 400 and a storage error 503, so the exporter retries it.
+func (r *Receiver) MetricsHandler(w http.ResponseWriter, req *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(req.Header.Get("Content-Type"))
+
+	var c codec
+	switch mediaType {
+	case protobufContentType:
+		c = protobufCodec
+	case jsonContentType:
+		c = jsonCodec
+	default:
+		http.Error(w, fmt.Sprintf("unsupported content type `%s`", mediaType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			r.writeStatus(w, c, http.StatusRequestEntityTooLarge, codes.InvalidArgument, err)
+			return
+		}
+		r.writeStatus(w, c, http.StatusBadRequest, codes.InvalidArgument, err)
+		return
+	}
+
+	var request colmetricspb.ExportMetricsServiceRequest
+	if err := c.unmarshal(body, &request); err != nil {
+		r.writeStatus(w, c, http.StatusBadRequest, codes.InvalidArgument, err)
+		return
+	}
+
+	rejected, reason, err := r.Export(req.Context(), &request)
+	if err != nil {
+		r.logger.Error("error write otlp metrics", zap.Error(err))
+		r.writeStatus(w, c, http.StatusServiceUnavailable, codes.Unavailable, err)
+		return
+	}
+
+	response := &colmetricspb.ExportMetricsServiceResponse{}
+	if rejected > 0 {
+		response.PartialSuccess = &colmetricspb.ExportMetricsPartialSuccess{
+			RejectedDataPoints: rejected,
+			ErrorMessage:       reason,
+		}
+	}
+	r.write(w, c, http.StatusOK, response)
+}
+
+// writeStatus writes the error as google.rpc.Status like OTLP/HTTP requires.
+func (r *Receiver) writeStatus(w http.ResponseWriter, c codec, httpStatus int, code codes.Code, err error) {
+	r.write(w, c, httpStatus, status.New(code, err.Error()).Proto())
+}
+
+func (r *Receiver) write(w http.ResponseWriter, c codec, httpStatus int, message proto.Message) {
+	data, err := c.marshal(message)
+	if err != nil {
+		r.logger.Error("Error encoding response", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", c.contentType)
+	w.WriteHeader(httpStatus)
+	if _, err := w.Write(data); err != nil {
+		r.logger.Error("Error writing response", zap.Error(err))
+	}
+}
@@ -0,0 +1,118 @@
+package otlp_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/otlp"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+func post(t *testing.T, receiver *otlp.Receiver, contentType string, body []byte) *http.Response {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/metrics", bytes.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	receiver.MetricsHandler(w, req)
+	return w.Result()
+}
+
+func TestMetricsHandlerProtobuf(t *testing.T) {
+	store := memory.NewMemStorage()
+	receiver := otlp.NewReceiver(store)
+
+	body, err := proto.Marshal(request(
+		sum("requests", delta, true, 0, 3),
+		&metricspb.Metric{
+			Name: "rpc.duration",
+			Data: &metricspb.Metric_Summary{Summary: &metricspb.Summary{
+				DataPoints: []*metricspb.SummaryDataPoint{{Count: 1, Sum: 0.5}},
+			}},
+		},
+	))
+	require.NoError(t, err)
+
+	res := post(t, receiver, "application/x-protobuf", body)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/x-protobuf", res.Header.Get("Content-Type"))
+
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	var response colmetricspb.ExportMetricsServiceResponse
+	require.NoError(t, proto.Unmarshal(data, &response))
+	assert.Equal(t, int64(1), response.GetPartialSuccess().GetRejectedDataPoints())
+	assert.Contains(t, response.GetPartialSuccess().GetErrorMessage(), "summaries")
+
+	m := metrics.Metrics{ID: "requests", MType: metrics.Counter}
+	require.NoError(t, store.Get(context.Background(), &m))
+	assert.Equal(t, int64(3), *m.Delta)
+}
+
+func TestMetricsHandlerJSON(t *testing.T) {
+	store := memory.NewMemStorage()
+	receiver := otlp.NewReceiver(store)
+
+	body := `{"resourceMetrics":[{"scopeMetrics":[{"metrics":[{"name":"temperature",` +
+		`"gauge":{"dataPoints":[{"asDouble":21.5,"timeUnixNano":"1718000000000000000"}]}}]}]}]}`
+
+	res := post(t, receiver, "application/json; charset=utf-8", []byte(body))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	var response colmetricspb.ExportMetricsServiceResponse
+	require.NoError(t, protojson.Unmarshal(data, &response))
+	assert.Nil(t, response.GetPartialSuccess())
+
+	m := metrics.Metrics{ID: "temperature", MType: metrics.Gauge}
+	require.NoError(t, store.Get(context.Background(), &m))
+	assert.Equal(t, 21.5, *m.Value)
+}
+
+func TestMetricsHandlerErrors(t *testing.T) {
+	receiver := otlp.NewReceiver(memory.NewMemStorage())
+
+	res := post(t, receiver, "text/plain", []byte("requests 1"))
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnsupportedMediaType, res.StatusCode)
+
+	res = post(t, receiver, "application/json", []byte("{bad json"))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	data, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+
+	var st status.Status
+	require.NoError(t, protojson.Unmarshal(data, &st))
+	assert.NotEmpty(t, st.GetMessage())
+}
+
+func TestMetricsHandlerStorageError(t *testing.T) {
+	receiver := otlp.NewReceiver(&failingStorage{MemStorage: memory.NewMemStorage(), fail: true})
+
+	body, err := proto.Marshal(request(sum("requests", delta, true, 0, 1)))
+	require.NoError(t, err)
+
+	res := post(t, receiver, "application/x-protobuf", body)
+	res.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+}
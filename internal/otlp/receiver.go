@@ -0,0 +1,524 @@
+package otlp
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"github.com/screamsoul/go-metrics-tpl/pkg/logging"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"go.uber.org/zap"
+)
+
+// staleStreamAge streams not updated for this time are forgotten,
+// a forgotten cumulative stream is taken as an unseen one.
+const staleStreamAge = time.Hour
+
+// serviceNameLabel the label of the `service.name` resource attribute.
+const serviceNameLabel = "service_name"
+
+// streamKey identifies a stream: the same series sent by different resources,
+// e.g. by two instances of a service, are separate streams.
+type streamKey struct {
+	resource string
+	series   string
+}
+
+// resource the attributes of the resource sending a group of metrics.
+type resource struct {
+	// key the encoded attributes of the resource.
+	key string
+	// service the `service.name` attribute value.
+	service string
+}
+
+// counterState the last point of a monotonic sum stream.
+type counterState struct {
+	start uint64
+	last  float64
+	// carry the rounding remainder not written to the integer counter yet.
+	carry float64
+	seen  time.Time
+}
+
+// histogramState the last point of a cumulative histogram stream.
+type histogramState struct {
+	start     uint64
+	histogram *metrics.HistogramValue
+	seen      time.Time
+}
+
+// Receiver maps OTLP metrics to the metric storage.
+//
+// Monotonic sums become counters: delta points are added as they are, cumulative points
+// are converted to the increase since the previous point of the stream. A stream is a series
+// of a resource, identified by all its attributes. The first point of an unseen stream is taken
+// as the baseline, unless the stream started after the receiver: its earlier increase may have been
+// written before the restart of the server. A point with another start time or with a smaller value
+// starts the stream over from zero. Fractional increases are rounded, the remainder is carried to the next point.
+// Non-monotonic sums and gauges become gauges, a non-monotonic delta is added to the stored gauge.
+// Explicit bucket histograms become histograms, cumulative ones are converted to deltas the same way.
+// Exponential histograms and summaries are rejected.
+//
+// Data point attributes become labels with invalid characters replaced by `_`,
+// the `service.name` resource attribute becomes the `service_name` label.
+type Receiver struct {
+	store  repositories.MetricStorage
+	logger *zap.Logger
+
+	// started the time the receiver was created, streams started later are counted from zero.
+	started time.Time
+
+	mu         sync.Mutex
+	counters   map[streamKey]counterState
+	histograms map[streamKey]histogramState
+	swept      time.Time
+}
+
+func NewReceiver(store repositories.MetricStorage) *Receiver {
+	now := time.Now()
+	return &Receiver{
+		store:      store,
+		logger:     logging.GetLogger(),
+		started:    now,
+		counters:   make(map[streamKey]counterState),
+		histograms: make(map[streamKey]histogramState),
+		swept:      now,
+	}
+}
+
+// Export writes the metrics of the request to the storage and returns the number
+// of rejected data points with the first rejection reason.
+//
+// Stream states are updated only when the storage write succeeds,
+// so a retried request is converted the same way.
+func (r *Receiver) Export(ctx context.Context, req *colmetricspb.ExportMetricsServiceRequest) (int64, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	b := &batch{
+		receiver:   r,
+		now:        now,
+		counters:   make(map[streamKey]counterState),
+		histograms: make(map[streamKey]histogramState),
+		gauges:     make(map[string]float64),
+	}
+
+	for _, rm := range req.GetResourceMetrics() {
+		attributes := rm.GetResource().GetAttributes()
+		res := resource{
+			key:     resourceKey(attributes),
+			service: attributeValue(findAttribute(attributes, "service.name")),
+		}
+		for _, sm := range rm.GetScopeMetrics() {
+			for _, m := range sm.GetMetrics() {
+				b.addMetric(ctx, m, res)
+			}
+		}
+	}
+
+	if len(b.metrics) > 0 {
+		if err := r.store.BulkAdd(ctx, b.metrics); err != nil {
+			return 0, "", err
+		}
+	}
+
+	for key, s := range b.counters {
+		r.counters[key] = s
+	}
+	for key, s := range b.histograms {
+		r.histograms[key] = s
+	}
+	r.sweep(now)
+
+	return b.rejected, b.reason, nil
+}
+
+// sweep forgets stale streams, at most once per staleStreamAge.
+func (r *Receiver) sweep(now time.Time) {
+	if now.Sub(r.swept) < staleStreamAge {
+		return
+	}
+	r.swept = now
+
+	for key, s := range r.counters {
+		if now.Sub(s.seen) > staleStreamAge {
+			delete(r.counters, key)
+		}
+	}
+	for key, s := range r.histograms {
+		if now.Sub(s.seen) > staleStreamAge {
+			delete(r.histograms, key)
+		}
+	}
+}
+
+// startedAfter reports whether the stream with the start time (unix nanoseconds)
+// started after the receiver was created.
+func (r *Receiver) startedAfter(start uint64) bool {
+	return start > uint64(r.started.UnixNano())
+}
+
+// batch the metrics and stream states of a single request.
+type batch struct {
+	receiver *Receiver
+	now      time.Time
+	metrics  []metrics.Metrics
+
+	counters   map[streamKey]counterState
+	histograms map[streamKey]histogramState
+	// gauges values of non-monotonic delta sums written in this request.
+	gauges map[string]float64
+
+	rejected int64
+	reason   string
+}
+
+func (b *batch) reject(name string, count int, err error) {
+	if count == 0 {
+		return
+	}
+	if b.rejected == 0 {
+		b.reason = fmt.Sprintf("metric `%s`: %s", name, err)
+	}
+	b.rejected += int64(count)
+}
+
+func (b *batch) addMetric(ctx context.Context, m *metricspb.Metric, res resource) {
+	name := m.GetName()
+	if name == "" {
+		b.reject(name, countPoints(m), errors.New("missing name"))
+		return
+	}
+
+	switch data := m.GetData().(type) {
+	case *metricspb.Metric_Gauge:
+		for _, dp := range data.Gauge.GetDataPoints() {
+			if noRecordedValue(dp.GetFlags()) {
+				continue
+			}
+			value := numberValue(dp)
+			if math.IsNaN(value) || math.IsInf(value, 0) {
+				b.reject(name, 1, fmt.Errorf("bad gauge value %v", value))
+				continue
+			}
+			b.add(metrics.Metrics{ID: name, MType: metrics.Gauge, Labels: labels(dp.GetAttributes(), res.service), Value: &value})
+		}
+	case *metricspb.Metric_Sum:
+		for _, dp := range data.Sum.GetDataPoints() {
+			if noRecordedValue(dp.GetFlags()) {
+				continue
+			}
+			if err := b.addSumPoint(ctx, name, data.Sum, dp, res); err != nil {
+				b.reject(name, 1, err)
+			}
+		}
+	case *metricspb.Metric_Histogram:
+		for _, dp := range data.Histogram.GetDataPoints() {
+			if noRecordedValue(dp.GetFlags()) {
+				continue
+			}
+			if err := b.addHistogramPoint(name, data.Histogram.GetAggregationTemporality(), dp, res); err != nil {
+				b.reject(name, 1, err)
+			}
+		}
+	case *metricspb.Metric_ExponentialHistogram:
+		b.reject(name, countPoints(m), errors.New("exponential histograms are not supported"))
+	case *metricspb.Metric_Summary:
+		b.reject(name, countPoints(m), errors.New("summaries are not supported"))
+	default:
+		b.reject(name, 1, errors.New("missing data"))
+	}
+}
+
+func (b *batch) addSumPoint(ctx context.Context, name string, sum *metricspb.Sum, dp *metricspb.NumberDataPoint, res resource) error {
+	value := numberValue(dp)
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return fmt.Errorf("bad sum value %v", value)
+	}
+	temporality := sum.GetAggregationTemporality()
+	if err := checkTemporality(temporality); err != nil {
+		return err
+	}
+
+	m := metrics.Metrics{ID: name, Labels: labels(dp.GetAttributes(), res.service)}
+
+	if !sum.GetIsMonotonic() {
+		m.MType = metrics.Gauge
+		if temporality == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA {
+			key := m.SeriesKey()
+			current, ok := b.gauges[key]
+			if !ok {
+				stored := metrics.Metrics{ID: m.ID, MType: metrics.Gauge, Labels: m.Labels}
+				if err := b.receiver.store.Get(ctx, &stored); err == nil && stored.Value != nil {
+					current = *stored.Value
+				}
+			}
+			value += current
+			b.gauges[key] = value
+		}
+		m.Value = &value
+		b.add(m)
+		return nil
+	}
+
+	m.MType = metrics.Counter
+	key := streamKey{resource: res.key, series: m.SeriesKey()}
+	state, seen := b.counterState(key)
+
+	var increase float64
+	if temporality == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA {
+		if value < 0 {
+			return fmt.Errorf("monotonic delta %v is negative", value)
+		}
+		increase = value
+	} else {
+		start := dp.GetStartTimeUnixNano()
+		switch {
+		case seen && state.start == start && value >= state.last:
+			increase = value - state.last
+		case !seen && !b.receiver.startedAfter(start):
+			// the baseline of the stream.
+			increase = 0
+		default:
+			increase = value
+		}
+		state.start, state.last = start, value
+	}
+
+	total := increase + state.carry
+	delta := math.Round(total)
+	if delta > math.MaxInt64 {
+		return fmt.Errorf("value %v overflows the counter", value)
+	}
+	state.carry = total - delta
+	state.seen = b.now
+	b.counters[key] = state
+
+	if delta == 0 {
+		return nil
+	}
+	d := int64(delta)
+	m.Delta = &d
+	b.add(m)
+	return nil
+}
+
+func (b *batch) addHistogramPoint(
+	name string,
+	temporality metricspb.AggregationTemporality,
+	dp *metricspb.HistogramDataPoint,
+	res resource,
+) error {
+	if err := checkTemporality(temporality); err != nil {
+		return err
+	}
+
+	h := &metrics.HistogramValue{
+		Bounds: slices.Clone(dp.GetExplicitBounds()),
+		Counts: slices.Clone(dp.GetBucketCounts()),
+		Sum:    dp.GetSum(),
+		Count:  dp.GetCount(),
+	}
+	if len(h.Bounds) == 0 && len(h.Counts) == 0 {
+		h.Counts = []uint64{h.Count}
+	}
+	if err := h.Validate(); err != nil {
+		return err
+	}
+
+	m := metrics.Metrics{ID: name, MType: metrics.Histogram, Labels: labels(dp.GetAttributes(), res.service)}
+
+	if temporality == metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE {
+		key := streamKey{resource: res.key, series: m.SeriesKey()}
+		state, seen := b.histogramState(key)
+		start := dp.GetStartTimeUnixNano()
+		current := h.Clone()
+		switch {
+		case seen && state.start == start:
+			if diff, ok := subtractHistogram(h, state.histogram); ok {
+				h = diff
+			}
+		case !seen && !b.receiver.startedAfter(start):
+			// the baseline of the stream.
+			h = &metrics.HistogramValue{}
+		}
+		b.histograms[key] = histogramState{start: start, histogram: current, seen: b.now}
+	}
+
+	if h.Count == 0 {
+		return nil
+	}
+	m.Histogram = h
+	b.add(m)
+	return nil
+}
+
+func (b *batch) add(m metrics.Metrics) {
+	b.metrics = append(b.metrics, m)
+}
+
+// counterState returns the stream state staged in the request or the committed one.
+func (b *batch) counterState(key streamKey) (counterState, bool) {
+	if s, ok := b.counters[key]; ok {
+		return s, true
+	}
+	s, ok := b.receiver.counters[key]
+	return s, ok
+}
+
+// histogramState returns the stream state staged in the request or the committed one.
+func (b *batch) histogramState(key streamKey) (histogramState, bool) {
+	if s, ok := b.histograms[key]; ok {
+		return s, true
+	}
+	s, ok := b.receiver.histograms[key]
+	return s, ok
+}
+
+// subtractHistogram returns the observations of cur made after prev,
+// false if cur is not a continuation of prev: the bounds changed or a count decreased.
+func subtractHistogram(cur, prev *metrics.HistogramValue) (*metrics.HistogramValue, bool) {
+	if !slices.Equal(cur.Bounds, prev.Bounds) || cur.Count < prev.Count {
+		return nil, false
+	}
+	diff := cur.Clone()
+	for i := range diff.Counts {
+		if cur.Counts[i] < prev.Counts[i] {
+			return nil, false
+		}
+		diff.Counts[i] -= prev.Counts[i]
+	}
+	diff.Count -= prev.Count
+	diff.Sum -= prev.Sum
+	return diff, true
+}
+
+func checkTemporality(temporality metricspb.AggregationTemporality) error {
+	switch temporality {
+	case metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA,
+		metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE:
+		return nil
+	}
+	return fmt.Errorf("unsupported aggregation temporality %s", temporality)
+}
+
+func noRecordedValue(flags uint32) bool {
+	return flags&uint32(metricspb.DataPointFlags_DATA_POINT_FLAGS_NO_RECORDED_VALUE_MASK) != 0
+}
+
+func numberValue(dp *metricspb.NumberDataPoint) float64 {
+	if v, ok := dp.GetValue().(*metricspb.NumberDataPoint_AsInt); ok {
+		return float64(v.AsInt)
+	}
+	return dp.GetAsDouble()
+}
+
+func countPoints(m *metricspb.Metric) int {
+	switch data := m.GetData().(type) {
+	case *metricspb.Metric_Gauge:
+		return len(data.Gauge.GetDataPoints())
+	case *metricspb.Metric_Sum:
+		return len(data.Sum.GetDataPoints())
+	case *metricspb.Metric_Histogram:
+		return len(data.Histogram.GetDataPoints())
+	case *metricspb.Metric_ExponentialHistogram:
+		return len(data.ExponentialHistogram.GetDataPoints())
+	case *metricspb.Metric_Summary:
+		return len(data.Summary.GetDataPoints())
+	}
+	return 0
+}
+
+// labels returns the attributes as labels, attributes with array and map values are skipped.
+func labels(attributes []*commonpb.KeyValue, service string) metrics.Labels {
+	if len(attributes) == 0 && service == "" {
+		return nil
+	}
+
+	result := make(metrics.Labels, len(attributes)+1)
+	for _, kv := range attributes {
+		value := kv.GetValue()
+		switch value.GetValue().(type) {
+		case *commonpb.AnyValue_ArrayValue, *commonpb.AnyValue_KvlistValue, nil:
+			continue
+		}
+		result[LabelName(kv.GetKey())] = attributeValue(value)
+	}
+	if _, ok := result[serviceNameLabel]; !ok && service != "" {
+		result[serviceNameLabel] = service
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// resourceKey returns the attributes of a resource encoded in the order of keys.
+func resourceKey(attributes []*commonpb.KeyValue) string {
+	pairs := make([]string, 0, len(attributes))
+	for _, kv := range attributes {
+		pairs = append(pairs, strconv.Quote(kv.GetKey())+"="+strconv.Quote(attributeValue(kv.GetValue())))
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func findAttribute(attributes []*commonpb.KeyValue, key string) *commonpb.AnyValue {
+	for _, kv := range attributes {
+		if kv.GetKey() == key {
+			return kv.GetValue()
+		}
+	}
+	return nil
+}
+
+func attributeValue(value *commonpb.AnyValue) string {
+	switch v := value.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'g', -1, 64)
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	}
+	return ""
+}
+
+// LabelName returns the attribute key as a valid label name,
+// e.g. `http.method` becomes `http_method`.
+func LabelName(key string) string {
+	var sb strings.Builder
+	for i, c := range key {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9':
+			if i == 0 {
+				sb.WriteByte('_')
+			}
+		default:
+			c = '_'
+		}
+		sb.WriteRune(c)
+	}
+	if sb.Len() == 0 {
+		return "_"
+	}
+	return sb.String()
+}
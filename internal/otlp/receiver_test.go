@@ -0,0 +1,333 @@
+package otlp_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/otlp"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	colmetricspb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+const (
+	delta      = metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA
+	cumulative = metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE
+)
+
+func request(ms ...*metricspb.Metric) *colmetricspb.ExportMetricsServiceRequest {
+	return &colmetricspb.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{{
+			ScopeMetrics: []*metricspb.ScopeMetrics{{Metrics: ms}},
+		}},
+	}
+}
+
+func sum(name string, temporality metricspb.AggregationTemporality, monotonic bool, start uint64, value float64) *metricspb.Metric {
+	return &metricspb.Metric{
+		Name: name,
+		Data: &metricspb.Metric_Sum{Sum: &metricspb.Sum{
+			AggregationTemporality: temporality,
+			IsMonotonic:            monotonic,
+			DataPoints: []*metricspb.NumberDataPoint{{
+				StartTimeUnixNano: start,
+				Value:             &metricspb.NumberDataPoint_AsDouble{AsDouble: value},
+			}},
+		}},
+	}
+}
+
+func histogram(name string, temporality metricspb.AggregationTemporality, start uint64, counts []uint64, total float64) *metricspb.Metric {
+	var count uint64
+	for _, c := range counts {
+		count += c
+	}
+	return &metricspb.Metric{
+		Name: name,
+		Data: &metricspb.Metric_Histogram{Histogram: &metricspb.Histogram{
+			AggregationTemporality: temporality,
+			DataPoints: []*metricspb.HistogramDataPoint{{
+				StartTimeUnixNano: start,
+				ExplicitBounds:    []float64{1, 10},
+				BucketCounts:      counts,
+				Count:             count,
+				Sum:               &total,
+			}},
+		}},
+	}
+}
+
+func stored(t *testing.T, store *memory.MemStorage, m metrics.Metrics) metrics.Metrics {
+	t.Helper()
+	require.NoError(t, store.Get(context.Background(), &m))
+	return m
+}
+
+// withResource sets the resource attributes of the request.
+func withResource(req *colmetricspb.ExportMetricsServiceRequest, attributes map[string]string) *colmetricspb.ExportMetricsServiceRequest {
+	res := &resourcepb.Resource{}
+	for key, value := range attributes {
+		res.Attributes = append(res.Attributes, &commonpb.KeyValue{
+			Key:   key,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+		})
+	}
+	req.ResourceMetrics[0].Resource = res
+	return req
+}
+
+// startedNow returns a stream start time after the receiver was created.
+func startedNow() uint64 {
+	return uint64(time.Now().UnixNano()) + 1
+}
+
+func TestReceiverCumulativeSum(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemStorage()
+	receiver := otlp.NewReceiver(store)
+	start := startedNow()
+
+	for _, point := range []struct {
+		start uint64
+		value float64
+		want  int64
+	}{
+		// the stream started after the receiver, so it is counted from zero.
+		{start: start, value: 10, want: 10},
+		{start: start, value: 15, want: 15},
+		{start: start, value: 15, want: 15},
+		// a smaller value is a reset.
+		{start: start, value: 4, want: 19},
+		// another start time is a reset.
+		{start: start + 1, value: 6, want: 25},
+	} {
+		rejected, _, err := receiver.Export(ctx, request(sum("requests", cumulative, true, point.start, point.value)))
+		require.NoError(t, err)
+		assert.Zero(t, rejected)
+
+		m := stored(t, store, metrics.Metrics{ID: "requests", MType: metrics.Counter})
+		assert.Equal(t, point.want, *m.Delta)
+	}
+}
+
+func TestReceiverCumulativeBaseline(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemStorage()
+	receiver := otlp.NewReceiver(store)
+
+	// the streams started before the receiver, their first points are baselines.
+	_, _, err := receiver.Export(ctx, withResource(request(sum("requests", cumulative, true, 1, 100)), map[string]string{"host.name": "a"}))
+	require.NoError(t, err)
+	_, _, err = receiver.Export(ctx, withResource(request(sum("requests", cumulative, true, 1, 50)), map[string]string{"host.name": "b"}))
+	require.NoError(t, err)
+	_, _, err = receiver.Export(ctx, withResource(request(histogram("latency", cumulative, 1, []uint64{1, 2, 0}, 10)), map[string]string{"host.name": "a"}))
+	require.NoError(t, err)
+
+	m := metrics.Metrics{ID: "requests", MType: metrics.Counter}
+	assert.Error(t, store.Get(ctx, &m))
+	m = metrics.Metrics{ID: "latency", MType: metrics.Histogram}
+	assert.Error(t, store.Get(ctx, &m))
+
+	// the same series of different resources are separate streams.
+	_, _, err = receiver.Export(ctx, withResource(request(sum("requests", cumulative, true, 1, 110)), map[string]string{"host.name": "a"}))
+	require.NoError(t, err)
+	_, _, err = receiver.Export(ctx, withResource(request(sum("requests", cumulative, true, 1, 55)), map[string]string{"host.name": "b"}))
+	require.NoError(t, err)
+	_, _, err = receiver.Export(ctx, withResource(request(histogram("latency", cumulative, 1, []uint64{1, 3, 0}, 12)), map[string]string{"host.name": "a"}))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(15), *stored(t, store, metrics.Metrics{ID: "requests", MType: metrics.Counter}).Delta)
+	assert.Equal(t, []uint64{0, 1, 0}, stored(t, store, metrics.Metrics{ID: "latency", MType: metrics.Histogram}).Histogram.Counts)
+}
+
+func TestReceiverDeltaSum(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemStorage()
+	receiver := otlp.NewReceiver(store)
+
+	// fractional deltas are carried to the next point.
+	for _, value := range []float64{0.6, 0.6, 0.6} {
+		_, _, err := receiver.Export(ctx, request(sum("bytes", delta, true, 0, value)))
+		require.NoError(t, err)
+	}
+	m := stored(t, store, metrics.Metrics{ID: "bytes", MType: metrics.Counter})
+	assert.Equal(t, int64(2), *m.Delta)
+
+	rejected, reason, err := receiver.Export(ctx, request(sum("bytes", delta, true, 0, -1)))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), rejected)
+	assert.Contains(t, reason, "negative")
+}
+
+func TestReceiverNonMonotonicSum(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemStorage()
+	receiver := otlp.NewReceiver(store)
+
+	_, _, err := receiver.Export(ctx, request(
+		sum("queue", cumulative, false, 1, 7),
+		sum("inflight", delta, false, 0, 3),
+		sum("inflight", delta, false, 0, -1),
+	))
+	require.NoError(t, err)
+	_, _, err = receiver.Export(ctx, request(sum("inflight", delta, false, 0, 5)))
+	require.NoError(t, err)
+
+	assert.Equal(t, 7.0, *stored(t, store, metrics.Metrics{ID: "queue", MType: metrics.Gauge}).Value)
+	assert.Equal(t, 7.0, *stored(t, store, metrics.Metrics{ID: "inflight", MType: metrics.Gauge}).Value)
+}
+
+func TestReceiverGauge(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemStorage()
+	receiver := otlp.NewReceiver(store)
+
+	req := request(&metricspb.Metric{
+		Name: "cpu.usage",
+		Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+			DataPoints: []*metricspb.NumberDataPoint{{
+				Attributes: []*commonpb.KeyValue{
+					{Key: "host.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "web01"}}},
+					{Key: "0core", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 2}}},
+				},
+				Value: &metricspb.NumberDataPoint_AsInt{AsInt: 42},
+			}},
+		}},
+	})
+	req.ResourceMetrics[0].Resource = &resourcepb.Resource{Attributes: []*commonpb.KeyValue{
+		{Key: "service.name", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: "api"}}},
+	}}
+
+	_, _, err := receiver.Export(ctx, req)
+	require.NoError(t, err)
+
+	m := stored(t, store, metrics.Metrics{
+		ID:     "cpu.usage",
+		MType:  metrics.Gauge,
+		Labels: metrics.Labels{"host_name": "web01", "_0core": "2", "service_name": "api"},
+	})
+	assert.Equal(t, 42.0, *m.Value)
+}
+
+func TestReceiverGaugeRejectsNonFinite(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemStorage()
+	receiver := otlp.NewReceiver(store)
+
+	rejected, reason, err := receiver.Export(ctx, request(&metricspb.Metric{
+		Name: "temperature",
+		Data: &metricspb.Metric_Gauge{Gauge: &metricspb.Gauge{
+			DataPoints: []*metricspb.NumberDataPoint{
+				{Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: math.NaN()}},
+				{Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: math.Inf(1)}},
+				{Value: &metricspb.NumberDataPoint_AsDouble{AsDouble: 21.5}},
+			},
+		}},
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), rejected)
+	assert.Contains(t, reason, "temperature")
+
+	assert.Equal(t, 21.5, *stored(t, store, metrics.Metrics{ID: "temperature", MType: metrics.Gauge}).Value)
+}
+
+func TestReceiverCumulativeHistogram(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemStorage()
+	receiver := otlp.NewReceiver(store)
+	start := startedNow()
+
+	_, _, err := receiver.Export(ctx, request(histogram("latency", cumulative, start, []uint64{1, 2, 0}, 10)))
+	require.NoError(t, err)
+	_, _, err = receiver.Export(ctx, request(histogram("latency", cumulative, start, []uint64{2, 2, 1}, 30)))
+	require.NoError(t, err)
+
+	m := stored(t, store, metrics.Metrics{ID: "latency", MType: metrics.Histogram})
+	assert.Equal(t, []uint64{2, 2, 1}, m.Histogram.Counts)
+	assert.Equal(t, uint64(5), m.Histogram.Count)
+	assert.Equal(t, 30.0, m.Histogram.Sum)
+
+	// delta histograms are merged as they are.
+	_, _, err = receiver.Export(ctx, request(histogram("latency", delta, 0, []uint64{0, 1, 0}, 5)))
+	require.NoError(t, err)
+
+	m = stored(t, store, metrics.Metrics{ID: "latency", MType: metrics.Histogram})
+	assert.Equal(t, []uint64{2, 3, 1}, m.Histogram.Counts)
+}
+
+func TestReceiverRejectsUnsupported(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemStorage()
+	receiver := otlp.NewReceiver(store)
+
+	rejected, reason, err := receiver.Export(ctx, request(
+		&metricspb.Metric{
+			Name: "sizes",
+			Data: &metricspb.Metric_ExponentialHistogram{ExponentialHistogram: &metricspb.ExponentialHistogram{
+				DataPoints: []*metricspb.ExponentialHistogramDataPoint{{}, {}},
+			}},
+		},
+		sum("unspecified", metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_UNSPECIFIED, true, 0, 1),
+		sum("ok", delta, true, 0, 1),
+	))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), rejected)
+	assert.Contains(t, reason, "sizes")
+
+	assert.Equal(t, int64(1), *stored(t, store, metrics.Metrics{ID: "ok", MType: metrics.Counter}).Delta)
+}
+
+// failingStorage fails writes while fail is set.
+type failingStorage struct {
+	*memory.MemStorage
+	fail bool
+}
+
+func (s *failingStorage) BulkAdd(ctx context.Context, ms []metrics.Metrics) error {
+	if s.fail {
+		return errors.New("storage unavailable")
+	}
+	return s.MemStorage.BulkAdd(ctx, ms)
+}
+
+func TestReceiverKeepsStateOnStorageError(t *testing.T) {
+	ctx := context.Background()
+	store := &failingStorage{MemStorage: memory.NewMemStorage()}
+	receiver := otlp.NewReceiver(store)
+	start := startedNow()
+
+	_, _, err := receiver.Export(ctx, request(sum("requests", cumulative, true, start, 10)))
+	require.NoError(t, err)
+
+	store.fail = true
+	_, _, err = receiver.Export(ctx, request(sum("requests", cumulative, true, start, 15)))
+	require.Error(t, err)
+
+	// the retried request gives the same increase.
+	store.fail = false
+	_, _, err = receiver.Export(ctx, request(sum("requests", cumulative, true, start, 15)))
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(15), *stored(t, store.MemStorage, metrics.Metrics{ID: "requests", MType: metrics.Counter}).Delta)
+}
+
+func TestLabelName(t *testing.T) {
+	tests := map[string]string{
+		"http.method":  "http_method",
+		"k8s-pod_name": "k8s_pod_name",
+		"1st":          "_1st",
+		"":             "_",
+		"ok_Name9":     "ok_Name9",
+	}
+	for key, want := range tests {
+		assert.Equal(t, want, otlp.LabelName(key), key)
+	}
+}
@@ -0,0 +1,86 @@
+package pb
+
+import (
+	"fmt"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+)
+
+var metricTypes = map[Metric_MType]metrics.MetricType{
+	Metric_GAUGE:     metrics.Gauge,
+	Metric_COUNTER:   metrics.Counter,
+	Metric_HISTOGRAM: metrics.Histogram,
+}
+
+// FromMetricType converts the metric type to the protobuf enum.
+func FromMetricType(mType metrics.MetricType) Metric_MType {
+	for pbType, t := range metricTypes {
+		if t == mType {
+			return pbType
+		}
+	}
+	return Metric_UNSPECIFIED
+}
+
+// ToMetricType converts the protobuf enum to the metric type.
+func ToMetricType(pbType Metric_MType) (metrics.MetricType, error) {
+	mType, ok := metricTypes[pbType]
+	if !ok {
+		return "", fmt.Errorf("invalid metric type: %s", pbType)
+	}
+	return mType, nil
+}
+
+// FromMetric converts the metric to the protobuf message.
+func FromMetric(m metrics.Metrics) *Metric {
+	metric := &Metric{
+		Id:     m.ID,
+		Type:   FromMetricType(m.MType),
+		Delta:  m.Delta,
+		Value:  m.Value,
+		Labels: m.Labels,
+	}
+	if m.Histogram != nil {
+		metric.Histogram = &Histogram{
+			Bounds: m.Histogram.Bounds,
+			Counts: m.Histogram.Counts,
+			Sum:    m.Histogram.Sum,
+			Count:  m.Histogram.Count,
+		}
+	}
+	return metric
+}
+
+// ToMetric converts the protobuf message to the metric.
+func ToMetric(metric *Metric) (metrics.Metrics, error) {
+	mType, err := ToMetricType(metric.GetType())
+	if err != nil {
+		return metrics.Metrics{}, err
+	}
+
+	m := metrics.Metrics{
+		ID:     metric.GetId(),
+		MType:  mType,
+		Delta:  metric.Delta,
+		Value:  metric.Value,
+		Labels: metric.GetLabels(),
+	}
+	if h := metric.GetHistogram(); h != nil {
+		m.Histogram = &metrics.HistogramValue{
+			Bounds: h.GetBounds(),
+			Counts: h.GetCounts(),
+			Sum:    h.GetSum(),
+			Count:  h.GetCount(),
+		}
+	}
+	return m, nil
+}
+
+// FromMetrics converts the list of metrics to protobuf messages.
+func FromMetrics(list []metrics.Metrics) []*Metric {
+	out := make([]*Metric, 0, len(list))
+	for _, m := range list {
+		out = append(out, FromMetric(m))
+	}
+	return out
+}
@@ -0,0 +1,54 @@
+package pb
+
+import (
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricConversion(t *testing.T) {
+	value := 1.5
+	delta := int64(3)
+	histogram := metrics.NewHistogramValue([]float64{1, 2})
+	histogram.Observe(1.5)
+
+	tests := []metrics.Metrics{
+		{ID: "gauge", MType: metrics.Gauge, Value: &value},
+		{ID: "counter", MType: metrics.Counter, Delta: &delta, Labels: metrics.Labels{"host": "a"}},
+		{ID: "histogram", MType: metrics.Histogram, Histogram: histogram},
+	}
+
+	for _, m := range tests {
+		t.Run(m.ID, func(t *testing.T) {
+			converted, err := ToMetric(FromMetric(m))
+			require.NoError(t, err)
+			assert.Equal(t, m, converted)
+		})
+	}
+}
+
+func TestToMetricInvalidType(t *testing.T) {
+	_, err := ToMetric(&Metric{Id: "metric"})
+	assert.Error(t, err)
+}
+
+func TestHashSumIsDeterministic(t *testing.T) {
+	req := &UpdateMetricsRequest{Metrics: FromMetrics([]metrics.Metrics{
+		{ID: "counter", MType: metrics.Counter, Delta: new(int64), Labels: metrics.Labels{"a": "1", "b": "2", "c": "3"}},
+	})}
+
+	first, err := HashSum(req, "key")
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		hash, err := HashSum(req, "key")
+		require.NoError(t, err)
+		assert.Equal(t, first, hash)
+	}
+
+	other, err := HashSum(req, "other")
+	require.NoError(t, err)
+	assert.NotEqual(t, first, other)
+}
@@ -0,0 +1,5 @@
+// Package pb contains the gRPC API of the metric server generated from metrics.proto
+// and conversions between protobuf messages and metrics.Metrics.
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative metrics.proto
@@ -0,0 +1,29 @@
+package pb
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// HashMetadataKey the metadata key with the hash of a request, like the HashSHA256 HTTP header.
+const HashMetadataKey = "hashsha256"
+
+// RealIPMetadataKey the metadata key with the agent address, like the X-Real-IP HTTP header.
+const RealIPMetadataKey = "x-real-ip"
+
+// HashSum returns the hex SHA256 of the deterministically marshaled message followed by the key.
+func HashSum(msg proto.Message, hashKey string) (string, error) {
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+
+	h.Write(data)
+	h.Write([]byte(hashKey))
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
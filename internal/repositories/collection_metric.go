@@ -8,8 +8,14 @@ import (
 
 //go:generate minimock -i github.com/screamsoul/go-metrics-tpl/internal/repositories.CollectionMetric -o ./mocks/collection_metric_mock.go -g
 type CollectionMetric interface {
-	Update()
-	UpdateRuntime()
-	UpdateGopsutil()
+	// BulkAdd stores metrics of a collector: gauges replace values, counters are incremented.
+	BulkAdd(ctx context.Context, m []metrics.Metrics) error
 	List(ctx context.Context) ([]metrics.Metrics, error)
+
+	// Reserve returns the batch to send with counters as deltas since the last reservation.
+	Reserve(ctx context.Context) ([]metrics.Metrics, error)
+	// Commit acknowledges the counter deltas of the sent batch.
+	Commit(batch []metrics.Metrics)
+	// Rollback returns the counter deltas of the unsent batch to the next reservation.
+	Rollback(batch []metrics.Metrics)
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
@@ -16,10 +18,14 @@ import (
 type FileRestoreMetricWrapper struct {
 	ms              repositories.MetricStorage
 	restoreFile     string
-	restoreInterval int
+	restoreInterval atomic.Int64
 	restoreInit     bool
 	IsActiveRestore bool
 	logger          *zap.Logger
+	saveLock        sync.Mutex
+	stop            context.CancelFunc
+	reschedule      chan struct{}
+	wg              sync.WaitGroup
 }
 
 func NewFileRestoreMetricWrapper(
@@ -33,41 +39,110 @@ func NewFileRestoreMetricWrapper(
 	restoreMetric := &FileRestoreMetricWrapper{
 		ms:              ms,
 		restoreFile:     restoreFile,
-		restoreInterval: restoreInterval,
 		restoreInit:     restoreInit,
 		IsActiveRestore: restoreFile != "",
 		logger:          logging.GetLogger(),
+		reschedule:      make(chan struct{}, 1),
 	}
+	restoreMetric.restoreInterval.Store(int64(restoreInterval))
 
 	if restoreMetric.IsActiveRestore && restoreMetric.restoreInit {
 		restoreMetric.Load(ctx)
 	}
 
-	if restoreMetric.IsActiveRestore && restoreMetric.restoreInterval > 0 {
-		go func(context.Context) {
-			ticker := time.NewTicker(time.Duration(restoreMetric.restoreInterval) * time.Second)
-			defer ticker.Stop()
+	tickerCtx, stop := context.WithCancel(ctx)
+	restoreMetric.stop = stop
 
-			for range ticker.C {
-				restoreMetric.Save(ctx)
-			}
-		}(ctx)
+	if restoreMetric.IsActiveRestore {
+		restoreMetric.wg.Add(1)
+		go restoreMetric.saveLoop(tickerCtx)
 	}
 
 	return restoreMetric
 }
 
+// saveLoop saves the snapshot every restoreInterval seconds,
+// the ticker is recreated when the interval is changed.
+func (wrapper *FileRestoreMetricWrapper) saveLoop(ctx context.Context) {
+	defer wrapper.wg.Done()
+
+	var (
+		ticker *time.Ticker
+		tick   <-chan time.Time
+	)
+
+	resetTicker := func() {
+		if ticker != nil {
+			ticker.Stop()
+			ticker, tick = nil, nil
+		}
+		// with zero interval the snapshot is saved synchronously on every update.
+		if interval := wrapper.StoreInterval(); interval > 0 {
+			ticker = time.NewTicker(time.Duration(interval) * time.Second)
+			tick = ticker.C
+		}
+	}
+
+	resetTicker()
+	defer func() {
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-wrapper.reschedule:
+			resetTicker()
+		case <-tick:
+			wrapper.Save(ctx)
+		}
+	}
+}
+
+// StoreInterval returns the current snapshot interval in seconds.
+func (wrapper *FileRestoreMetricWrapper) StoreInterval() int {
+	return int(wrapper.restoreInterval.Load())
+}
+
+// SetStoreInterval changes the snapshot interval and reschedules the snapshot ticker.
+func (wrapper *FileRestoreMetricWrapper) SetStoreInterval(restoreInterval int) {
+	if wrapper.restoreInterval.Swap(int64(restoreInterval)) == int64(restoreInterval) {
+		return
+	}
+
+	select {
+	case wrapper.reschedule <- struct{}{}:
+	default:
+	}
+}
+
+// Shutdown stops the periodic snapshot and writes the final snapshot to the file.
+func (wrapper *FileRestoreMetricWrapper) Shutdown(ctx context.Context) {
+	wrapper.stop()
+	wrapper.wg.Wait()
+
+	if wrapper.IsActiveRestore {
+		wrapper.Save(ctx)
+	}
+}
+
 func (wrapper *FileRestoreMetricWrapper) Save(ctx context.Context) {
+	wrapper.saveLock.Lock()
+	defer wrapper.saveLock.Unlock()
+
 	wrapper.logger.Info("save metric to file")
 
-	file, err := os.OpenFile(wrapper.restoreFile, os.O_WRONLY|os.O_CREATE, 0666)
+	file, err := os.OpenFile(wrapper.restoreFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0666)
 	if err != nil {
 		wrapper.logger.Error("error open or create file for write", zap.Error(err))
 		return
 	}
 	defer utils.CloseForse(file)
 
-	metricsList, err := wrapper.ms.List(ctx)
+	metricsList, err := wrapper.ms.List(ctx, repositories.ListQuery{})
 	if err != nil {
 		wrapper.logger.Error("error read metric", zap.Error(err))
 		return
@@ -99,7 +174,8 @@ func (wrapper *FileRestoreMetricWrapper) Load(ctx context.Context) {
 		wrapper.logger.Error("error loading metrics from file", zap.Error(decodeErr))
 		return
 	}
-	err = wrapper.ms.BulkAdd(ctx, metrics)
+	// restored values are current values, not new samples of the history.
+	err = repositories.BulkAddCurrent(ctx, wrapper.ms, metrics)
 	if err != nil {
 		wrapper.logger.Error("error append metric to storage from file", zap.Error(err))
 		return
@@ -110,14 +186,14 @@ func (wrapper *FileRestoreMetricWrapper) Get(ctx context.Context, metric *metric
 	return wrapper.ms.Get(ctx, metric)
 }
 
-func (wrapper *FileRestoreMetricWrapper) List(ctx context.Context) ([]metrics.Metrics, error) {
-	return wrapper.ms.List(ctx)
+func (wrapper *FileRestoreMetricWrapper) List(ctx context.Context, query repositories.ListQuery) ([]metrics.Metrics, error) {
+	return wrapper.ms.List(ctx, query)
 }
 
 func (wrapper *FileRestoreMetricWrapper) Add(ctx context.Context, m metrics.Metrics) error {
 	err := wrapper.ms.Add(ctx, m)
 
-	if err != nil && wrapper.IsActiveRestore && wrapper.restoreInterval == 0 {
+	if err == nil && wrapper.IsActiveRestore && wrapper.StoreInterval() == 0 {
 		wrapper.Save(ctx)
 	}
 
@@ -129,5 +205,68 @@ func (wrapper *FileRestoreMetricWrapper) Ping(ctx context.Context) bool {
 }
 
 func (wrapper *FileRestoreMetricWrapper) BulkAdd(ctx context.Context, metricList []metrics.Metrics) error {
-	return wrapper.ms.BulkAdd(ctx, metricList)
+	err := wrapper.ms.BulkAdd(ctx, metricList)
+
+	if err == nil && wrapper.IsActiveRestore && wrapper.StoreInterval() == 0 {
+		wrapper.Save(ctx)
+	}
+
+	return err
+}
+
+// BulkAddCurrent writes the metrics without recording them in the history of the wrapped storage.
+func (wrapper *FileRestoreMetricWrapper) BulkAddCurrent(ctx context.Context, metricList []metrics.Metrics) error {
+	err := repositories.BulkAddCurrent(ctx, wrapper.ms, metricList)
+
+	if err == nil && wrapper.IsActiveRestore && wrapper.StoreInterval() == 0 {
+		wrapper.Save(ctx)
+	}
+
+	return err
+}
+
+// History returns the metric history if the wrapped storage keeps it.
+func (wrapper *FileRestoreMetricWrapper) History(ctx context.Context, query repositories.HistoryQuery) ([]metrics.Point, error) {
+	if hs, ok := wrapper.ms.(repositories.HistoryStorage); ok {
+		return hs.History(ctx, query)
+	}
+	return nil, repositories.ErrHistoryNotSupported
+}
+
+// HistoryBatch returns the history of several series if the wrapped storage keeps it.
+func (wrapper *FileRestoreMetricWrapper) HistoryBatch(ctx context.Context, query repositories.HistoryBatchQuery) ([][]metrics.Point, error) {
+	if hs, ok := wrapper.ms.(repositories.HistoryStorage); ok {
+		return repositories.HistoryBatch(ctx, hs, query)
+	}
+	return nil, repositories.ErrHistoryNotSupported
+}
+
+func (wrapper *FileRestoreMetricWrapper) Delete(ctx context.Context, m metrics.Metrics) error {
+	err := wrapper.ms.Delete(ctx, m)
+
+	if err == nil && wrapper.IsActiveRestore && wrapper.StoreInterval() == 0 {
+		wrapper.Save(ctx)
+	}
+
+	return err
+}
+
+func (wrapper *FileRestoreMetricWrapper) DeleteByPrefix(ctx context.Context, prefix string) (int64, error) {
+	deleted, err := wrapper.ms.DeleteByPrefix(ctx, prefix)
+
+	if err == nil && deleted > 0 && wrapper.IsActiveRestore && wrapper.StoreInterval() == 0 {
+		wrapper.Save(ctx)
+	}
+
+	return deleted, err
+}
+
+func (wrapper *FileRestoreMetricWrapper) Reset(ctx context.Context, m metrics.Metrics) error {
+	err := wrapper.ms.Reset(ctx, m)
+
+	if err == nil && wrapper.IsActiveRestore && wrapper.StoreInterval() == 0 {
+		wrapper.Save(ctx)
+	}
+
+	return err
 }
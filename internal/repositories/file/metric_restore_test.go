@@ -4,12 +4,16 @@ import (
 	"context"
 	"encoding/json"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/gojuno/minimock/v3"
 	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
 	"github.com/screamsoul/go-metrics-tpl/internal/repositories/file"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories/memory"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -35,7 +39,10 @@ func TestFileRestoreMetricWrapper_Save_Success(t *testing.T) {
 		ctx, mockMetricService, fileTemp.Name(), 1, false,
 	)
 
-	metricsList := []metrics.Metrics{{ID: "test_metric", MType: metrics.Gauge, Value: new(float64)}}
+	metricsList := []metrics.Metrics{
+		{ID: "test_metric", MType: metrics.Gauge, Value: new(float64)},
+		{ID: "test_metric", MType: metrics.Gauge, Value: new(float64), Labels: metrics.Labels{"host": "a"}},
+	}
 
 	mockMetricService.ListMock.Return(metricsList, nil)
 
@@ -87,6 +94,35 @@ func TestFileRestoreMetricWrapper_Load_Success(t *testing.T) {
 	wrapper.Load(ctx)
 }
 
+// Loaded values are restored as current values and do not add history samples
+func TestLoadDoesNotRecordHistory(t *testing.T) {
+	ctx := context.Background()
+
+	restoreFile := filepath.Join(t.TempDir(), "metrics.json")
+	value := 1.5
+	metricsData := []metrics.Metrics{{ID: "test_metric", MType: metrics.Gauge, Value: &value}}
+	data, err := json.Marshal(metricsData)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(restoreFile, data, 0666))
+
+	storage := memory.NewHistoryMemStorage()
+	wrapper := file.NewFileRestoreMetricWrapper(ctx, storage, restoreFile, 1, false)
+
+	wrapper.Load(ctx)
+
+	m := metrics.Metrics{ID: "test_metric", MType: metrics.Gauge}
+	require.NoError(t, storage.Get(ctx, &m))
+	assert.Equal(t, value, *m.Value)
+
+	points, err := storage.History(ctx, repositories.HistoryQuery{
+		Metric: m,
+		From:   time.Time{},
+		To:     time.Now().Add(time.Hour),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, points)
+}
+
 func TestGetMetricSuccessfully(t *testing.T) {
 	ctrl := minimock.NewController(t)
 
@@ -120,9 +156,132 @@ func TestListMetricSuccessfully(t *testing.T) {
 
 	metricsList := []metrics.Metrics{}
 
-	mockMetricService.ListMock.Expect(ctx).Return(metricsList, nil)
+	mockMetricService.ListMock.Expect(ctx, repositories.ListQuery{}).Return(metricsList, nil)
 
-	_, err := wrapper.List(ctx)
+	_, err := wrapper.List(ctx, repositories.ListQuery{})
 
 	assert.NoError(t, err)
 }
+
+func TestHistoryNotSupported(t *testing.T) {
+	ctrl := minimock.NewController(t)
+
+	mockMetricService := NewMetricStorageMock(ctrl)
+
+	ctx := context.Background()
+
+	wrapper := file.NewFileRestoreMetricWrapper(
+		ctx, mockMetricService, "", 0, false,
+	)
+
+	_, err := wrapper.History(ctx, repositories.HistoryQuery{})
+
+	assert.ErrorIs(t, err, repositories.ErrHistoryNotSupported)
+}
+
+func TestShutdownWritesFinalSnapshot(t *testing.T) {
+	ctrl := minimock.NewController(t)
+
+	mockMetricService := NewMetricStorageMock(ctrl)
+
+	ctx := context.Background()
+
+	restoreFile := filepath.Join(t.TempDir(), "metrics.json")
+
+	wrapper := file.NewFileRestoreMetricWrapper(
+		ctx, mockMetricService, restoreFile, 300, false,
+	)
+
+	metricsList := []metrics.Metrics{{ID: "test_metric", MType: metrics.Gauge, Value: new(float64)}}
+	mockMetricService.ListMock.Return(metricsList, nil)
+
+	wrapper.Shutdown(ctx)
+
+	fileContent, err := os.ReadFile(restoreFile)
+	require.NoError(t, err)
+
+	var savedMetrics []metrics.Metrics
+	require.NoError(t, json.Unmarshal(fileContent, &savedMetrics))
+	assert.Equal(t, metricsList, savedMetrics)
+}
+
+func TestAddSavesSynchronously(t *testing.T) {
+	ctrl := minimock.NewController(t)
+
+	mockMetricService := NewMetricStorageMock(ctrl)
+
+	ctx := context.Background()
+
+	restoreFile := filepath.Join(t.TempDir(), "metrics.json")
+
+	wrapper := file.NewFileRestoreMetricWrapper(
+		ctx, mockMetricService, restoreFile, 0, false,
+	)
+
+	metric := metrics.Metrics{ID: "test_metric", MType: metrics.Gauge, Value: new(float64)}
+	mockMetricService.AddMock.Expect(ctx, metric).Return(nil)
+	mockMetricService.ListMock.Return([]metrics.Metrics{metric}, nil)
+
+	require.NoError(t, wrapper.Add(ctx, metric))
+
+	_, err := os.Stat(restoreFile)
+	assert.NoError(t, err)
+}
+
+func TestDeleteSavesSynchronously(t *testing.T) {
+	ctrl := minimock.NewController(t)
+
+	mockMetricService := NewMetricStorageMock(ctrl)
+
+	ctx := context.Background()
+
+	restoreFile := filepath.Join(t.TempDir(), "metrics.json")
+
+	wrapper := file.NewFileRestoreMetricWrapper(
+		ctx, mockMetricService, restoreFile, 0, false,
+	)
+
+	metric := metrics.Metrics{ID: "test_metric", MType: metrics.Counter}
+	mockMetricService.DeleteMock.Expect(ctx, metric).Return(nil)
+	mockMetricService.ResetMock.Expect(ctx, metric).Return(repositories.ErrMetricNotFound)
+	mockMetricService.DeleteByPrefixMock.Expect(ctx, "test_").Return(0, nil)
+	mockMetricService.ListMock.Return([]metrics.Metrics{}, nil)
+
+	require.NoError(t, wrapper.Delete(ctx, metric))
+
+	data, err := os.ReadFile(restoreFile)
+	require.NoError(t, err)
+	assert.JSONEq(t, "[]", string(data))
+
+	assert.ErrorIs(t, wrapper.Reset(ctx, metric), repositories.ErrMetricNotFound)
+
+	deleted, err := wrapper.DeleteByPrefix(ctx, "test_")
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
+
+func TestSetStoreIntervalReschedulesSnapshot(t *testing.T) {
+	ctrl := minimock.NewController(t)
+
+	mockMetricService := NewMetricStorageMock(ctrl)
+
+	ctx := context.Background()
+
+	restoreFile := filepath.Join(t.TempDir(), "metrics.json")
+
+	wrapper := file.NewFileRestoreMetricWrapper(
+		ctx, mockMetricService, restoreFile, 300, false,
+	)
+
+	mockMetricService.ListMock.Return([]metrics.Metrics{}, nil)
+
+	wrapper.SetStoreInterval(1)
+	assert.Equal(t, 1, wrapper.StoreInterval())
+
+	assert.Eventually(t, func() bool {
+		_, err := os.Stat(restoreFile)
+		return err == nil
+	}, 3*time.Second, 50*time.Millisecond)
+
+	wrapper.Shutdown(ctx)
+}
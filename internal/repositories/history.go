@@ -0,0 +1,100 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+)
+
+// ErrHistoryNotSupported is returned when the repository does not keep the history of values.
+var ErrHistoryNotSupported = errors.New("metric history is not supported by the storage")
+
+// HistoryQuery describes the requested range of a metric series history.
+//
+// With a zero Step raw samples are returned, otherwise samples are grouped into Step-long buckets:
+// the last value is taken for gauges and the sum of deltas for counters.
+type HistoryQuery struct {
+	Metric metrics.Metrics
+	From   time.Time
+	To     time.Time
+	Step   time.Duration
+}
+
+// HistoryStorage is an interface for a repository keeping the history of metric values.
+type HistoryStorage interface {
+	History(ctx context.Context, query HistoryQuery) ([]metrics.Point, error)
+}
+
+// HistoryBatchQuery describes the requested range of the history of several metric series,
+// the range and the step are applied like in HistoryQuery.
+type HistoryBatchQuery struct {
+	Metrics []metrics.Metrics
+	From    time.Time
+	To      time.Time
+	Step    time.Duration
+}
+
+// BatchHistoryStorage is an interface for a history repository reading several series in one request.
+type BatchHistoryStorage interface {
+	// HistoryBatch returns the points of the series in the order of query.Metrics.
+	HistoryBatch(ctx context.Context, query HistoryBatchQuery) ([][]metrics.Point, error)
+}
+
+// HistoryBatch returns the points of the series in the order of query.Metrics,
+// in one request if the storage supports it, otherwise series by series.
+func HistoryBatch(ctx context.Context, hs HistoryStorage, query HistoryBatchQuery) ([][]metrics.Point, error) {
+	if bs, ok := hs.(BatchHistoryStorage); ok {
+		return bs.HistoryBatch(ctx, query)
+	}
+
+	result := make([][]metrics.Point, len(query.Metrics))
+	for i, m := range query.Metrics {
+		points, err := hs.History(ctx, HistoryQuery{Metric: m, From: query.From, To: query.To, Step: query.Step})
+		if err != nil {
+			return nil, err
+		}
+		result[i] = points
+	}
+	return result, nil
+}
+
+// CurrentValueStorage is an interface for a history repository which can write
+// current values without recording them in the history.
+type CurrentValueStorage interface {
+	BulkAddCurrent(ctx context.Context, metricList []metrics.Metrics) error
+}
+
+// BulkAddCurrent writes the metrics without recording them in the history
+// if the storage supports it, otherwise with BulkAdd.
+func BulkAddCurrent(ctx context.Context, store MetricStorage, metricList []metrics.Metrics) error {
+	if cs, ok := store.(CurrentValueStorage); ok {
+		return cs.BulkAddCurrent(ctx, metricList)
+	}
+	return store.BulkAdd(ctx, metricList)
+}
+
+// RetentionPolicy describes how long the history is kept at each resolution.
+// A zero duration disables the corresponding step.
+type RetentionPolicy struct {
+	// RawTTL raw samples older than this are rolled up into per-minute aggregates.
+	RawTTL time.Duration
+	// MinuteTTL per-minute aggregates older than this are rolled up into per-hour aggregates.
+	MinuteTTL time.Duration
+	// HourTTL per-hour aggregates older than this are deleted.
+	HourTTL time.Duration
+}
+
+// CompactionStats the result of a single history compaction.
+type CompactionStats struct {
+	RawCompacted    int64 // raw samples rolled up into per-minute aggregates
+	MinuteCompacted int64 // per-minute aggregates rolled up into per-hour aggregates
+	HourDeleted     int64 // expired per-hour aggregates
+	RollupsWritten  int64 // created or updated aggregates
+}
+
+// RetentionStorage is an interface for a history repository supporting retention and downsampling.
+type RetentionStorage interface {
+	Compact(ctx context.Context, now time.Time, policy RetentionPolicy) (CompactionStats, error)
+}
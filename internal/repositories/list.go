@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+)
+
+// ErrBadCursor is returned when the pagination cursor cannot be decoded.
+var ErrBadCursor = errors.New("bad cursor")
+
+// Cursor points to the last metric series of the previous page.
+//
+// Series are ordered by the series key and then by the type,
+// so the pair identifies the position in the list.
+type Cursor struct {
+	Key   string
+	MType metrics.MetricType
+}
+
+// NewCursor returns the cursor pointing to the metric series.
+func NewCursor(m metrics.Metrics) *Cursor {
+	return &Cursor{Key: m.SeriesKey(), MType: m.MType}
+}
+
+// ParseCursor decodes the cursor returned by Cursor.String.
+func ParseCursor(s string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrBadCursor
+	}
+
+	mType, key, ok := strings.Cut(string(data), ":")
+	if !ok || !metrics.MetricType(mType).IsValid() {
+		return nil, ErrBadCursor
+	}
+	return &Cursor{Key: key, MType: metrics.MetricType(mType)}, nil
+}
+
+// String encodes the cursor to be passed in an url.
+func (c *Cursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(string(c.MType) + ":" + c.Key))
+}
+
+// Less reports whether the series goes before the cursor in the ascending order.
+func (c *Cursor) Less(key string, mType metrics.MetricType) bool {
+	if key != c.Key {
+		return key < c.Key
+	}
+	return mType < c.MType
+}
+
+// ListQuery describes the filter, the order and the page of the requested metric series.
+// The zero value requests all series in the ascending order.
+type ListQuery struct {
+	// Type only series of the type are returned if set.
+	Type metrics.MetricType
+	// Prefix only series whose name starts with the prefix are returned.
+	Prefix string
+	// Regex only series whose name matches the expression are returned if set.
+	Regex *regexp.Regexp
+	// Desc sorts series by the series key in the descending order.
+	Desc bool
+	// Limit the maximum number of returned series, zero means no limit.
+	Limit int
+	// After only series following the cursor in the requested order are returned if set.
+	After *Cursor
+}
+
+// Match reports whether the series of the type and name passes the filter of the query.
+func (q ListQuery) Match(mType metrics.MetricType, name string) bool {
+	if q.Type != "" && q.Type != mType {
+		return false
+	}
+	if !strings.HasPrefix(name, q.Prefix) {
+		return false
+	}
+	return q.Regex == nil || q.Regex.MatchString(name)
+}
+
+// Follows reports whether the series goes after the cursor of the query in the requested order.
+func (q ListQuery) Follows(key string, mType metrics.MetricType) bool {
+	if q.After == nil {
+		return true
+	}
+	if key == q.After.Key && mType == q.After.MType {
+		return false
+	}
+	return q.After.Less(key, mType) == q.Desc
+}
@@ -0,0 +1,23 @@
+package repositories_test
+
+import (
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCursor(t *testing.T) {
+	m := metrics.Metrics{ID: "cpu_user", MType: metrics.Gauge, Labels: metrics.Labels{"cpu": "0"}}
+
+	cursor, err := repositories.ParseCursor(repositories.NewCursor(m).String())
+	require.NoError(t, err)
+	assert.Equal(t, &repositories.Cursor{Key: `cpu_user{cpu="0"}`, MType: metrics.Gauge}, cursor)
+
+	for _, bad := range []string{"!!!", "Y3B1X3VzZXI", "ZmFrZTpjcHU"} {
+		_, err := repositories.ParseCursor(bad)
+		assert.ErrorIs(t, err, repositories.ErrBadCursor, bad)
+	}
+}
@@ -1,84 +1,128 @@
 package memory
 
 import (
-	"fmt"
-	"runtime"
-	"time"
+	"context"
 
-	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
 )
 
+// CollectionMetricStorage storage of the agent metrics.
+//
+// Counters are accumulated locally and reported to the server as deltas: a batch reserves the delta
+// of every counter since the last reservation, the delta is acknowledged when the batch is committed
+// and returned to the next batch when the batch is rolled back. Histograms are reported
+// the same way with observations made since the last reservation.
 type CollectionMetricStorage struct {
 	MemStorage
+	pending          map[string]int64                   // counter deltas reserved by batches in flight
+	acked            map[string]int64                   // counter totals acknowledged by the server
+	pendingHistogram map[string]*metrics.HistogramValue // histogram observations reserved by batches in flight
+	ackedHistogram   map[string]*metrics.HistogramValue // histogram observations acknowledged by the server
 }
 
 func NewCollectionMetricStorage() *CollectionMetricStorage {
 	return &CollectionMetricStorage{
-		*NewMemStorage(),
+		MemStorage:       *NewMemStorage(),
+		pending:          make(map[string]int64),
+		acked:            make(map[string]int64),
+		pendingHistogram: make(map[string]*metrics.HistogramValue),
+		ackedHistogram:   make(map[string]*metrics.HistogramValue),
 	}
 }
 
-func (collection *CollectionMetricStorage) Update() {
+// List returns all collected metrics.
+func (collection *CollectionMetricStorage) List(ctx context.Context) ([]metrics.Metrics, error) {
+	return collection.MemStorage.List(ctx, repositories.ListQuery{})
+}
+
+// Reserve returns the batch to send: current gauges, counter deltas and histogram observations
+// not yet acknowledged or reserved by other batches. Counters and histograms without changes are skipped.
+func (collection *CollectionMetricStorage) Reserve(ctx context.Context) ([]metrics.Metrics, error) {
 	collection.Lock()
 	defer collection.Unlock()
 
-	collection.gauge["RandomValue"] = float64(time.Now().UnixNano()) / float64(time.Second)
-	collection.counter["PollCount"]++
+	batch := make([]metrics.Metrics, 0, len(collection.gauge)+len(collection.counter))
+	for k, v := range collection.gauge {
+		n, l := collection.describe(k)
+		batch = append(batch, metrics.Metrics{ID: n, MType: metrics.Gauge, Value: &v, Labels: l})
+	}
+	for k, v := range collection.counter {
+		delta := v - collection.acked[k] - collection.pending[k]
+		if delta == 0 {
+			continue
+		}
+		collection.pending[k] += delta
+
+		n, l := collection.describe(k)
+		batch = append(batch, metrics.Metrics{ID: n, MType: metrics.Counter, Delta: &delta, Labels: l})
+	}
+	for k, h := range collection.histogram {
+		delta := h.Clone()
+		for _, reported := range []*metrics.HistogramValue{collection.ackedHistogram[k], collection.pendingHistogram[k]} {
+			if reported == nil {
+				continue
+			}
+			// bounds of a series never change, they are checked when observations are added.
+			_ = delta.Sub(reported)
+		}
+		if delta.Count == 0 {
+			continue
+		}
+		addHistogram(collection.pendingHistogram, k, delta)
+
+		n, l := collection.describe(k)
+		batch = append(batch, metrics.Metrics{ID: n, MType: metrics.Histogram, Histogram: delta, Labels: l})
+	}
+	return batch, nil
 }
 
-func (collection *CollectionMetricStorage) UpdateRuntime() {
-	collection.Lock()
-	defer collection.Unlock()
+// addHistogram merges the observations into the histogram of the key.
+func addHistogram(histograms map[string]*metrics.HistogramValue, key string, h *metrics.HistogramValue) {
+	if current, ok := histograms[key]; ok {
+		_ = current.Merge(h)
+		return
+	}
+	histograms[key] = h.Clone()
+}
 
-	var mem runtime.MemStats
-	runtime.ReadMemStats(&mem)
-	collection.gauge["Alloc"] = float64(mem.Alloc)
-	collection.gauge["BuckHashSys"] = float64(mem.BuckHashSys)
-	collection.gauge["Frees"] = float64(mem.Frees)
-	collection.gauge["GCCPUFraction"] = mem.GCCPUFraction
-	collection.gauge["GCSys"] = float64(mem.GCSys)
-	collection.gauge["HeapAlloc"] = float64(mem.HeapAlloc)
-	collection.gauge["HeapIdle"] = float64(mem.HeapIdle)
-	collection.gauge["HeapInuse"] = float64(mem.HeapInuse)
-	collection.gauge["HeapObjects"] = float64(mem.HeapObjects)
-	collection.gauge["HeapReleased"] = float64(mem.HeapReleased)
-	collection.gauge["HeapSys"] = float64(mem.HeapSys)
-	collection.gauge["LastGC"] = float64(mem.LastGC)
-	collection.gauge["Lookups"] = float64(mem.Lookups)
-	collection.gauge["MCacheInuse"] = float64(mem.MCacheInuse)
-	collection.gauge["MCacheSys"] = float64(mem.MCacheSys)
-	collection.gauge["MSpanInuse"] = float64(mem.MSpanInuse)
-	collection.gauge["MSpanSys"] = float64(mem.MSpanSys)
-	collection.gauge["Mallocs"] = float64(mem.Mallocs)
-	collection.gauge["NextGC"] = float64(mem.NextGC)
-	collection.gauge["NumForcedGC"] = float64(mem.NumForcedGC)
-	collection.gauge["NumGC"] = float64(mem.NumGC)
-	collection.gauge["OtherSys"] = float64(mem.OtherSys)
-	collection.gauge["PauseTotalNs"] = float64(mem.PauseTotalNs)
-	collection.gauge["StackInuse"] = float64(mem.StackInuse)
-	collection.gauge["StackSys"] = float64(mem.StackSys)
-	collection.gauge["Sys"] = float64(mem.Sys)
-	collection.gauge["TotalAlloc"] = float64(mem.TotalAlloc)
+// subHistogram removes the observations reserved before from the histogram of the key.
+func subHistogram(histograms map[string]*metrics.HistogramValue, key string, h *metrics.HistogramValue) {
+	if current, ok := histograms[key]; ok {
+		_ = current.Sub(h)
+	}
 }
 
-func (collection *CollectionMetricStorage) UpdateGopsutil() {
+// Commit marks counter deltas of the batch as acknowledged by the server.
+func (collection *CollectionMetricStorage) Commit(batch []metrics.Metrics) {
 	collection.Lock()
 	defer collection.Unlock()
 
-	memory, err := mem.VirtualMemory()
-	if err != nil {
-		return
+	for _, m := range batch {
+		key := m.SeriesKey()
+		switch {
+		case m.MType == metrics.Counter && m.Delta != nil:
+			collection.pending[key] -= *m.Delta
+			collection.acked[key] += *m.Delta
+		case m.MType == metrics.Histogram && m.Histogram != nil:
+			subHistogram(collection.pendingHistogram, key, m.Histogram)
+			addHistogram(collection.ackedHistogram, key, m.Histogram)
+		}
 	}
+}
 
-	collection.gauge["TotalMemory"] = float64(memory.Total)
-	collection.gauge["FreeMemory"] = float64(memory.Free)
+// Rollback returns counter deltas of the unsent batch, so they are reserved by the next batch.
+func (collection *CollectionMetricStorage) Rollback(batch []metrics.Metrics) {
+	collection.Lock()
+	defer collection.Unlock()
 
-	cpuPercents, err := cpu.Percent(0, false)
-	if err != nil {
-		return
-	}
-	for i, percent := range cpuPercents {
-		collection.gauge[fmt.Sprintf("CPUutilization%d", i+1)] = percent
+	for _, m := range batch {
+		key := m.SeriesKey()
+		switch {
+		case m.MType == metrics.Counter && m.Delta != nil:
+			collection.pending[key] -= *m.Delta
+		case m.MType == metrics.Histogram && m.Histogram != nil:
+			subHistogram(collection.pendingHistogram, key, m.Histogram)
+		}
 	}
 }
@@ -0,0 +1,138 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func counterDelta(batch []metrics.Metrics, id string) int64 {
+	for _, m := range batch {
+		if m.MType == metrics.Counter && m.ID == id {
+			return *m.Delta
+		}
+	}
+	return 0
+}
+
+func TestCollectionMetricStorageReserve(t *testing.T) {
+	ctx := context.Background()
+	collection := NewCollectionMetricStorage()
+
+	one, value := int64(1), 0.5
+	poll := func() {
+		require.NoError(t, collection.BulkAdd(ctx, []metrics.Metrics{
+			{ID: "PollCount", MType: metrics.Counter, Delta: &one},
+			{ID: "RandomValue", MType: metrics.Gauge, Value: &value},
+		}))
+	}
+
+	poll()
+	poll()
+
+	first, err := collection.Reserve(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), counterDelta(first, "PollCount"))
+
+	// a concurrent batch does not include deltas reserved by the first one
+	poll()
+	second, err := collection.Reserve(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), counterDelta(second, "PollCount"))
+
+	// the failed batch returns its delta to the next reservation
+	collection.Rollback(first)
+	collection.Commit(second)
+
+	third, err := collection.Reserve(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), counterDelta(third, "PollCount"))
+	collection.Commit(third)
+
+	// nothing changed since the last acknowledged batch
+	fourth, err := collection.Reserve(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), counterDelta(fourth, "PollCount"))
+	for _, m := range fourth {
+		assert.NotEqual(t, metrics.Counter, m.MType)
+	}
+
+	// gauges are always reported with the current value
+	assert.NotEmpty(t, fourth)
+
+	m := metrics.Metrics{ID: "PollCount", MType: metrics.Counter}
+	require.NoError(t, collection.Get(ctx, &m))
+	assert.Equal(t, int64(3), *m.Delta)
+}
+
+func TestCollectionMetricStorageReserveEscapedName(t *testing.T) {
+	ctx := context.Background()
+	collection := NewCollectionMetricStorage()
+
+	one := int64(1)
+	require.NoError(t, collection.BulkAdd(ctx, []metrics.Metrics{{ID: `jobs{mail}`, MType: metrics.Counter, Delta: &one}}))
+
+	// the batch has the name as it was added, so its delta is acknowledged by the commit.
+	batch, err := collection.Reserve(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), counterDelta(batch, `jobs{mail}`))
+	collection.Commit(batch)
+
+	batch, err = collection.Reserve(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, counterDelta(batch, `jobs{mail}`))
+}
+
+func histogramCount(batch []metrics.Metrics, id string) uint64 {
+	for _, m := range batch {
+		if m.MType == metrics.Histogram && m.ID == id {
+			return m.Histogram.Count
+		}
+	}
+	return 0
+}
+
+func TestCollectionMetricStorageReserveHistogram(t *testing.T) {
+	ctx := context.Background()
+	collection := NewCollectionMetricStorage()
+
+	observe := func(v float64) {
+		h := metrics.NewHistogramValue([]float64{1})
+		h.Observe(v)
+		require.NoError(t, collection.Add(ctx, metrics.Metrics{ID: "latency", MType: metrics.Histogram, Histogram: h}))
+	}
+
+	observe(0.5)
+	observe(2)
+
+	first, err := collection.Reserve(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), histogramCount(first, "latency"))
+
+	observe(0.1)
+	second, err := collection.Reserve(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), histogramCount(second, "latency"))
+
+	// observations of the failed batch are returned to the next reservation
+	collection.Rollback(first)
+	collection.Commit(second)
+
+	third, err := collection.Reserve(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), histogramCount(third, "latency"))
+	for _, m := range third {
+		if m.MType == metrics.Histogram {
+			assert.Equal(t, []uint64{1, 1}, m.Histogram.Counts)
+			assert.Equal(t, 2.5, m.Histogram.Sum)
+		}
+	}
+	collection.Commit(third)
+
+	fourth, err := collection.Reserve(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, histogramCount(fourth, "latency"))
+}
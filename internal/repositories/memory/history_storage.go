@@ -0,0 +1,256 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+)
+
+const (
+	minuteResolution = time.Minute
+	hourResolution   = time.Hour
+)
+
+// seriesRef identifies the history of a metric series.
+type seriesRef struct {
+	mType metrics.MetricType
+	key   string
+}
+
+// HistoryMemStorage is an in-memory storage which, in addition to current values,
+// keeps raw samples and their per-minute and per-hour rollups.
+type HistoryMemStorage struct {
+	*MemStorage
+	historyLock sync.Mutex
+	samples     map[seriesRef][]metrics.Point
+	rollups     map[time.Duration]map[seriesRef][]metrics.Rollup
+	now         func() time.Time
+}
+
+func NewHistoryMemStorage() *HistoryMemStorage {
+	return &HistoryMemStorage{
+		MemStorage: NewMemStorage(),
+		samples:    make(map[seriesRef][]metrics.Point),
+		rollups: map[time.Duration]map[seriesRef][]metrics.Rollup{
+			minuteResolution: make(map[seriesRef][]metrics.Rollup),
+			hourResolution:   make(map[seriesRef][]metrics.Rollup),
+		},
+		now: time.Now,
+	}
+}
+
+func (db *HistoryMemStorage) Add(ctx context.Context, m metrics.Metrics) error {
+	return db.BulkAdd(ctx, []metrics.Metrics{m})
+}
+
+// BulkAdd writes the metrics at once and records samples of gauges and counters.
+func (db *HistoryMemStorage) BulkAdd(ctx context.Context, metricList []metrics.Metrics) error {
+	if err := db.MemStorage.BulkAdd(ctx, metricList); err != nil {
+		return err
+	}
+
+	db.historyLock.Lock()
+	defer db.historyLock.Unlock()
+
+	now := db.now()
+	for _, m := range metricList {
+		var value float64
+		switch m.MType {
+		case metrics.Gauge:
+			value = *m.Value
+		case metrics.Counter:
+			value = float64(*m.Delta)
+		default:
+			continue
+		}
+
+		ref := seriesRef{mType: m.MType, key: m.SeriesKey()}
+		db.samples[ref] = append(db.samples[ref], metrics.Point{Timestamp: now, Value: value})
+	}
+	return nil
+}
+
+// BulkAddCurrent writes the metrics without recording them in the history.
+func (db *HistoryMemStorage) BulkAddCurrent(ctx context.Context, metricList []metrics.Metrics) error {
+	return db.MemStorage.BulkAdd(ctx, metricList)
+}
+
+// Delete removes the metric series together with its samples and rollups.
+func (db *HistoryMemStorage) Delete(ctx context.Context, m metrics.Metrics) error {
+	if err := db.MemStorage.Delete(ctx, m); err != nil {
+		return err
+	}
+
+	db.dropHistory(seriesRef{mType: m.MType, key: m.SeriesKey()})
+	return nil
+}
+
+// DeleteByPrefix removes the series whose name starts with prefix together with their samples and rollups.
+func (db *HistoryMemStorage) DeleteByPrefix(ctx context.Context, prefix string) (int64, error) {
+	deleted := db.MemStorage.deleteByPrefix(prefix)
+	db.dropHistory(deleted...)
+	return int64(len(deleted)), nil
+}
+
+// dropHistory removes the samples and rollups of the series.
+func (db *HistoryMemStorage) dropHistory(refs ...seriesRef) {
+	db.historyLock.Lock()
+	defer db.historyLock.Unlock()
+
+	for _, ref := range refs {
+		delete(db.samples, ref)
+		for _, rollups := range db.rollups {
+			delete(rollups, ref)
+		}
+	}
+}
+
+// rollupValue returns the value of the rollup matching the raw samples semantic of the metric type.
+func rollupValue(mType metrics.MetricType, r metrics.Rollup) float64 {
+	if mType == metrics.Counter {
+		return r.Sum
+	}
+	return r.Last
+}
+
+// History returns the points of the metric series from rollups and raw samples in the requested range.
+func (db *HistoryMemStorage) History(ctx context.Context, query repositories.HistoryQuery) ([]metrics.Point, error) {
+	m := query.Metric
+	if m.MType != metrics.Gauge && m.MType != metrics.Counter {
+		return nil, fmt.Errorf("history is not available for metric type `%s`", m.MType)
+	}
+
+	inRange := func(ts time.Time) bool {
+		return !ts.Before(query.From) && ts.Before(query.To)
+	}
+
+	ref := seriesRef{mType: m.MType, key: m.SeriesKey()}
+
+	db.historyLock.Lock()
+	var points []metrics.Point
+	for _, resolution := range []time.Duration{hourResolution, minuteResolution} {
+		for _, r := range db.rollups[resolution][ref] {
+			if inRange(r.Timestamp) {
+				points = append(points, metrics.Point{Timestamp: r.Timestamp, Value: rollupValue(m.MType, r)})
+			}
+		}
+	}
+	for _, p := range db.samples[ref] {
+		if inRange(p.Timestamp) {
+			points = append(points, p)
+		}
+	}
+	db.historyLock.Unlock()
+
+	sort.SliceStable(points, func(i, j int) bool {
+		return points[i].Timestamp.Before(points[j].Timestamp)
+	})
+
+	if query.Step <= 0 {
+		return points, nil
+	}
+
+	step := int64(query.Step)
+	grouped := make([]metrics.Point, 0, len(points))
+	for _, p := range points {
+		bucket := time.Unix(0, p.Timestamp.UnixNano()/step*step)
+
+		if n := len(grouped); n > 0 && grouped[n-1].Timestamp.Equal(bucket) {
+			if m.MType == metrics.Counter {
+				grouped[n-1].Value += p.Value
+			} else {
+				grouped[n-1].Value = p.Value
+			}
+			continue
+		}
+		grouped = append(grouped, metrics.Point{Timestamp: bucket, Value: p.Value})
+	}
+
+	return grouped, nil
+}
+
+// mergeRollup merges the rollup into the sorted list of rollups of the series.
+func mergeRollup(list []metrics.Rollup, r metrics.Rollup) []metrics.Rollup {
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].Timestamp.Equal(r.Timestamp) {
+			list[i].Merge(r)
+			return list
+		}
+		if list[i].Timestamp.Before(r.Timestamp) {
+			return append(list[:i+1], append([]metrics.Rollup{r}, list[i+1:]...)...)
+		}
+	}
+	return append([]metrics.Rollup{r}, list...)
+}
+
+// Compact rolls up expired raw samples and per-minute aggregates and deletes expired per-hour aggregates.
+func (db *HistoryMemStorage) Compact(ctx context.Context, now time.Time, policy repositories.RetentionPolicy) (stats repositories.CompactionStats, err error) {
+	db.historyLock.Lock()
+	defer db.historyLock.Unlock()
+
+	minutes := db.rollups[minuteResolution]
+	hours := db.rollups[hourResolution]
+
+	if policy.RawTTL > 0 {
+		deadline := now.Add(-policy.RawTTL)
+		for ref, samples := range db.samples {
+			i := sort.Search(len(samples), func(i int) bool { return !samples[i].Timestamp.Before(deadline) })
+
+			var last time.Time
+			for j, p := range samples[:i] {
+				bucket := p.Timestamp.Truncate(minuteResolution)
+				if j == 0 || !bucket.Equal(last) {
+					stats.RollupsWritten++
+					last = bucket
+				}
+				minutes[ref] = mergeRollup(minutes[ref], metrics.NewRollup(bucket, p.Value))
+			}
+
+			stats.RawCompacted += int64(i)
+			if db.samples[ref] = samples[i:]; len(db.samples[ref]) == 0 {
+				delete(db.samples, ref)
+			}
+		}
+	}
+
+	if policy.MinuteTTL > 0 {
+		deadline := now.Add(-policy.MinuteTTL)
+		for ref, rollups := range minutes {
+			i := sort.Search(len(rollups), func(i int) bool { return !rollups[i].Timestamp.Before(deadline) })
+
+			var last time.Time
+			for j, r := range rollups[:i] {
+				r.Timestamp = r.Timestamp.Truncate(hourResolution)
+				if j == 0 || !r.Timestamp.Equal(last) {
+					stats.RollupsWritten++
+					last = r.Timestamp
+				}
+				hours[ref] = mergeRollup(hours[ref], r)
+			}
+
+			stats.MinuteCompacted += int64(i)
+			if minutes[ref] = rollups[i:]; len(minutes[ref]) == 0 {
+				delete(minutes, ref)
+			}
+		}
+	}
+
+	if policy.HourTTL > 0 {
+		deadline := now.Add(-policy.HourTTL)
+		for ref, rollups := range hours {
+			i := sort.Search(len(rollups), func(i int) bool { return !rollups[i].Timestamp.Before(deadline) })
+
+			stats.HourDeleted += int64(i)
+			if hours[ref] = rollups[i:]; len(hours[ref]) == 0 {
+				delete(hours, ref)
+			}
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"github.com/stretchr/testify/suite"
+)
+
+type HistoryMemStorageSuite struct {
+	suite.Suite
+	storage *HistoryMemStorage
+	now     time.Time
+}
+
+func TestHistoryMemStorageSuite(t *testing.T) {
+	suite.Run(t, new(HistoryMemStorageSuite))
+}
+
+func (s *HistoryMemStorageSuite) SetupTest() {
+	s.now = time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	s.storage = NewHistoryMemStorage()
+	s.storage.now = func() time.Time { return s.now }
+}
+
+func (s *HistoryMemStorageSuite) addAt(ts time.Time, m metrics.Metrics) {
+	s.now = ts
+	s.Require().NoError(s.storage.Add(context.Background(), m))
+}
+
+func (s *HistoryMemStorageSuite) TestHistory() {
+	start := s.now
+
+	s.addAt(start, metrics.Metrics{ID: "Alloc", MType: metrics.Gauge, Value: newFloat64(1)})
+	s.addAt(start.Add(20*time.Second), metrics.Metrics{ID: "Alloc", MType: metrics.Gauge, Value: newFloat64(2)})
+	s.addAt(start.Add(70*time.Second), metrics.Metrics{ID: "Alloc", MType: metrics.Gauge, Value: newFloat64(3)})
+	s.addAt(start, metrics.Metrics{ID: "PollCount", MType: metrics.Counter, Delta: newInt64(2)})
+	s.addAt(start.Add(30*time.Second), metrics.Metrics{ID: "PollCount", MType: metrics.Counter, Delta: newInt64(3)})
+
+	ctx := context.Background()
+
+	points, err := s.storage.History(ctx, repositories.HistoryQuery{
+		Metric: metrics.Metrics{ID: "Alloc", MType: metrics.Gauge},
+		From:   start, To: start.Add(time.Hour),
+	})
+	s.Require().NoError(err)
+	s.Len(points, 3)
+
+	points, err = s.storage.History(ctx, repositories.HistoryQuery{
+		Metric: metrics.Metrics{ID: "Alloc", MType: metrics.Gauge},
+		From:   start, To: start.Add(time.Hour), Step: time.Minute,
+	})
+	s.Require().NoError(err)
+	s.Equal([]metrics.Point{
+		{Timestamp: time.Unix(0, start.UnixNano()), Value: 2},
+		{Timestamp: time.Unix(0, start.Add(time.Minute).UnixNano()), Value: 3},
+	}, points)
+
+	points, err = s.storage.History(ctx, repositories.HistoryQuery{
+		Metric: metrics.Metrics{ID: "PollCount", MType: metrics.Counter},
+		From:   start, To: start.Add(time.Hour), Step: time.Minute,
+	})
+	s.Require().NoError(err)
+	s.Equal([]metrics.Point{{Timestamp: time.Unix(0, start.UnixNano()), Value: 5}}, points)
+
+	// current values are still kept by the embedded storage
+	m := metrics.Metrics{ID: "PollCount", MType: metrics.Counter}
+	s.Require().NoError(s.storage.Get(ctx, &m))
+	s.Equal(int64(5), *m.Delta)
+}
+
+func (s *HistoryMemStorageSuite) TestDeleteDropsHistory() {
+	start := s.now
+	ctx := context.Background()
+
+	s.addAt(start, metrics.Metrics{ID: "Alloc", MType: metrics.Gauge, Value: newFloat64(1)})
+	s.addAt(start, metrics.Metrics{ID: "cpu_user", MType: metrics.Gauge, Value: newFloat64(1)})
+	s.addAt(start.Add(-2*time.Hour), metrics.Metrics{ID: "cpu_user", MType: metrics.Gauge, Value: newFloat64(2)})
+	_, err := s.storage.Compact(ctx, start, repositories.RetentionPolicy{RawTTL: time.Hour})
+	s.Require().NoError(err)
+
+	s.Require().NoError(s.storage.Delete(ctx, metrics.Metrics{ID: "Alloc", MType: metrics.Gauge}))
+	deleted, err := s.storage.DeleteByPrefix(ctx, "cpu_")
+	s.Require().NoError(err)
+	s.Equal(int64(1), deleted)
+
+	s.Empty(s.storage.samples)
+	s.Empty(s.storage.rollups[minuteResolution])
+
+	// a recreated series starts with an empty history
+	s.addAt(start, metrics.Metrics{ID: "Alloc", MType: metrics.Gauge, Value: newFloat64(3)})
+	points, err := s.storage.History(ctx, repositories.HistoryQuery{
+		Metric: metrics.Metrics{ID: "Alloc", MType: metrics.Gauge},
+		From:   start.Add(-3 * time.Hour), To: start.Add(time.Hour),
+	})
+	s.Require().NoError(err)
+	s.Equal([]metrics.Point{{Timestamp: start, Value: 3}}, points)
+}
+
+func (s *HistoryMemStorageSuite) TestCompact() {
+	start := s.now
+	ctx := context.Background()
+
+	for i := 0; i < 6; i++ {
+		// two samples per minute over three minutes
+		s.addAt(start.Add(time.Duration(i)*30*time.Second), metrics.Metrics{ID: "Alloc", MType: metrics.Gauge, Value: newFloat64(float64(i))})
+	}
+
+	policy := repositories.RetentionPolicy{RawTTL: time.Minute, MinuteTTL: time.Hour, HourTTL: 24 * time.Hour}
+
+	stats, err := s.storage.Compact(ctx, start.Add(3*time.Minute), policy)
+	s.Require().NoError(err)
+	s.Equal(repositories.CompactionStats{RawCompacted: 4, RollupsWritten: 2}, stats)
+
+	ref := seriesRef{mType: metrics.Gauge, key: "Alloc"}
+	s.Equal([]metrics.Rollup{
+		{Timestamp: start, Min: 0, Max: 1, Sum: 1, Count: 2, Last: 1},
+		{Timestamp: start.Add(time.Minute), Min: 2, Max: 3, Sum: 5, Count: 2, Last: 3},
+	}, s.storage.rollups[minuteResolution][ref])
+	s.Len(s.storage.samples[ref], 2)
+
+	// rollups are returned together with raw samples
+	points, err := s.storage.History(ctx, repositories.HistoryQuery{
+		Metric: metrics.Metrics{ID: "Alloc", MType: metrics.Gauge},
+		From:   start, To: start.Add(time.Hour),
+	})
+	s.Require().NoError(err)
+	s.Len(points, 4)
+	s.Equal(float64(1), points[0].Value)
+
+	stats, err = s.storage.Compact(ctx, start.Add(2*time.Hour), policy)
+	s.Require().NoError(err)
+	s.Equal(repositories.CompactionStats{RawCompacted: 2, MinuteCompacted: 3, RollupsWritten: 2}, stats)
+	s.Equal([]metrics.Rollup{
+		{Timestamp: start, Min: 0, Max: 5, Sum: 15, Count: 6, Last: 5},
+	}, s.storage.rollups[hourResolution][ref])
+	s.Empty(s.storage.rollups[minuteResolution][ref])
+	s.Empty(s.storage.samples[ref])
+
+	stats, err = s.storage.Compact(ctx, start.Add(48*time.Hour), policy)
+	s.Require().NoError(err)
+	s.Equal(repositories.CompactionStats{HourDeleted: 1}, stats)
+	s.Empty(s.storage.rollups[hourResolution][ref])
+}
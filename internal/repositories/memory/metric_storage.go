@@ -2,74 +2,187 @@ package memory
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
 	"github.com/screamsoul/go-metrics-tpl/pkg/logging"
 	"go.uber.org/zap"
 )
 
+// series describes a metric series stored under a series key other than its name.
+type series struct {
+	name   string
+	labels metrics.Labels
+}
+
 type MemStorage struct {
 	sync.Mutex
-	gauge   map[string]float64
-	counter map[string]int64
-	logger  *zap.Logger
+	gauge     map[string]float64
+	counter   map[string]int64
+	histogram map[string]*metrics.HistogramValue
+	series    map[string]series
+	logger    *zap.Logger
 }
 
 func NewMemStorage() *MemStorage {
 	return &MemStorage{
-		counter: make(map[string]int64),
-		gauge:   make(map[string]float64),
-		logger:  logging.GetLogger(),
+		counter:   make(map[string]int64),
+		gauge:     make(map[string]float64),
+		histogram: make(map[string]*metrics.HistogramValue),
+		series:    make(map[string]series),
+		logger:    logging.GetLogger(),
+	}
+}
+
+// describe returns the metric name and labels for the series key.
+func (db *MemStorage) describe(key string) (string, metrics.Labels) {
+	if s, ok := db.series[key]; ok {
+		return s.name, s.labels
 	}
+	return key, nil
 }
 
 func (db *MemStorage) Add(ctx context.Context, m metrics.Metrics) error {
-	db.Lock()
-	defer db.Unlock()
+	return db.BulkAdd(ctx, []metrics.Metrics{m})
+}
+
+// check returns an error if a histogram of the list cannot be merged with the stored one
+// or with a histogram of the same series earlier in the list, must be called with the lock held.
+func (db *MemStorage) check(metricList []metrics.Metrics) error {
+	merged := make(map[string]*metrics.HistogramValue)
+	for _, m := range metricList {
+		if m.MType != metrics.Histogram {
+			continue
+		}
+
+		key := m.SeriesKey()
+		h, ok := merged[key]
+		if !ok {
+			stored, ok := db.histogram[key]
+			if !ok {
+				merged[key] = m.Histogram.Clone()
+				continue
+			}
+			h = stored.Clone()
+			merged[key] = h
+		}
+		if err := h.Merge(m.Histogram); err != nil {
+			return fmt.Errorf("metric %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// add writes the metric checked with check, must be called with the lock held.
+func (db *MemStorage) add(m metrics.Metrics) {
+	key := m.SeriesKey()
 
 	switch m.MType {
 	case metrics.Gauge:
-		db.gauge[m.ID] = *m.Value
+		db.gauge[key] = *m.Value
 	case metrics.Counter:
-		db.counter[m.ID] += *m.Delta
+		db.counter[key] += *m.Delta
+	case metrics.Histogram:
+		if h, ok := db.histogram[key]; ok {
+			// the buckets are checked by check.
+			_ = h.Merge(m.Histogram)
+		} else {
+			db.histogram[key] = m.Histogram.Clone()
+		}
+	}
+
+	// the key of a labeled series or of a name with escaped characters is not the name.
+	if key != m.ID {
+		db.series[key] = series{name: m.ID, labels: m.Labels}
 	}
-	return nil
 }
 
 func (db *MemStorage) Get(ctx context.Context, metric *metrics.Metrics) error {
+	db.Lock()
+	defer db.Unlock()
+
+	key := metric.SeriesKey()
+
 	switch metric.MType {
 	case metrics.Gauge:
-		if v, ok := db.gauge[metric.ID]; ok {
+		if v, ok := db.gauge[key]; ok {
 			metric.Value = &v
 			return nil
 		}
 	case metrics.Counter:
-		if v, ok := db.counter[metric.ID]; ok {
+		if v, ok := db.counter[key]; ok {
 			metric.Delta = &v
 			return nil
 		}
+	case metrics.Histogram:
+		if v, ok := db.histogram[key]; ok {
+			metric.Histogram = v.Clone()
+			return nil
+		}
 	}
 
-	return errors.New("not found")
+	return repositories.ErrMetricNotFound
 }
 
-func (db *MemStorage) List(ctx context.Context) ([]metrics.Metrics, error) {
-	metics := make([]metrics.Metrics, 0, len(db.counter)+len(db.gauge))
-	for n, v := range db.gauge {
-		metics = append(metics, metrics.Metrics{
-			ID:    n,
-			MType: metrics.Gauge,
-			Value: &v,
-		})
+// List returns the series matching the query, the filter, the order and the page are applied under the lock
+// so only the requested series are copied.
+func (db *MemStorage) List(ctx context.Context, query repositories.ListQuery) ([]metrics.Metrics, error) {
+	db.Lock()
+	defer db.Unlock()
+
+	type entry struct {
+		key   string
+		mType metrics.MetricType
+	}
+
+	entries := make([]entry, 0, len(db.counter)+len(db.gauge)+len(db.histogram))
+	collect := func(mType metrics.MetricType, key string) {
+		if name, _ := db.describe(key); query.Match(mType, name) && query.Follows(key, mType) {
+			entries = append(entries, entry{key: key, mType: mType})
+		}
+	}
+	for k := range db.gauge {
+		collect(metrics.Gauge, k)
+	}
+	for k := range db.counter {
+		collect(metrics.Counter, k)
+	}
+	for k := range db.histogram {
+		collect(metrics.Histogram, k)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		less := entries[i].key < entries[j].key ||
+			entries[i].key == entries[j].key && entries[i].mType < entries[j].mType
+		if query.Desc {
+			return !less
+		}
+		return less
+	})
+	if query.Limit > 0 && len(entries) > query.Limit {
+		entries = entries[:query.Limit]
 	}
-	for n, v := range db.counter {
-		metics = append(metics, metrics.Metrics{
-			ID:    n,
-			MType: metrics.Counter,
-			Delta: &v,
-		})
+
+	metics := make([]metrics.Metrics, 0, len(entries))
+	for _, e := range entries {
+		n, l := db.describe(e.key)
+		m := metrics.Metrics{ID: n, MType: e.mType, Labels: l}
+
+		switch e.mType {
+		case metrics.Gauge:
+			v := db.gauge[e.key]
+			m.Value = &v
+		case metrics.Counter:
+			v := db.counter[e.key]
+			m.Delta = &v
+		case metrics.Histogram:
+			m.Histogram = db.histogram[e.key].Clone()
+		}
+		metics = append(metics, m)
 	}
 	return metics, nil
 }
@@ -78,11 +191,109 @@ func (db *MemStorage) Ping(ctx context.Context) bool {
 	return true
 }
 
+// BulkAdd writes the metrics at once: if a metric cannot be written, none of them is.
 func (db *MemStorage) BulkAdd(ctx context.Context, metricList []metrics.Metrics) error {
-	for _, metric := range metricList {
-		if err := db.Add(ctx, metric); err != nil {
-			return err
+	db.Lock()
+	defer db.Unlock()
+
+	if err := db.check(metricList); err != nil {
+		return err
+	}
+	for _, m := range metricList {
+		db.add(m)
+	}
+	return nil
+}
+
+// forget removes the description of the series key if no metric type uses it anymore.
+func (db *MemStorage) forget(key string) {
+	_, isGauge := db.gauge[key]
+	_, isCounter := db.counter[key]
+	_, isHistogram := db.histogram[key]
+
+	if !isGauge && !isCounter && !isHistogram {
+		delete(db.series, key)
+	}
+}
+
+func (db *MemStorage) Delete(ctx context.Context, m metrics.Metrics) error {
+	db.Lock()
+	defer db.Unlock()
+
+	key := m.SeriesKey()
+
+	var found bool
+	switch m.MType {
+	case metrics.Gauge:
+		_, found = db.gauge[key]
+		delete(db.gauge, key)
+	case metrics.Counter:
+		_, found = db.counter[key]
+		delete(db.counter, key)
+	case metrics.Histogram:
+		_, found = db.histogram[key]
+		delete(db.histogram, key)
+	}
+
+	if !found {
+		return repositories.ErrMetricNotFound
+	}
+	db.forget(key)
+	return nil
+}
+
+func (db *MemStorage) DeleteByPrefix(ctx context.Context, prefix string) (int64, error) {
+	return int64(len(db.deleteByPrefix(prefix))), nil
+}
+
+// deleteByPrefix removes the series whose name starts with prefix and returns their references.
+func (db *MemStorage) deleteByPrefix(prefix string) []seriesRef {
+	db.Lock()
+	defer db.Unlock()
+
+	var deleted []seriesRef
+	match := func(mType metrics.MetricType, key string) bool {
+		if name, _ := db.describe(key); !strings.HasPrefix(name, prefix) {
+			return false
+		}
+		deleted = append(deleted, seriesRef{mType: mType, key: key})
+		return true
+	}
+
+	for key := range db.gauge {
+		if match(metrics.Gauge, key) {
+			delete(db.gauge, key)
+		}
+	}
+	for key := range db.counter {
+		if match(metrics.Counter, key) {
+			delete(db.counter, key)
+		}
+	}
+	for key := range db.histogram {
+		if match(metrics.Histogram, key) {
+			delete(db.histogram, key)
 		}
 	}
+
+	for _, ref := range deleted {
+		db.forget(ref.key)
+	}
+	return deleted
+}
+
+func (db *MemStorage) Reset(ctx context.Context, m metrics.Metrics) error {
+	if m.MType != metrics.Counter {
+		return fmt.Errorf("reset is not available for metric type `%s`", m.MType)
+	}
+
+	db.Lock()
+	defer db.Unlock()
+
+	key := m.SeriesKey()
+	if _, ok := db.counter[key]; !ok {
+		return repositories.ErrMetricNotFound
+	}
+	db.counter[key] = 0
 	return nil
 }
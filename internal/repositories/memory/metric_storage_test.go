@@ -3,9 +3,11 @@ package memory
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"testing"
 
 	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -37,6 +39,8 @@ func (s *MemStorageSuite) SetupTest() {
 func (s *MemStorageSuite) TearDownTest() {
 	s.storage.gauge = make(map[string]float64)
 	s.storage.counter = make(map[string]int64)
+	s.storage.histogram = make(map[string]*metrics.HistogramValue)
+	s.storage.series = make(map[string]series)
 }
 
 func (s *MemStorageSuite) TestAdd() {
@@ -127,8 +131,8 @@ func (s *MemStorageSuite) TestList() {
 				s.storage.counter["counter1"] = 1
 			},
 			expect: []metrics.Metrics{
-				{ID: "gauge1", MType: metrics.Gauge, Value: newFloat64(1.1)},
 				{ID: "counter1", MType: metrics.Counter, Delta: newInt64(1)},
+				{ID: "gauge1", MType: metrics.Gauge, Value: newFloat64(1.1)},
 			},
 		},
 	}
@@ -136,10 +140,288 @@ func (s *MemStorageSuite) TestList() {
 	for _, tc := range testCases {
 		tc.initDB()
 
-		resMetrics, err := s.storage.List(ctx)
+		resMetrics, err := s.storage.List(ctx, repositories.ListQuery{})
 		s.NoError(err)
 		s.Equal(tc.expect, resMetrics)
 
 		s.TearDownTest()
 	}
 }
+
+func (s *MemStorageSuite) TestListQuery() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.Require().NoError(s.storage.BulkAdd(ctx, []metrics.Metrics{
+		{ID: "cpu_user", MType: metrics.Gauge, Value: newFloat64(1), Labels: metrics.Labels{"cpu": "1"}},
+		{ID: "cpu_user", MType: metrics.Gauge, Value: newFloat64(2), Labels: metrics.Labels{"cpu": "0"}},
+		{ID: "cpu_count", MType: metrics.Counter, Delta: newInt64(4)},
+		{ID: "cpu_count", MType: metrics.Gauge, Value: newFloat64(4)},
+		{ID: "mem", MType: metrics.Gauge, Value: newFloat64(3)},
+	}))
+
+	seriesKeys := func(list []metrics.Metrics) (keys []string) {
+		for _, m := range list {
+			keys = append(keys, string(m.MType)+" "+m.SeriesKey())
+		}
+		return
+	}
+
+	testCases := []struct {
+		name   string
+		query  repositories.ListQuery
+		expect []string
+	}{
+		{
+			name:  "all",
+			query: repositories.ListQuery{},
+			expect: []string{
+				"counter cpu_count", "gauge cpu_count", `gauge cpu_user{cpu="0"}`, `gauge cpu_user{cpu="1"}`, "gauge mem",
+			},
+		},
+		{
+			name:   "type and prefix",
+			query:  repositories.ListQuery{Type: metrics.Gauge, Prefix: "cpu_"},
+			expect: []string{"gauge cpu_count", `gauge cpu_user{cpu="0"}`, `gauge cpu_user{cpu="1"}`},
+		},
+		{
+			name:   "regex desc",
+			query:  repositories.ListQuery{Regex: regexp.MustCompile(`^(mem|cpu_user)$`), Desc: true},
+			expect: []string{"gauge mem", `gauge cpu_user{cpu="1"}`, `gauge cpu_user{cpu="0"}`},
+		},
+		{
+			name:   "page",
+			query:  repositories.ListQuery{Limit: 2, After: &repositories.Cursor{Key: "cpu_count", MType: metrics.Counter}},
+			expect: []string{"gauge cpu_count", `gauge cpu_user{cpu="0"}`},
+		},
+		{
+			name:   "page desc",
+			query:  repositories.ListQuery{Desc: true, After: &repositories.Cursor{Key: "cpu_count", MType: metrics.Gauge}},
+			expect: []string{"counter cpu_count"},
+		},
+	}
+
+	for _, tc := range testCases {
+		s.Run(tc.name, func() {
+			list, err := s.storage.List(ctx, tc.query)
+			s.Require().NoError(err)
+			s.Equal(tc.expect, seriesKeys(list))
+		})
+	}
+}
+
+func (s *MemStorageSuite) TestLabels() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hostA := metrics.Labels{"host": "a", "region": "eu"}
+	hostB := metrics.Labels{"host": "b", "region": "eu"}
+
+	s.Require().NoError(s.storage.BulkAdd(ctx, []metrics.Metrics{
+		{ID: "requests", MType: metrics.Counter, Delta: newInt64(1), Labels: hostA},
+		{ID: "requests", MType: metrics.Counter, Delta: newInt64(2), Labels: hostA},
+		{ID: "requests", MType: metrics.Counter, Delta: newInt64(5), Labels: hostB},
+		{ID: "requests", MType: metrics.Counter, Delta: newInt64(7)},
+	}))
+
+	testCases := []struct {
+		labels metrics.Labels
+		expect int64
+	}{
+		{labels: metrics.Labels{"region": "eu", "host": "a"}, expect: 3},
+		{labels: hostB, expect: 5},
+		{labels: nil, expect: 7},
+	}
+
+	for _, tc := range testCases {
+		m := metrics.Metrics{ID: "requests", MType: metrics.Counter, Labels: tc.labels}
+		s.Require().NoError(s.storage.Get(ctx, &m))
+		s.Equal(tc.expect, *m.Delta)
+	}
+
+	err := s.storage.Get(ctx, &metrics.Metrics{ID: "requests", MType: metrics.Counter, Labels: metrics.Labels{"host": "c"}})
+	s.ErrorIs(err, repositories.ErrMetricNotFound)
+
+	list, err := s.storage.List(ctx, repositories.ListQuery{})
+	s.Require().NoError(err)
+	s.Len(list, 3)
+	for _, m := range list {
+		s.Equal("requests", m.ID)
+	}
+}
+
+func (s *MemStorageSuite) TestHistogram() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bounds := []float64{0.1, 1}
+
+	first := metrics.NewHistogramValue(bounds)
+	first.Observe(0.05)
+	first.Observe(0.5)
+
+	second := metrics.NewHistogramValue(bounds)
+	second.Observe(3)
+
+	s.Require().NoError(s.storage.Add(ctx, metrics.Metrics{ID: "latency", MType: metrics.Histogram, Histogram: first}))
+	s.Require().NoError(s.storage.Add(ctx, metrics.Metrics{ID: "latency", MType: metrics.Histogram, Histogram: second}))
+
+	m := metrics.Metrics{ID: "latency", MType: metrics.Histogram}
+	s.Require().NoError(s.storage.Get(ctx, &m))
+	s.Equal([]uint64{1, 1, 1}, m.Histogram.Counts)
+	s.Equal(uint64(3), m.Histogram.Count)
+	s.InDelta(3.55, m.Histogram.Sum, 1e-9)
+
+	// the stored histogram must not share memory with the added one
+	s.Equal([]uint64{1, 1, 0}, first.Counts)
+
+	other := metrics.NewHistogramValue([]float64{1, 2})
+	other.Observe(1)
+	err := s.storage.Add(ctx, metrics.Metrics{ID: "latency", MType: metrics.Histogram, Histogram: other})
+	s.Error(err)
+}
+
+func (s *MemStorageSuite) TestBulkAddIsAtomic() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stored := metrics.NewHistogramValue([]float64{1})
+	stored.Observe(0.5)
+	s.Require().NoError(s.storage.BulkAdd(ctx, []metrics.Metrics{
+		{ID: "requests", MType: metrics.Counter, Delta: newInt64(5)},
+		{ID: "latency", MType: metrics.Histogram, Histogram: stored},
+	}))
+
+	// the histogram of the batch has other buckets, so the counter before it is not added either.
+	other := metrics.NewHistogramValue([]float64{1, 2})
+	other.Observe(1.5)
+	for i := 0; i < 3; i++ {
+		err := s.storage.BulkAdd(ctx, []metrics.Metrics{
+			{ID: "requests", MType: metrics.Counter, Delta: newInt64(5)},
+			{ID: "Alloc", MType: metrics.Gauge, Value: newFloat64(1)},
+			{ID: "latency", MType: metrics.Histogram, Histogram: other},
+		})
+		s.Require().Error(err)
+	}
+
+	// histograms of the same series in one batch are checked against each other.
+	fresh := metrics.NewHistogramValue([]float64{1})
+	fresh.Observe(2)
+	err := s.storage.BulkAdd(ctx, []metrics.Metrics{
+		{ID: "sizes", MType: metrics.Histogram, Histogram: fresh},
+		{ID: "sizes", MType: metrics.Histogram, Histogram: other},
+	})
+	s.Require().Error(err)
+
+	m := metrics.Metrics{ID: "requests", MType: metrics.Counter}
+	s.Require().NoError(s.storage.Get(ctx, &m))
+	s.Equal(int64(5), *m.Delta)
+
+	m = metrics.Metrics{ID: "latency", MType: metrics.Histogram}
+	s.Require().NoError(s.storage.Get(ctx, &m))
+	s.Equal(uint64(1), m.Histogram.Count)
+
+	for _, m := range []metrics.Metrics{{ID: "Alloc", MType: metrics.Gauge}, {ID: "sizes", MType: metrics.Histogram}} {
+		s.ErrorIs(s.storage.Get(ctx, &m), repositories.ErrMetricNotFound)
+	}
+}
+
+func (s *MemStorageSuite) TestDelete() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hostA := metrics.Labels{"host": "a"}
+
+	s.Require().NoError(s.storage.BulkAdd(ctx, []metrics.Metrics{
+		{ID: "requests", MType: metrics.Counter, Delta: newInt64(1), Labels: hostA},
+		{ID: "requests", MType: metrics.Counter, Delta: newInt64(2)},
+		{ID: "Alloc", MType: metrics.Gauge, Value: newFloat64(1)},
+	}))
+
+	s.Require().NoError(s.storage.Delete(ctx, metrics.Metrics{ID: "requests", MType: metrics.Counter, Labels: hostA}))
+	s.NotContains(s.storage.series, `requests{host="a"}`)
+
+	err := s.storage.Delete(ctx, metrics.Metrics{ID: "requests", MType: metrics.Counter, Labels: hostA})
+	s.ErrorIs(err, repositories.ErrMetricNotFound)
+
+	err = s.storage.Delete(ctx, metrics.Metrics{ID: "Alloc", MType: metrics.Counter})
+	s.ErrorIs(err, repositories.ErrMetricNotFound)
+
+	list, err := s.storage.List(ctx, repositories.ListQuery{})
+	s.Require().NoError(err)
+	s.Len(list, 2)
+}
+
+func (s *MemStorageSuite) TestDeleteByPrefix() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.Require().NoError(s.storage.BulkAdd(ctx, []metrics.Metrics{
+		{ID: "cpu_user", MType: metrics.Gauge, Value: newFloat64(1), Labels: metrics.Labels{"cpu": "0"}},
+		{ID: "cpu_user", MType: metrics.Gauge, Value: newFloat64(2), Labels: metrics.Labels{"cpu": "1"}},
+		{ID: "cpu_count", MType: metrics.Counter, Delta: newInt64(4)},
+		{ID: "mem", MType: metrics.Gauge, Value: newFloat64(3)},
+	}))
+
+	deleted, err := s.storage.DeleteByPrefix(ctx, "cpu_")
+	s.Require().NoError(err)
+	s.Equal(int64(3), deleted)
+	s.Empty(s.storage.series)
+
+	list, err := s.storage.List(ctx, repositories.ListQuery{})
+	s.Require().NoError(err)
+	s.Require().Len(list, 1)
+	s.Equal("mem", list[0].ID)
+
+	deleted, err = s.storage.DeleteByPrefix(ctx, "cpu_")
+	s.Require().NoError(err)
+	s.Zero(deleted)
+}
+
+func (s *MemStorageSuite) TestEscapedNames() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.Require().NoError(s.storage.BulkAdd(ctx, []metrics.Metrics{
+		{ID: `a{b`, MType: metrics.Gauge, Value: newFloat64(1)},
+		{ID: `a{b`, MType: metrics.Counter, Delta: newInt64(2), Labels: metrics.Labels{"host": "a"}},
+		{ID: "ab", MType: metrics.Gauge, Value: newFloat64(3)},
+	}))
+
+	// the names are listed and filtered as they were added, not as series keys.
+	list, err := s.storage.List(ctx, repositories.ListQuery{Prefix: "a{"})
+	s.Require().NoError(err)
+	s.Require().Len(list, 2)
+	for _, m := range list {
+		s.Equal(`a{b`, m.ID)
+	}
+
+	list, err = s.storage.List(ctx, repositories.ListQuery{Regex: regexp.MustCompile(`^a\{b$`)})
+	s.Require().NoError(err)
+	s.Len(list, 2)
+
+	deleted, err := s.storage.DeleteByPrefix(ctx, "a{")
+	s.Require().NoError(err)
+	s.Equal(int64(2), deleted)
+	s.Empty(s.storage.series)
+
+	list, err = s.storage.List(ctx, repositories.ListQuery{})
+	s.Require().NoError(err)
+	s.Require().Len(list, 1)
+	s.Equal("ab", list[0].ID)
+}
+
+func (s *MemStorageSuite) TestReset() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	s.Require().NoError(s.storage.Add(ctx, metrics.Metrics{ID: "PollCount", MType: metrics.Counter, Delta: newInt64(5)}))
+	s.Require().NoError(s.storage.Reset(ctx, metrics.Metrics{ID: "PollCount", MType: metrics.Counter}))
+
+	m := metrics.Metrics{ID: "PollCount", MType: metrics.Counter}
+	s.Require().NoError(s.storage.Get(ctx, &m))
+	s.Zero(*m.Delta)
+
+	s.ErrorIs(s.storage.Reset(ctx, metrics.Metrics{ID: "missing", MType: metrics.Counter}), repositories.ErrMetricNotFound)
+	s.Error(s.storage.Reset(ctx, metrics.Metrics{ID: "Alloc", MType: metrics.Gauge}))
+}
@@ -2,10 +2,14 @@ package repositories
 
 import (
 	"context"
+	"errors"
 
 	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
 )
 
+// ErrMetricNotFound is returned when the requested metric series is not in the repository.
+var ErrMetricNotFound = errors.New("metric not found")
+
 // MatrixStorage is the main interface defining methods for interacting with the repository.
 //
 //go:generate minimock -i github.com/screamsoul/go-metrics-tpl/internal/repositories.MetricStorage -o ./mocks/metric_storage_mock.go -g
@@ -14,6 +18,14 @@ type MetricStorage interface {
 	BulkAdd(ctx context.Context, m []metrics.Metrics) error
 
 	Get(ctx context.Context, m *metrics.Metrics) error
-	List(ctx context.Context) ([]metrics.Metrics, error)
+	// List returns the metric series matching the query, sorted by the series key.
+	List(ctx context.Context, query ListQuery) ([]metrics.Metrics, error)
 	Ping(ctx context.Context) bool
+
+	// Delete removes the metric series of the type, name and labels of m together with its history.
+	Delete(ctx context.Context, m metrics.Metrics) error
+	// DeleteByPrefix removes all series whose name starts with prefix and returns their number.
+	DeleteByPrefix(ctx context.Context, prefix string) (int64, error)
+	// Reset sets the value of the counter series of m to zero.
+	Reset(ctx context.Context, m metrics.Metrics) error
 }
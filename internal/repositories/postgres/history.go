@@ -0,0 +1,273 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"github.com/screamsoul/go-metrics-tpl/pkg/backoff"
+	"go.uber.org/zap"
+)
+
+const (
+	minuteResolution = 60
+	hourResolution   = 3600
+)
+
+// historyPoints selects raw samples together with rollups of the series,
+// rollups are represented by their last value for gauges and the sum for counters.
+const historyPoints = `
+	WITH points AS (
+		SELECT ts, COALESCE(value, delta::double precision) AS value
+		FROM metric_samples
+		WHERE series_key = $1 AND m_type = $2 AND ts >= $3 AND ts < $4
+		UNION ALL
+		SELECT bucket AS ts, CASE WHEN m_type = 'counter' THEN sum ELSE last END AS value
+		FROM metric_rollups
+		WHERE series_key = $1 AND m_type = $2 AND bucket >= $3 AND bucket < $4
+	)`
+
+const (
+	historyRawQuery = historyPoints + `
+		SELECT ts, value FROM points ORDER BY ts`
+
+	historyGaugeQuery = historyPoints + `
+		SELECT to_timestamp(floor(extract(epoch FROM ts) / $5) * $5) AS ts,
+			(array_agg(value ORDER BY ts DESC))[1] AS value
+		FROM points
+		GROUP BY 1
+		ORDER BY 1`
+
+	historyCounterQuery = historyPoints + `
+		SELECT to_timestamp(floor(extract(epoch FROM ts) / $5) * $5) AS ts,
+			SUM(value) AS value
+		FROM points
+		GROUP BY 1
+		ORDER BY 1`
+)
+
+// historyBatchPoints selects the points of several series like historyPoints,
+// the series are passed as a JSON array of {"series_key", "m_type"} objects.
+const historyBatchPoints = `
+	WITH series AS (
+		SELECT s.series_key, s.m_type::metric_type AS m_type
+		FROM jsonb_to_recordset($1::jsonb) AS s(series_key text, m_type text)
+	), points AS (
+		SELECT series_key, m_type, ts, COALESCE(value, delta::double precision) AS value
+		FROM metric_samples JOIN series USING (series_key, m_type)
+		WHERE ts >= $2 AND ts < $3
+		UNION ALL
+		SELECT series_key, m_type, bucket AS ts, CASE WHEN m_type = 'counter' THEN sum ELSE last END AS value
+		FROM metric_rollups JOIN series USING (series_key, m_type)
+		WHERE bucket >= $2 AND bucket < $3
+	)`
+
+const (
+	historyBatchRawQuery = historyBatchPoints + `
+		SELECT series_key, m_type, ts, value FROM points ORDER BY series_key, m_type, ts`
+
+	historyBatchStepQuery = historyBatchPoints + `
+		SELECT series_key, m_type, to_timestamp(floor(extract(epoch FROM ts) / $4) * $4) AS ts,
+			CASE WHEN m_type = 'counter' THEN SUM(value) ELSE (array_agg(value ORDER BY ts DESC))[1] END AS value
+		FROM points
+		GROUP BY series_key, m_type, 3
+		ORDER BY series_key, m_type, 3`
+)
+
+const (
+	// compactRawQuery moves expired raw samples into per-minute rollups.
+	compactRawQuery = `
+		WITH moved AS (
+			DELETE FROM metric_samples WHERE ts < $1
+			RETURNING series_key, name, m_type, labels, ts, COALESCE(value, delta::double precision) AS v
+		), written AS (
+			INSERT INTO metric_rollups (series_key, name, m_type, labels, resolution, bucket, min, max, sum, count, last)
+			SELECT series_key, name, m_type, labels, $2::integer, date_trunc('minute', ts),
+				MIN(v), MAX(v), SUM(v), COUNT(*), (array_agg(v ORDER BY ts DESC))[1]
+			FROM moved
+			GROUP BY series_key, name, m_type, labels, date_trunc('minute', ts)
+			ON CONFLICT (series_key, m_type, resolution, bucket) DO UPDATE SET
+				min = LEAST(metric_rollups.min, excluded.min),
+				max = GREATEST(metric_rollups.max, excluded.max),
+				sum = metric_rollups.sum + excluded.sum,
+				count = metric_rollups.count + excluded.count,
+				last = excluded.last
+			RETURNING 1
+		)
+		SELECT (SELECT COUNT(*) FROM moved), (SELECT COUNT(*) FROM written)`
+
+	// compactMinuteQuery moves expired per-minute rollups into per-hour rollups.
+	compactMinuteQuery = `
+		WITH moved AS (
+			DELETE FROM metric_rollups WHERE resolution = $2 AND bucket < $1
+			RETURNING series_key, name, m_type, labels, bucket, min, max, sum, count, last
+		), written AS (
+			INSERT INTO metric_rollups (series_key, name, m_type, labels, resolution, bucket, min, max, sum, count, last)
+			SELECT series_key, name, m_type, labels, $3::integer, date_trunc('hour', bucket),
+				MIN(min), MAX(max), SUM(sum), SUM(count), (array_agg(last ORDER BY bucket DESC))[1]
+			FROM moved
+			GROUP BY series_key, name, m_type, labels, date_trunc('hour', bucket)
+			ON CONFLICT (series_key, m_type, resolution, bucket) DO UPDATE SET
+				min = LEAST(metric_rollups.min, excluded.min),
+				max = GREATEST(metric_rollups.max, excluded.max),
+				sum = metric_rollups.sum + excluded.sum,
+				count = metric_rollups.count + excluded.count,
+				last = excluded.last
+			RETURNING 1
+		)
+		SELECT (SELECT COUNT(*) FROM moved), (SELECT COUNT(*) FROM written)`
+
+	// compactHourQuery deletes expired per-hour rollups.
+	compactHourQuery = `DELETE FROM metric_rollups WHERE resolution = $2 AND bucket < $1`
+)
+
+// History returns the points of the metric series in the requested range.
+func (storage *PostgresStorage) History(ctx context.Context, query repositories.HistoryQuery) (points []metrics.Point, err error) {
+	m := query.Metric
+
+	var (
+		sqlQuery string
+		args     = []any{m.SeriesKey(), m.MType, query.From, query.To}
+	)
+
+	switch {
+	case m.MType != metrics.Gauge && m.MType != metrics.Counter:
+		return nil, fmt.Errorf("history is not available for metric type `%s`", m.MType)
+	case query.Step <= 0:
+		sqlQuery = historyRawQuery
+	case m.MType == metrics.Gauge:
+		sqlQuery = historyGaugeQuery
+		args = append(args, query.Step.Seconds())
+	default:
+		sqlQuery = historyCounterQuery
+		args = append(args, query.Step.Seconds())
+	}
+
+	exec := func() error {
+		return storage.db.SelectContext(ctx, &points, sqlQuery, args...)
+	}
+
+	err = backoff.RetryWithBackoff(storage.backoffInteraval, IsTemporaryConnectionError, exec)
+	if err != nil {
+		err = fmt.Errorf("failed retries db request, %w", err)
+	}
+
+	return
+}
+
+// seriesPoint a point of one of the series of a batch history query.
+type seriesPoint struct {
+	SeriesKey string             `db:"series_key"`
+	MType     metrics.MetricType `db:"m_type"`
+	metrics.Point
+}
+
+// HistoryBatch returns the points of several metric series in the requested range with one query.
+func (storage *PostgresStorage) HistoryBatch(ctx context.Context, query repositories.HistoryBatchQuery) ([][]metrics.Point, error) {
+	type series struct {
+		SeriesKey string             `json:"series_key"`
+		MType     metrics.MetricType `json:"m_type"`
+	}
+
+	result := make([][]metrics.Point, len(query.Metrics))
+	if len(query.Metrics) == 0 {
+		return result, nil
+	}
+
+	list := make([]series, 0, len(query.Metrics))
+	for _, m := range query.Metrics {
+		if m.MType != metrics.Gauge && m.MType != metrics.Counter {
+			return nil, fmt.Errorf("history is not available for metric type `%s`", m.MType)
+		}
+		list = append(list, series{SeriesKey: m.SeriesKey(), MType: m.MType})
+	}
+	seriesJSON, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlQuery := historyBatchRawQuery
+	args := []any{string(seriesJSON), query.From, query.To}
+	if query.Step > 0 {
+		sqlQuery = historyBatchStepQuery
+		args = append(args, query.Step.Seconds())
+	}
+
+	var rows []seriesPoint
+	exec := func() error {
+		rows = rows[:0]
+		return storage.db.SelectContext(ctx, &rows, sqlQuery, args...)
+	}
+
+	if err := backoff.RetryWithBackoff(storage.backoffInteraval, IsTemporaryConnectionError, exec); err != nil {
+		return nil, fmt.Errorf("failed retries db request, %w", err)
+	}
+
+	points := make(map[series][]metrics.Point)
+	for _, row := range rows {
+		key := series{SeriesKey: row.SeriesKey, MType: row.MType}
+		points[key] = append(points[key], row.Point)
+	}
+	for i, s := range list {
+		result[i] = points[s]
+	}
+	return result, nil
+}
+
+// Compact rolls up expired raw samples and per-minute rollups and deletes expired per-hour rollups.
+func (storage *PostgresStorage) Compact(ctx context.Context, now time.Time, policy repositories.RetentionPolicy) (stats repositories.CompactionStats, err error) {
+	tx, err := storage.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return stats, err
+	}
+	defer func() {
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				storage.logging.Warn("rollback transaction error", zap.Error(rollbackErr))
+			}
+		}
+	}()
+
+	if policy.RawTTL > 0 {
+		var written int64
+		err = tx.QueryRowxContext(ctx, compactRawQuery, now.Add(-policy.RawTTL), minuteResolution).
+			Scan(&stats.RawCompacted, &written)
+		if err != nil {
+			return stats, err
+		}
+		stats.RollupsWritten += written
+	}
+
+	if policy.MinuteTTL > 0 {
+		var written int64
+		err = tx.QueryRowxContext(ctx, compactMinuteQuery, now.Add(-policy.MinuteTTL), minuteResolution, hourResolution).
+			Scan(&stats.MinuteCompacted, &written)
+		if err != nil {
+			return stats, err
+		}
+		stats.RollupsWritten += written
+	}
+
+	if policy.HourTTL > 0 {
+		res, execErr := tx.ExecContext(ctx, compactHourQuery, now.Add(-policy.HourTTL), hourResolution)
+		if err = execErr; err != nil {
+			return stats, err
+		}
+		if stats.HourDeleted, err = res.RowsAffected(); err != nil {
+			return stats, err
+		}
+	}
+
+	exec := func() error {
+		return tx.Commit()
+	}
+
+	err = backoff.RetryWithBackoff(storage.backoffInteraval, IsTemporaryConnectionError, exec)
+	if err != nil {
+		err = fmt.Errorf("failed retries db request, %w", err)
+	}
+	return stats, err
+}
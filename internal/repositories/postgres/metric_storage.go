@@ -3,13 +3,16 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jmoiron/sqlx"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
 	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
 	"github.com/screamsoul/go-metrics-tpl/pkg/backoff"
 	"github.com/screamsoul/go-metrics-tpl/pkg/logging"
 	"github.com/screamsoul/go-metrics-tpl/pkg/utils"
@@ -30,12 +33,21 @@ func NewPostgresStorage(dataSourceName string, backoffInteraval []time.Duration)
 }
 
 func (storage *PostgresStorage) Add(ctx context.Context, metric metrics.Metrics) error {
+	if metric.MType == metrics.Histogram {
+		// histograms are merged with the stored value, which requires a transaction.
+		return storage.BulkAdd(ctx, []metrics.Metrics{metric})
+	}
+
 	stmt, err := storage.db.PrepareContext(ctx, `
-		INSERT INTO metrics (name, m_type, delta, value)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (name) DO UPDATE SET
-			delta = CASE WHEN metrics.m_type = 'counter' THEN metrics.delta + excluded.delta ELSE excluded.delta END,
-			value = excluded.value;
+		WITH sample AS (
+			INSERT INTO metric_samples (series_key, name, m_type, delta, value, labels)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		)
+		INSERT INTO metrics (series_key, name, m_type, delta, value, labels)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (series_key, m_type) DO UPDATE SET
+			delta = CASE WHEN metrics.m_type = 'counter' THEN metrics.delta + excluded.delta ELSE metrics.delta END,
+			value = CASE WHEN metrics.m_type = 'gauge' THEN excluded.value ELSE metrics.value END;
 	`)
 	if err != nil {
 		return err
@@ -43,7 +55,7 @@ func (storage *PostgresStorage) Add(ctx context.Context, metric metrics.Metrics)
 	defer utils.CloseForse(stmt)
 
 	exec := func() error {
-		_, err = stmt.ExecContext(ctx, metric.ID, metric.MType, metric.Delta, metric.Value)
+		_, err = stmt.ExecContext(ctx, metric.SeriesKey(), metric.ID, metric.MType, metric.Delta, metric.Value, metric.Labels)
 		return err
 	}
 
@@ -55,18 +67,19 @@ func (storage *PostgresStorage) Add(ctx context.Context, metric metrics.Metrics)
 }
 
 func (storage *PostgresStorage) Get(ctx context.Context, metric *metrics.Metrics) error {
-	query := `SELECT value, delta FROM metrics WHERE name = $1 AND m_type = $2`
+	query := `SELECT value, delta, histogram FROM metrics WHERE series_key = $1 AND m_type = $2`
 	var value sql.NullFloat64
 	var delta sql.NullInt64
+	var histogram *metrics.HistogramValue
 
-	row := storage.db.QueryRowContext(ctx, query, metric.ID, metric.MType)
+	row := storage.db.QueryRowContext(ctx, query, metric.SeriesKey(), metric.MType)
 	var err error
 
 	exec := func() error {
-		scanErr := row.Scan(&value, &delta)
+		scanErr := row.Scan(&value, &delta, &histogram)
 
 		if scanErr == sql.ErrNoRows {
-			return fmt.Errorf("metric with Name %s not found", metric.ID)
+			return fmt.Errorf("metric with Name %s: %w", metric.ID, repositories.ErrMetricNotFound)
 		}
 		return scanErr
 	}
@@ -86,14 +99,18 @@ func (storage *PostgresStorage) Get(ctx context.Context, metric *metrics.Metrics
 	if delta.Valid {
 		metric.Delta = &delta.Int64
 	}
+	if histogram != nil {
+		metric.Histogram = histogram
+	}
 
 	return nil
 }
 
-func (storage *PostgresStorage) List(ctx context.Context) (metricsList []metrics.Metrics, err error) {
-	query := `SELECT name, m_type, delta, value FROM metrics`
+// List returns the series matching the query, the filter, the order and the page are applied by the database.
+func (storage *PostgresStorage) List(ctx context.Context, query repositories.ListQuery) (metricsList []metrics.Metrics, err error) {
+	sqlQuery, args := buildListQuery(query)
 	exec := func() error {
-		return storage.db.SelectContext(ctx, &metricsList, query)
+		return storage.db.SelectContext(ctx, &metricsList, sqlQuery, args...)
 	}
 
 	err = backoff.RetryWithBackoff(storage.backoffInteraval, IsTemporaryConnectionError, exec)
@@ -104,6 +121,140 @@ func (storage *PostgresStorage) List(ctx context.Context) (metricsList []metrics
 	return
 }
 
+// buildListQuery builds the select of the series matching the query.
+//
+// Series keys and types are compared with the "C" collation, byte-wise like in the cursor,
+// a series is identified by the key together with the type.
+// The name prefix is matched with LIKE to use the text_pattern_ops index.
+func buildListQuery(query repositories.ListQuery) (string, []any) {
+	var (
+		conditions []string
+		args       []any
+	)
+	arg := func(value any) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if query.Type != "" {
+		conditions = append(conditions, "m_type = "+arg(query.Type))
+	}
+	if query.Prefix != "" {
+		conditions = append(conditions, `name LIKE `+arg(likePrefix(query.Prefix)))
+	}
+	if query.Regex != nil {
+		conditions = append(conditions, "name ~ "+arg(query.Regex.String()))
+	}
+
+	order, compare := "ASC", ">"
+	if query.Desc {
+		order, compare = "DESC", "<"
+	}
+	if query.After != nil {
+		conditions = append(conditions, `(series_key COLLATE "C", m_type::text COLLATE "C") `+compare+
+			" ("+arg(query.After.Key)+", "+arg(query.After.MType)+")")
+	}
+
+	var sqlQuery strings.Builder
+	sqlQuery.WriteString(`SELECT name, m_type, delta, value, histogram, labels FROM metrics`)
+	if len(conditions) > 0 {
+		sqlQuery.WriteString(" WHERE " + strings.Join(conditions, " AND "))
+	}
+	sqlQuery.WriteString(` ORDER BY series_key COLLATE "C" ` + order + `, m_type::text COLLATE "C" ` + order)
+	if query.Limit > 0 {
+		sqlQuery.WriteString(" LIMIT " + arg(query.Limit))
+	}
+
+	return sqlQuery.String(), args
+}
+
+// likePrefix returns the LIKE pattern matching strings starting with the prefix.
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+}
+
+// Delete removes the metric series together with its samples and rollups.
+func (storage *PostgresStorage) Delete(ctx context.Context, metric metrics.Metrics) error {
+	query := `
+		WITH deleted AS (
+			DELETE FROM metrics WHERE series_key = $1 AND m_type = $2 RETURNING series_key
+		), samples AS (
+			DELETE FROM metric_samples WHERE series_key = $1 AND m_type = $2
+		), rollups AS (
+			DELETE FROM metric_rollups WHERE series_key = $1 AND m_type = $2
+		)
+		SELECT count(*) FROM deleted
+	`
+	var deleted int64
+
+	exec := func() error {
+		return storage.db.QueryRowContext(ctx, query, metric.SeriesKey(), metric.MType).Scan(&deleted)
+	}
+
+	err := backoff.RetryWithBackoff(storage.backoffInteraval, IsTemporaryConnectionError, exec)
+	if err != nil {
+		return fmt.Errorf("failed retries db request, %w", err)
+	}
+
+	if deleted == 0 {
+		return repositories.ErrMetricNotFound
+	}
+	return nil
+}
+
+// DeleteByPrefix removes the series whose name starts with prefix together with their samples and rollups.
+func (storage *PostgresStorage) DeleteByPrefix(ctx context.Context, prefix string) (deleted int64, err error) {
+	query := `
+		WITH deleted AS (
+			DELETE FROM metrics WHERE starts_with(name, $1) RETURNING series_key
+		), samples AS (
+			DELETE FROM metric_samples WHERE starts_with(name, $1)
+		), rollups AS (
+			DELETE FROM metric_rollups WHERE starts_with(name, $1)
+		)
+		SELECT count(*) FROM deleted
+	`
+
+	exec := func() error {
+		return storage.db.QueryRowContext(ctx, query, prefix).Scan(&deleted)
+	}
+
+	err = backoff.RetryWithBackoff(storage.backoffInteraval, IsTemporaryConnectionError, exec)
+	if err != nil {
+		err = fmt.Errorf("failed retries db request, %w", err)
+	}
+	return
+}
+
+// Reset sets the value of the counter to zero, the history of deltas is kept.
+func (storage *PostgresStorage) Reset(ctx context.Context, metric metrics.Metrics) error {
+	if metric.MType != metrics.Counter {
+		return fmt.Errorf("reset is not available for metric type `%s`", metric.MType)
+	}
+
+	query := `UPDATE metrics SET delta = 0 WHERE series_key = $1 AND m_type = 'counter'`
+	var result sql.Result
+
+	exec := func() (err error) {
+		result, err = storage.db.ExecContext(ctx, query, metric.SeriesKey())
+		return err
+	}
+
+	err := backoff.RetryWithBackoff(storage.backoffInteraval, IsTemporaryConnectionError, exec)
+	if err != nil {
+		return fmt.Errorf("failed retries db request, %w", err)
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return repositories.ErrMetricNotFound
+	}
+	return nil
+}
+
 func (storage *PostgresStorage) Ping(ctx context.Context) bool {
 	err := storage.db.PingContext(ctx)
 	if err != nil {
@@ -120,6 +271,16 @@ func (storage *PostgresStorage) Close() {
 }
 
 func (storage *PostgresStorage) BulkAdd(ctx context.Context, metricList []metrics.Metrics) error {
+	return storage.bulkAdd(ctx, metricList, true)
+}
+
+// BulkAddCurrent writes the metrics without recording them in the history.
+func (storage *PostgresStorage) BulkAddCurrent(ctx context.Context, metricList []metrics.Metrics) error {
+	return storage.bulkAdd(ctx, metricList, false)
+}
+
+// bulkAdd writes the metrics in a transaction, with withHistory samples are recorded too.
+func (storage *PostgresStorage) bulkAdd(ctx context.Context, metricList []metrics.Metrics, withHistory bool) error {
 	tx, err := storage.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
@@ -131,17 +292,27 @@ func (storage *PostgresStorage) BulkAdd(ctx context.Context, metricList []metric
 	}()
 
 	stmt, err := tx.PreparexContext(ctx, `
-		INSERT INTO metrics (name, m_type, delta, value)
-		VALUES ($1, $2, $3, $4)
-		ON CONFLICT (name) DO UPDATE SET
+		INSERT INTO metrics (series_key, name, m_type, delta, value, histogram, labels)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (series_key, m_type) DO UPDATE SET
 			delta = CASE WHEN metrics.m_type = 'counter' THEN metrics.delta + excluded.delta ELSE metrics.delta END,
-			value = CASE WHEN metrics.m_type = 'gauge' THEN excluded.value ELSE metrics.value END;
+			value = CASE WHEN metrics.m_type = 'gauge' THEN excluded.value ELSE metrics.value END,
+			histogram = CASE WHEN metrics.m_type = 'histogram' THEN excluded.histogram ELSE metrics.histogram END;
 	`)
 	if err != nil {
 		return err
 	}
 	defer utils.CloseForse(stmt)
 
+	sampleStmt, err := tx.PreparexContext(ctx, `
+		INSERT INTO metric_samples (series_key, name, m_type, delta, value, labels)
+		VALUES ($1, $2, $3, $4, $5, $6);
+	`)
+	if err != nil {
+		return err
+	}
+	defer utils.CloseForse(sampleStmt)
+
 	for _, metric := range metricList {
 		var delta sql.NullInt64
 		var value sql.NullFloat64
@@ -156,10 +327,23 @@ func (storage *PostgresStorage) BulkAdd(ctx context.Context, metricList []metric
 			value.Valid = true
 		}
 
-		_, err = stmt.ExecContext(ctx, metric.ID, metric.MType, delta, value)
+		if metric.MType == metrics.Histogram {
+			if err = mergeStoredHistogram(ctx, tx, &metric); err != nil {
+				return err
+			}
+		}
+
+		_, err = stmt.ExecContext(ctx, metric.SeriesKey(), metric.ID, metric.MType, delta, value, metric.Histogram, metric.Labels)
 		if err != nil {
 			return err
 		}
+
+		if withHistory && metric.MType != metrics.Histogram {
+			_, err = sampleStmt.ExecContext(ctx, metric.SeriesKey(), metric.ID, metric.MType, delta, value, metric.Labels)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	exec := func() error {
@@ -172,3 +356,28 @@ func (storage *PostgresStorage) BulkAdd(ctx context.Context, metricList []metric
 	}
 	return err
 }
+
+// mergeStoredHistogram merges the stored histogram of the series into the metric, locking the row until commit.
+func mergeStoredHistogram(ctx context.Context, tx *sqlx.Tx, metric *metrics.Metrics) error {
+	var stored metrics.HistogramValue
+
+	err := tx.QueryRowxContext(
+		ctx,
+		`SELECT histogram FROM metrics WHERE series_key = $1 AND m_type = 'histogram' FOR UPDATE`,
+		metric.SeriesKey(),
+	).Scan(&stored)
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := stored.Merge(metric.Histogram); err != nil {
+		return fmt.Errorf("metric %s: %w", metric.SeriesKey(), err)
+	}
+	metric.Histogram = &stored
+
+	return nil
+}
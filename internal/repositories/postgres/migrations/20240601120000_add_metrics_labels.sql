@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE metrics ADD COLUMN series_key TEXT;
+ALTER TABLE metrics ADD COLUMN labels JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+UPDATE metrics SET series_key = name;
+
+ALTER TABLE metrics ALTER COLUMN series_key SET NOT NULL;
+ALTER TABLE metrics DROP CONSTRAINT metrics_pkey;
+ALTER TABLE metrics ADD PRIMARY KEY (series_key);
+
+CREATE INDEX IF NOT EXISTS metrics_name_idx ON metrics (name);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS metrics_name_idx;
+DELETE FROM metrics WHERE labels <> '{}'::jsonb;
+
+ALTER TABLE metrics DROP CONSTRAINT metrics_pkey;
+ALTER TABLE metrics ADD PRIMARY KEY (name);
+
+ALTER TABLE metrics DROP COLUMN labels;
+ALTER TABLE metrics DROP COLUMN series_key;
+-- +goose StatementEnd
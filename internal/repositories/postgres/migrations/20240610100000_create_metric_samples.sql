@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS metric_samples (
+    series_key TEXT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    m_type metric_type NOT NULL,
+    labels JSONB NOT NULL DEFAULT '{}'::jsonb,
+    delta BIGINT,
+    value DOUBLE PRECISION,
+    ts TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS metric_samples_series_ts_idx ON metric_samples (series_key, ts);
+CREATE INDEX IF NOT EXISTS metric_samples_ts_idx ON metric_samples (ts);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS metric_samples;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE IF NOT EXISTS metric_rollups (
+    series_key TEXT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    m_type metric_type NOT NULL,
+    labels JSONB NOT NULL DEFAULT '{}'::jsonb,
+    resolution INTEGER NOT NULL,
+    bucket TIMESTAMPTZ NOT NULL,
+    min DOUBLE PRECISION NOT NULL,
+    max DOUBLE PRECISION NOT NULL,
+    sum DOUBLE PRECISION NOT NULL,
+    count BIGINT NOT NULL,
+    last DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (series_key, resolution, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS metric_rollups_resolution_bucket_idx ON metric_rollups (resolution, bucket);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS metric_rollups;
+-- +goose StatementEnd
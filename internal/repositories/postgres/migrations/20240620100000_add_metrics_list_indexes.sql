@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the list is paginated by the series key compared byte-wise.
+CREATE INDEX IF NOT EXISTS metrics_series_key_c_idx ON metrics (series_key COLLATE "C");
+-- prefix filters on the name.
+CREATE INDEX IF NOT EXISTS metrics_name_pattern_idx ON metrics (name text_pattern_ops);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS metrics_name_pattern_idx;
+DROP INDEX IF EXISTS metrics_series_key_c_idx;
+-- +goose StatementEnd
@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- a gauge and a counter with the same series key are separate series.
+ALTER TABLE metric_rollups DROP CONSTRAINT metric_rollups_pkey;
+ALTER TABLE metric_rollups ADD PRIMARY KEY (series_key, m_type, resolution, bucket);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM metric_rollups r USING metric_rollups o
+WHERE r.series_key = o.series_key AND r.resolution = o.resolution AND r.bucket = o.bucket AND r.m_type > o.m_type;
+ALTER TABLE metric_rollups DROP CONSTRAINT metric_rollups_pkey;
+ALTER TABLE metric_rollups ADD PRIMARY KEY (series_key, resolution, bucket);
+-- +goose StatementEnd
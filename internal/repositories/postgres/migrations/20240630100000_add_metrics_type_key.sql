@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- a gauge and a counter with the same series key are separate series.
+ALTER TABLE metrics DROP CONSTRAINT metrics_pkey;
+ALTER TABLE metrics ADD PRIMARY KEY (series_key, m_type);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM metrics m USING metrics o
+WHERE m.series_key = o.series_key AND m.m_type > o.m_type;
+ALTER TABLE metrics DROP CONSTRAINT metrics_pkey;
+ALTER TABLE metrics ADD PRIMARY KEY (series_key);
+-- +goose StatementEnd
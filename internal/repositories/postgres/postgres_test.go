@@ -12,6 +12,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/jmoiron/sqlx"
 	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
 	"github.com/screamsoul/go-metrics-tpl/pkg/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -77,10 +78,10 @@ func (suite *PostgresStorageTestSuite) TestAdd() {
 	metric := getRandomMetric()
 
 	// Mock expected insert statement
-	// Expecting INSERT statement
-	suite.mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO metrics`))
+	// Expecting INSERT statement, the sample is written by the same statement
+	suite.mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO metric_samples`))
 	suite.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO metrics`)).
-		WithArgs(metric.ID, metric.MType, metric.Delta, metric.Value).
+		WithArgs(metric.SeriesKey(), metric.ID, metric.MType, metric.Delta, metric.Value, "{}").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// Execute the Add method
@@ -90,13 +91,26 @@ func (suite *PostgresStorageTestSuite) TestAdd() {
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
 }
 
+func (suite *PostgresStorageTestSuite) TestAddKeyedByType() {
+	metric := metrics.Metrics{ID: "requests", MType: metrics.Gauge, Value: new(float64)}
+
+	// a gauge never updates a counter with the same series key.
+	suite.mock.ExpectPrepare(regexp.QuoteMeta(`ON CONFLICT (series_key, m_type) DO UPDATE SET`))
+	suite.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO metrics`)).
+		WithArgs(metric.SeriesKey(), metric.ID, metric.MType, metric.Delta, metric.Value, "{}").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	assert.NoError(suite.T(), suite.storage.Add(context.Background(), metric))
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
 func (suite *PostgresStorageTestSuite) TestGet() {
-	rows := sqlmock.NewRows([]string{"value", "delta"}).AddRow(123.45, 6789)
+	rows := sqlmock.NewRows([]string{"value", "delta", "histogram"}).AddRow(123.45, 6789, nil)
 
 	metric := &metrics.Metrics{ID: "test_id", MType: metrics.Gauge}
 	suite.mock.
-		ExpectQuery(regexp.QuoteMeta(`SELECT value, delta`)).
-		WithArgs(metric.ID, metric.MType).
+		ExpectQuery(regexp.QuoteMeta(`SELECT value, delta, histogram`)).
+		WithArgs(metric.SeriesKey(), metric.MType).
 		WillReturnRows(rows)
 
 	err := suite.storage.Get(context.Background(), metric)
@@ -117,18 +131,18 @@ func (suite *PostgresStorageTestSuite) TestList() {
 		m = getRandomMetric()
 		metricsExpected[i] = m
 		valuesExpected[i] = []driver.Value{
-			m.ID, string(m.MType), m.Delta, m.Value,
+			m.ID, string(m.MType), m.Delta, m.Value, nil, "{}",
 		}
 	}
 
-	rows := sqlmock.NewRows([]string{"name", "m_type", "delta", "value"}).
+	rows := sqlmock.NewRows([]string{"name", "m_type", "delta", "value", "histogram", "labels"}).
 		AddRows(valuesExpected...)
 
 	suite.mock.
-		ExpectQuery(regexp.QuoteMeta(`SELECT name, m_type, delta, value`)).
+		ExpectQuery(regexp.QuoteMeta(`SELECT name, m_type, delta, value, histogram, labels`)).
 		WillReturnRows(rows)
 
-	metricsActual, err := suite.storage.List(context.Background())
+	metricsActual, err := suite.storage.List(context.Background(), repositories.ListQuery{})
 
 	require.NoError(suite.T(), err)
 
@@ -136,6 +150,31 @@ func (suite *PostgresStorageTestSuite) TestList() {
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
 }
 
+func (suite *PostgresStorageTestSuite) TestListQuery() {
+	query := repositories.ListQuery{
+		Type:   metrics.Gauge,
+		Prefix: "cpu_",
+		Regex:  regexp.MustCompile(`user$`),
+		Desc:   true,
+		Limit:  10,
+		After:  &repositories.Cursor{Key: `cpu_user{cpu="1"}`, MType: metrics.Gauge},
+	}
+
+	suite.mock.
+		ExpectQuery(regexp.QuoteMeta(
+			`SELECT name, m_type, delta, value, histogram, labels FROM metrics `+
+				`WHERE m_type = $1 AND name LIKE $2 AND name ~ $3 AND (series_key COLLATE "C", m_type::text COLLATE "C") < ($4, $5) `+
+				`ORDER BY series_key COLLATE "C" DESC, m_type::text COLLATE "C" DESC LIMIT $6`,
+		)).
+		WithArgs(metrics.Gauge, `cpu\_%`, `user$`, `cpu_user{cpu="1"}`, metrics.Gauge, 10).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "m_type", "delta", "value", "histogram", "labels"}))
+
+	metricsActual, err := suite.storage.List(context.Background(), query)
+	require.NoError(suite.T(), err)
+	assert.Empty(suite.T(), metricsActual)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
 func (suite *PostgresStorageTestSuite) TestPing() {
 	suite.mock.ExpectPing()
 
@@ -156,10 +195,20 @@ func (suite *PostgresStorageTestSuite) TestBulkAdd() {
 	}
 
 	suite.mock.ExpectBegin()
-	suite.mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO metrics (name, m_type, delta, value)`))
+	suite.mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO metrics (series_key, name, m_type, delta, value, histogram, labels)`))
+	suite.mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO metric_samples (series_key, name, m_type, delta, value, labels)`))
 	for i := 0; i < count; i++ {
-		suite.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO metrics (name, m_type, delta, value)`)).
-			WithArgs(metricsExpected[i].ID, metricsExpected[i].MType, metricsExpected[i].Delta, metricsExpected[i].Value).
+		suite.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO metrics (series_key, name, m_type, delta, value, histogram, labels)`)).
+			WithArgs(
+				metricsExpected[i].SeriesKey(), metricsExpected[i].ID, metricsExpected[i].MType,
+				metricsExpected[i].Delta, metricsExpected[i].Value, nil, metricsExpected[i].Labels,
+			).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		suite.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO metric_samples (series_key, name, m_type, delta, value, labels)`)).
+			WithArgs(
+				metricsExpected[i].SeriesKey(), metricsExpected[i].ID, metricsExpected[i].MType,
+				metricsExpected[i].Delta, metricsExpected[i].Value, metricsExpected[i].Labels,
+			).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 	}
 	suite.mock.ExpectCommit()
@@ -168,3 +217,241 @@ func (suite *PostgresStorageTestSuite) TestBulkAdd() {
 	require.NoError(suite.T(), err)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
 }
+
+func (suite *PostgresStorageTestSuite) TestBulkAddCurrent() {
+	metric := getRandomMetric()
+
+	suite.mock.ExpectBegin()
+	suite.mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO metrics`))
+	suite.mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO metric_samples`))
+	suite.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO metrics`)).
+		WithArgs(metric.SeriesKey(), metric.ID, metric.MType, metric.Delta, metric.Value, nil, metric.Labels).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	suite.mock.ExpectCommit()
+
+	// no sample is recorded.
+	err := suite.storage.BulkAddCurrent(context.Background(), []metrics.Metrics{metric})
+	require.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *PostgresStorageTestSuite) TestAddHistogram() {
+	bounds := []float64{1, 5}
+
+	added := metrics.NewHistogramValue(bounds)
+	added.Observe(3)
+	metric := metrics.Metrics{ID: "latency", MType: metrics.Histogram, Histogram: added}
+
+	stored := metrics.NewHistogramValue(bounds)
+	stored.Observe(0.5)
+	stored.Observe(10)
+	storedJSON, err := stored.Value()
+	require.NoError(suite.T(), err)
+
+	merged := stored.Clone()
+	require.NoError(suite.T(), merged.Merge(added))
+	mergedJSON, err := merged.Value()
+	require.NoError(suite.T(), err)
+
+	suite.mock.ExpectBegin()
+	suite.mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO metrics`))
+	suite.mock.ExpectPrepare(regexp.QuoteMeta(`INSERT INTO metric_samples`))
+	suite.mock.ExpectQuery(regexp.QuoteMeta(`SELECT histogram FROM metrics`)).
+		WithArgs(metric.SeriesKey()).
+		WillReturnRows(sqlmock.NewRows([]string{"histogram"}).AddRow(storedJSON))
+	suite.mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO metrics`)).
+		WithArgs(metric.SeriesKey(), metric.ID, metric.MType, nil, nil, mergedJSON, "{}").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	suite.mock.ExpectCommit()
+
+	err = suite.storage.Add(context.Background(), metric)
+	require.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+	assert.Equal(suite.T(), uint64(1), added.Count)
+}
+
+func (suite *PostgresStorageTestSuite) TestHistory() {
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	testCases := []struct {
+		name  string
+		query repositories.HistoryQuery
+		sql   string
+		args  []driver.Value
+	}{
+		{
+			name: "raw samples",
+			query: repositories.HistoryQuery{
+				Metric: metrics.Metrics{ID: "Alloc", MType: metrics.Gauge}, From: from, To: to,
+			},
+			sql:  `SELECT ts, value FROM points ORDER BY ts`,
+			args: []driver.Value{"Alloc", "gauge", from, to},
+		},
+		{
+			name: "gauge step",
+			query: repositories.HistoryQuery{
+				Metric: metrics.Metrics{ID: "Alloc", MType: metrics.Gauge}, From: from, To: to, Step: time.Minute,
+			},
+			sql:  `(array_agg(value ORDER BY ts DESC))[1] AS value`,
+			args: []driver.Value{"Alloc", "gauge", from, to, float64(60)},
+		},
+		{
+			name: "counter step",
+			query: repositories.HistoryQuery{
+				Metric: metrics.Metrics{ID: "PollCount", MType: metrics.Counter, Labels: metrics.Labels{"host": "a"}},
+				From:   from, To: to, Step: time.Minute,
+			},
+			sql:  `SUM(value) AS value`,
+			args: []driver.Value{`PollCount{host="a"}`, "counter", from, to, float64(60)},
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			rows := sqlmock.NewRows([]string{"ts", "value"}).
+				AddRow(from, 1.5).
+				AddRow(from.Add(time.Minute), 2.5)
+
+			suite.mock.ExpectQuery(regexp.QuoteMeta(tc.sql)).WithArgs(tc.args...).WillReturnRows(rows)
+
+			points, err := suite.storage.History(context.Background(), tc.query)
+			require.NoError(suite.T(), err)
+			assert.Equal(suite.T(), []metrics.Point{
+				{Timestamp: from, Value: 1.5},
+				{Timestamp: from.Add(time.Minute), Value: 2.5},
+			}, points)
+			assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+		})
+	}
+
+	_, err := suite.storage.History(context.Background(), repositories.HistoryQuery{
+		Metric: metrics.Metrics{ID: "latency", MType: metrics.Histogram}, Step: time.Minute,
+	})
+	assert.Error(suite.T(), err)
+}
+
+func (suite *PostgresStorageTestSuite) TestHistoryBatch() {
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	query := repositories.HistoryBatchQuery{
+		Metrics: []metrics.Metrics{
+			{ID: "Alloc", MType: metrics.Gauge},
+			{ID: "PollCount", MType: metrics.Counter, Labels: metrics.Labels{"host": "a"}},
+			{ID: "Missing", MType: metrics.Gauge},
+		},
+		From: from, To: to, Step: time.Minute,
+	}
+
+	series := `[{"series_key":"Alloc","m_type":"gauge"},` +
+		`{"series_key":"PollCount{host=\"a\"}","m_type":"counter"},` +
+		`{"series_key":"Missing","m_type":"gauge"}]`
+
+	rows := sqlmock.NewRows([]string{"series_key", "m_type", "ts", "value"}).
+		AddRow("Alloc", "gauge", from, 1.5).
+		AddRow("Alloc", "gauge", from.Add(time.Minute), 2.5).
+		AddRow(`PollCount{host="a"}`, "counter", from, 3.0)
+
+	suite.mock.ExpectQuery(regexp.QuoteMeta(`FROM jsonb_to_recordset($1::jsonb)`)).
+		WithArgs(series, from, to, float64(60)).
+		WillReturnRows(rows)
+
+	points, err := suite.storage.HistoryBatch(context.Background(), query)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), [][]metrics.Point{
+		{{Timestamp: from, Value: 1.5}, {Timestamp: from.Add(time.Minute), Value: 2.5}},
+		{{Timestamp: from, Value: 3}},
+		nil,
+	}, points)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+
+	_, err = suite.storage.HistoryBatch(context.Background(), repositories.HistoryBatchQuery{
+		Metrics: []metrics.Metrics{{ID: "latency", MType: metrics.Histogram}},
+	})
+	assert.Error(suite.T(), err)
+}
+
+func (suite *PostgresStorageTestSuite) TestCompact() {
+	now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+	policy := repositories.RetentionPolicy{RawTTL: time.Hour, MinuteTTL: 24 * time.Hour, HourTTL: 30 * 24 * time.Hour}
+
+	suite.mock.ExpectBegin()
+	suite.mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM metric_samples WHERE ts < $1`)).
+		WithArgs(now.Add(-policy.RawTTL), minuteResolution).
+		WillReturnRows(sqlmock.NewRows([]string{"moved", "written"}).AddRow(120, 4))
+	suite.mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM metric_rollups WHERE resolution = $2 AND bucket < $1`)).
+		WithArgs(now.Add(-policy.MinuteTTL), minuteResolution, hourResolution).
+		WillReturnRows(sqlmock.NewRows([]string{"moved", "written"}).AddRow(60, 1))
+	suite.mock.ExpectExec(regexp.QuoteMeta(compactHourQuery)).
+		WithArgs(now.Add(-policy.HourTTL), hourResolution).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	suite.mock.ExpectCommit()
+
+	stats, err := suite.storage.Compact(context.Background(), now, policy)
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), repositories.CompactionStats{
+		RawCompacted: 120, MinuteCompacted: 60, HourDeleted: 3, RollupsWritten: 5,
+	}, stats)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+
+	// rollups of a gauge and a counter with the same series key do not conflict.
+	for _, query := range []string{compactRawQuery, compactMinuteQuery} {
+		assert.Contains(suite.T(), query, "ON CONFLICT (series_key, m_type, resolution, bucket)")
+	}
+}
+
+func (suite *PostgresStorageTestSuite) TestCompactRollback() {
+	now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	suite.mock.ExpectBegin()
+	suite.mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM metric_samples WHERE ts < $1`)).
+		WillReturnError(fmt.Errorf("some err"))
+	suite.mock.ExpectRollback()
+
+	_, err := suite.storage.Compact(context.Background(), now, repositories.RetentionPolicy{RawTTL: time.Hour})
+	require.Error(suite.T(), err)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *PostgresStorageTestSuite) TestDelete() {
+	metric := metrics.Metrics{ID: "test_id", MType: metrics.Gauge, Labels: metrics.Labels{"host": "a"}}
+
+	suite.mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM metrics WHERE series_key = $1 AND m_type = $2`)).
+		WithArgs(metric.SeriesKey(), metric.MType).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	suite.mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM metrics WHERE series_key = $1 AND m_type = $2`)).
+		WithArgs(metric.SeriesKey(), metric.MType).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	require.NoError(suite.T(), suite.storage.Delete(context.Background(), metric))
+	assert.ErrorIs(suite.T(), suite.storage.Delete(context.Background(), metric), repositories.ErrMetricNotFound)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *PostgresStorageTestSuite) TestDeleteByPrefix() {
+	suite.mock.ExpectQuery(regexp.QuoteMeta(`DELETE FROM metrics WHERE starts_with(name, $1)`)).
+		WithArgs("cpu_").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	deleted, err := suite.storage.DeleteByPrefix(context.Background(), "cpu_")
+	require.NoError(suite.T(), err)
+	assert.Equal(suite.T(), int64(3), deleted)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *PostgresStorageTestSuite) TestReset() {
+	metric := metrics.Metrics{ID: "PollCount", MType: metrics.Counter}
+
+	suite.mock.ExpectExec(regexp.QuoteMeta(`UPDATE metrics SET delta = 0`)).
+		WithArgs(metric.SeriesKey()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	suite.mock.ExpectExec(regexp.QuoteMeta(`UPDATE metrics SET delta = 0`)).
+		WithArgs(metric.SeriesKey()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(suite.T(), suite.storage.Reset(context.Background(), metric))
+	assert.ErrorIs(suite.T(), suite.storage.Reset(context.Background(), metric), repositories.ErrMetricNotFound)
+	assert.Error(suite.T(), suite.storage.Reset(context.Background(), metrics.Metrics{ID: "Alloc", MType: metrics.Gauge}))
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
@@ -0,0 +1,2 @@
+// Module with a background worker applying the retention policy to the stored metric history.
+package retention
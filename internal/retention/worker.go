@@ -0,0 +1,92 @@
+package retention
+
+import (
+	"context"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"github.com/screamsoul/go-metrics-tpl/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// Names of the metrics describing the compaction results.
+const (
+	RawCompactedMetric    = "RetentionRawCompacted"
+	MinuteCompactedMetric = "RetentionMinuteCompacted"
+	HourDeletedMetric     = "RetentionHourDeleted"
+	RollupsWrittenMetric  = "RetentionRollupsWritten"
+	DurationMetric        = "RetentionDurationSeconds"
+)
+
+// Worker periodically compacts the metric history and reports how much was compacted.
+type Worker struct {
+	history  repositories.RetentionStorage
+	store    repositories.MetricStorage
+	policy   repositories.RetentionPolicy
+	interval time.Duration
+	logger   *zap.Logger
+	now      func() time.Time
+}
+
+func NewWorker(
+	history repositories.RetentionStorage,
+	store repositories.MetricStorage,
+	policy repositories.RetentionPolicy,
+	interval time.Duration,
+) *Worker {
+	return &Worker{
+		history:  history,
+		store:    store,
+		policy:   policy,
+		interval: interval,
+		logger:   logging.GetLogger(),
+		now:      time.Now,
+	}
+}
+
+// Run compacts the history every interval until the context is done.
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.RunOnce(ctx); err != nil {
+				w.logger.Error("history compaction error", zap.Error(err))
+			}
+		}
+	}
+}
+
+// RunOnce compacts the history and writes the compaction metrics to the store,
+// they are not recorded in the history, so the worker does not compact its own samples.
+func (w *Worker) RunOnce(ctx context.Context) error {
+	start := w.now()
+
+	stats, err := w.history.Compact(ctx, start, w.policy)
+	if err != nil {
+		return err
+	}
+
+	duration := w.now().Sub(start).Seconds()
+
+	w.logger.Info("history compacted",
+		zap.Int64("raw_compacted", stats.RawCompacted),
+		zap.Int64("minute_compacted", stats.MinuteCompacted),
+		zap.Int64("hour_deleted", stats.HourDeleted),
+		zap.Int64("rollups_written", stats.RollupsWritten),
+		zap.Float64("duration", duration),
+	)
+
+	return repositories.BulkAddCurrent(ctx, w.store, []metrics.Metrics{
+		{ID: RawCompactedMetric, MType: metrics.Counter, Delta: &stats.RawCompacted},
+		{ID: MinuteCompactedMetric, MType: metrics.Counter, Delta: &stats.MinuteCompacted},
+		{ID: HourDeletedMetric, MType: metrics.Counter, Delta: &stats.HourDeleted},
+		{ID: RollupsWrittenMetric, MType: metrics.Counter, Delta: &stats.RollupsWritten},
+		{ID: DurationMetric, MType: metrics.Gauge, Value: &duration},
+	})
+}
@@ -0,0 +1,113 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type compactFunc func(ctx context.Context, now time.Time, policy repositories.RetentionPolicy) (repositories.CompactionStats, error)
+
+func (f compactFunc) Compact(ctx context.Context, now time.Time, policy repositories.RetentionPolicy) (repositories.CompactionStats, error) {
+	return f(ctx, now, policy)
+}
+
+func TestWorkerRunOnce(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemStorage()
+	policy := repositories.RetentionPolicy{RawTTL: time.Hour}
+	now := time.Date(2024, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	var gotPolicy repositories.RetentionPolicy
+	var gotNow time.Time
+	history := compactFunc(func(ctx context.Context, now time.Time, policy repositories.RetentionPolicy) (repositories.CompactionStats, error) {
+		gotNow, gotPolicy = now, policy
+		return repositories.CompactionStats{RawCompacted: 10, RollupsWritten: 2}, nil
+	})
+
+	worker := NewWorker(history, store, policy, time.Minute)
+	worker.now = func() time.Time { return now }
+
+	require.NoError(t, worker.RunOnce(ctx))
+	require.NoError(t, worker.RunOnce(ctx))
+
+	assert.Equal(t, policy, gotPolicy)
+	assert.Equal(t, now, gotNow)
+
+	m := metrics.Metrics{ID: RawCompactedMetric, MType: metrics.Counter}
+	require.NoError(t, store.Get(ctx, &m))
+	assert.Equal(t, int64(20), *m.Delta)
+
+	m = metrics.Metrics{ID: RollupsWrittenMetric, MType: metrics.Counter}
+	require.NoError(t, store.Get(ctx, &m))
+	assert.Equal(t, int64(4), *m.Delta)
+}
+
+func TestWorkerRunOnceSkipsHistory(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewHistoryMemStorage()
+	history := compactFunc(func(ctx context.Context, now time.Time, policy repositories.RetentionPolicy) (repositories.CompactionStats, error) {
+		return repositories.CompactionStats{RawCompacted: 10}, nil
+	})
+
+	worker := NewWorker(history, store, repositories.RetentionPolicy{}, time.Minute)
+	require.NoError(t, worker.RunOnce(ctx))
+
+	m := metrics.Metrics{ID: RawCompactedMetric, MType: metrics.Counter}
+	require.NoError(t, store.Get(ctx, &m))
+	assert.Equal(t, int64(10), *m.Delta)
+
+	// the compaction metrics are not recorded in the history they compact.
+	points, err := store.History(ctx, repositories.HistoryQuery{Metric: m, To: time.Now().Add(time.Minute)})
+	require.NoError(t, err)
+	assert.Empty(t, points)
+}
+
+func TestWorkerRunOnceError(t *testing.T) {
+	store := memory.NewMemStorage()
+	history := compactFunc(func(ctx context.Context, now time.Time, policy repositories.RetentionPolicy) (repositories.CompactionStats, error) {
+		return repositories.CompactionStats{}, errors.New("some err")
+	})
+
+	worker := NewWorker(history, store, repositories.RetentionPolicy{}, time.Minute)
+
+	assert.Error(t, worker.RunOnce(context.Background()))
+
+	list, err := store.List(context.Background(), repositories.ListQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestWorkerRun(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := make(chan struct{}, 10)
+	history := compactFunc(func(ctx context.Context, now time.Time, policy repositories.RetentionPolicy) (repositories.CompactionStats, error) {
+		calls <- struct{}{}
+		return repositories.CompactionStats{}, nil
+	})
+
+	worker := NewWorker(history, memory.NewMemStorage(), repositories.RetentionPolicy{}, 10*time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		worker.Run(ctx)
+		close(done)
+	}()
+
+	<-calls
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop after context cancel")
+	}
+}
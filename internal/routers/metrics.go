@@ -7,10 +7,28 @@ import (
 	"github.com/screamsoul/go-metrics-tpl/internal/handlers"
 )
 
+// Guards middlewares of route groups, they run after the middlewares of all routes.
+type Guards struct {
+	// Ingest guards the agent update routes: /update/, /updates/ and /update/{type}/{name}/{value}.
+	Ingest []func(http.Handler) http.Handler
+	// Admin guards the routes removing stored data: DELETE /value/, DELETE /value/{type}/{name}
+	// and POST /reset/{type}/{name}.
+	Admin []func(http.Handler) http.Handler
+}
+
 func NewMetricRouter(
 	mServer *handlers.MetricServer,
 	middlewares ...func(http.Handler) http.Handler,
 ) chi.Router {
+	return NewGuardedMetricRouter(mServer, Guards{}, middlewares...)
+}
+
+// NewGuardedMetricRouter creates the metric router with the route groups wrapped in the guards.
+func NewGuardedMetricRouter(
+	mServer *handlers.MetricServer,
+	guards Guards,
+	middlewares ...func(http.Handler) http.Handler,
+) chi.Router {
 
 	r := chi.NewRouter()
 
@@ -20,9 +38,24 @@ func NewMetricRouter(
 	r.Get("/ping", mServer.PingStorage)
 	r.Post("/value/", mServer.GetMetricJSON)
 	r.Get("/value/{metric_type}/{metric_name}", mServer.GetMetricValue)
-	r.Post("/update/", mServer.UpdateMetric)
-	r.Post("/updates/", mServer.UpdateMetricBulk)
-	r.Post("/update/{metric_type}/{metric_name}/{metric_value}", mServer.UpdateMetric)
+	r.Get("/history/{metric_type}/{metric_name}", mServer.GetMetricHistory)
+	r.Post("/write", mServer.WriteInflux)
+
+	r.Group(func(r chi.Router) {
+		r.Use(guards.Ingest...)
+
+		r.Post("/update/", mServer.UpdateMetric)
+		r.Post("/updates/", mServer.UpdateMetricBulk)
+		r.Post("/update/{metric_type}/{metric_name}/{metric_value}", mServer.UpdateMetric)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(guards.Admin...)
+
+		r.Delete("/value/", mServer.DeleteMetricsByPrefix)
+		r.Delete("/value/{metric_type}/{metric_name}", mServer.DeleteMetric)
+		r.Post("/reset/{metric_type}/{metric_name}", mServer.ResetMetric)
+	})
 
 	return r
 }
@@ -2,35 +2,81 @@ package server
 
 import (
 	"context"
+	"crypto/rsa"
+	"errors"
+	"net"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
+	"github.com/screamsoul/go-metrics-tpl/internal/graphite"
+	"github.com/screamsoul/go-metrics-tpl/internal/grpcserver"
 	"github.com/screamsoul/go-metrics-tpl/internal/handlers"
 	"github.com/screamsoul/go-metrics-tpl/internal/middlewares"
+	"github.com/screamsoul/go-metrics-tpl/internal/otlp"
 	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
 	"github.com/screamsoul/go-metrics-tpl/internal/repositories/file"
 	"github.com/screamsoul/go-metrics-tpl/internal/repositories/memory"
 	"github.com/screamsoul/go-metrics-tpl/internal/repositories/postgres"
+	"github.com/screamsoul/go-metrics-tpl/internal/retention"
 	"github.com/screamsoul/go-metrics-tpl/internal/routers"
+	"github.com/screamsoul/go-metrics-tpl/internal/statsd"
+	"github.com/screamsoul/go-metrics-tpl/pkg/encryption"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/encoding/gzip"
 )
 
-// Start starts the server
-func Start(ctx context.Context, cfg *Config, logger *zap.Logger) {
+// Start starts the server and blocks until the context is done or the server fails.
+//
+// On shutdown the server stops accepting connections and drains in-flight requests
+// within cfg.ShutdownTimeout, then stops background workers, writes the final snapshot
+// to disk and closes the database connection pool.
+//
+// SIGHUP or POST /admin/reload re-reads the configuration, see Reloader. Admin routes are
+// guarded by middlewares.NewAdminMiddleware.
+func Start(ctx context.Context, cfg *Config, logger *zap.Logger) error {
+	// Context of background workers, stopped after the http server drains.
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	var workers sync.WaitGroup
+
+	// the grpc server receives metrics unencrypted, so it would bypass the required encryption.
+	if cfg.GRPCAddress != "" && cfg.CryptoKey != "" {
+		return errors.New("crypto key is not supported by the grpc server")
+	}
+
+	var privateKey *rsa.PrivateKey
+	if cfg.CryptoKey != "" {
+		var err error
+		if privateKey, err = encryption.LoadPrivateKey(cfg.CryptoKey); err != nil {
+			return err
+		}
+	}
+
+	trustedSubnetMiddleware, err := middlewares.NewTrustedSubnetMiddleware(cfg.TrustedSubnet)
+	if err != nil {
+		return err
+	}
+
 	// Create MetricStorage.
 	var mStorage repositories.MetricStorage
 
 	if cfg.DatabaseDSN == "" {
 		// if no connection to the database is specified, the in-memory storage will be used.
 
-		memS := memory.NewMemStorage()
+		memS := memory.NewHistoryMemStorage()
 		mStorage = memS
 	} else {
 		postgresS := postgres.NewPostgresStorage(cfg.DatabaseDSN, cfg.BackoffIntervals)
 		defer postgresS.Close()
 
 		if err := postgresS.Bootstrap(ctx); err != nil {
-			panic(err)
+			return err
 		}
 
 		mStorage = postgresS
@@ -38,37 +84,195 @@ func Start(ctx context.Context, cfg *Config, logger *zap.Logger) {
 
 	// Create restore wrapper.
 	mStorageRestore := file.NewFileRestoreMetricWrapper(
-		ctx,
+		workersCtx,
 		mStorage,
 		cfg.FileStoragePath,
 		cfg.StoreInterval,
 		cfg.Restore,
 	)
 
-	if mStorageRestore.IsActiveRestore {
-		defer mStorageRestore.Save(ctx)
+	// Start the history retention worker.
+	if historyStorage, ok := mStorage.(repositories.RetentionStorage); ok && cfg.RetentionInterval > 0 {
+		retentionWorker := retention.NewWorker(
+			historyStorage,
+			mStorageRestore,
+			cfg.Retention.Policy(),
+			cfg.RetentionInterval,
+		)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			retentionWorker.Run(workersCtx)
+		}()
+		logger.Info("start history retention worker", zap.Duration("interval", cfg.RetentionInterval))
+	}
+
+	hashKey := middlewares.NewHashKey(cfg.HashBodyKey)
+
+	adminMiddleware, err := middlewares.NewAdminMiddleware(hashKey, cfg.TrustedSubnet, cfg.TrustedProxy)
+	if err != nil {
+		return err
 	}
 
+	reloader := NewReloader(NewConfig, hashKey, mStorageRestore)
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		for {
+			select {
+			case <-workersCtx.Done():
+				return
+			case <-hup:
+				logger.Info("reload config on SIGHUP")
+				_ = reloader.Reload()
+			}
+		}
+	}()
+
 	var metricServer = handlers.NewMetricServer(
 		mStorageRestore,
 	)
 
-	var router = routers.NewMetricRouter(
+	var router = routers.NewGuardedMetricRouter(
 		metricServer,
+		routers.Guards{
+			Ingest: []func(http.Handler) http.Handler{
+				trustedSubnetMiddleware,
+				middlewares.NewRequireEncryptionMiddleware(privateKey),
+			},
+			Admin: []func(http.Handler) http.Handler{adminMiddleware},
+		},
 		middlewares.LoggingMiddleware,
-		middlewares.NewHashSumHeaderMiddleware(cfg.HashBodyKey),
+		middlewares.NewDecryptMiddleware(privateKey),
+		middlewares.NewReloadableHashSumHeaderMiddleware(hashKey),
 		middlewares.GzipDecompressMiddleware,
 		middlewares.GzipCompressMiddleware,
 	)
 
+	router.With(adminMiddleware).Post("/admin/reload", reloader.ReloadHandler)
+	router.Post("/v1/metrics", otlp.NewReceiver(mStorageRestore).MetricsHandler)
+
+	if cfg.PrometheusPath != "" {
+		router.Get(cfg.PrometheusPath, metricServer.PrometheusMetrics)
+		logger.Info("mount prometheus metrics", zap.String("path", cfg.PrometheusPath))
+	}
+
 	if cfg.Debug {
 		router.Mount("/debug", http.DefaultServeMux)
 		logger.Info("mount debug pprof")
 	}
 
-	logger.Info("starting server", zap.String("ListenAddress", cfg.ListenAddress))
+	srv := &http.Server{
+		Addr:    cfg.ListenAddress,
+		Handler: router,
+	}
 
-	if err := http.ListenAndServe(cfg.ListenAddress, router); err != nil {
-		panic(err)
+	var grpcServer *grpc.Server
+	if cfg.GRPCAddress != "" {
+		if grpcServer, err = grpcserver.NewServer(mStorageRestore, hashKey, cfg.TrustedSubnet); err != nil {
+			return err
+		}
 	}
+
+	if cfg.StatsD.Enabled() {
+		statsdServer := statsd.NewServer(mStorageRestore, cfg.StatsdFlushInterval)
+		if err := statsdServer.Listen(cfg.StatsdUDPAddress, cfg.StatsdTCPAddress); err != nil {
+			return err
+		}
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			statsdServer.Run(workersCtx)
+		}()
+		logger.Info(
+			"start statsd listener",
+			zap.String("udp", cfg.StatsdUDPAddress),
+			zap.String("tcp", cfg.StatsdTCPAddress),
+			zap.Duration("flush", cfg.StatsdFlushInterval),
+		)
+	}
+
+	if cfg.GraphiteAddress != "" {
+		templates, err := graphite.ParseTemplates(cfg.GraphiteTemplates)
+		if err != nil {
+			return err
+		}
+		graphiteServer := graphite.NewServer(mStorageRestore, templates, cfg.GraphiteMaxConnections, cfg.GraphiteIdleTimeout)
+		if err := graphiteServer.Listen(cfg.GraphiteAddress); err != nil {
+			return err
+		}
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			graphiteServer.Run(workersCtx)
+		}()
+		logger.Info("start graphite listener", zap.String("address", cfg.GraphiteAddress))
+	}
+
+	// one slot per server, so that a failed server never blocks.
+	serverErr := make(chan error, 2)
+	go func() {
+		logger.Info("starting server", zap.String("ListenAddress", cfg.ListenAddress))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	if grpcServer != nil {
+		go func() {
+			logger.Info("starting grpc server", zap.String("GRPCAddress", cfg.GRPCAddress))
+			listener, err := net.Listen("tcp", cfg.GRPCAddress)
+			if err == nil {
+				err = grpcServer.Serve(listener)
+			}
+			if err != nil {
+				serverErr <- err
+			}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+		logger.Info("shutting down server", zap.Duration("timeout", cfg.ShutdownTimeout))
+	case err = <-serverErr:
+		logger.Error("server error", zap.Error(err))
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if shutdownErr := srv.Shutdown(shutdownCtx); shutdownErr != nil {
+		logger.Error("server shutdown error", zap.Error(shutdownErr))
+	}
+
+	if grpcServer != nil {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-shutdownCtx.Done():
+			logger.Error("grpc server shutdown error", zap.Error(shutdownCtx.Err()))
+			grpcServer.Stop()
+		}
+	}
+
+	stopWorkers()
+	workers.Wait()
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancelFlush()
+
+	mStorageRestore.Shutdown(flushCtx)
+
+	logger.Info("server stopped")
+
+	return err
 }
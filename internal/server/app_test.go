@@ -2,10 +2,27 @@ package server_test
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/screamsoul/go-metrics-tpl/internal/middlewares"
+	"github.com/screamsoul/go-metrics-tpl/internal/pb"
 	"github.com/screamsoul/go-metrics-tpl/internal/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 func TestStart_InitializesInMemoryStorage(t *testing.T) {
@@ -26,3 +43,284 @@ func TestStart_InitializesInMemoryStorage(t *testing.T) {
 	go server.Start(ctx, cfg, logger)
 	cancel()
 }
+
+func TestStart_GracefulShutdownWritesSnapshot(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	storagePath := filepath.Join(t.TempDir(), "metrics-db.json")
+
+	cfg := &server.Config{
+		FileStoragePath: storagePath,
+		StoreInterval:   300,
+		ListenAddress:   "localhost:0",
+		ShutdownTimeout: time.Second,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Start(ctx, cfg, zap.NewNop())
+	}()
+
+	cancel()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after context cancel")
+	}
+
+	data, err := os.ReadFile(storagePath)
+	require.NoError(t, err)
+	assert.JSONEq(t, "[]", string(data))
+}
+
+func TestStart_ReturnsListenError(t *testing.T) {
+	cfg := &server.Config{
+		ListenAddress:   "bad-address",
+		ShutdownTimeout: time.Second,
+	}
+
+	err := server.Start(context.Background(), cfg, zap.NewNop())
+	assert.Error(t, err)
+}
+
+func TestStart_ServesGRPC(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	grpcAddress := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	cfg := &server.Config{
+		ListenAddress:   "localhost:0",
+		GRPCAddress:     grpcAddress,
+		ShutdownTimeout: time.Second,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Start(ctx, cfg, zap.NewNop())
+	}()
+
+	conn, err := grpc.NewClient(grpcAddress, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, conn.Close())
+	}()
+
+	client := pb.NewMetricsClient(conn)
+	assert.Eventually(t, func() bool {
+		_, err := client.ListMetrics(ctx, &pb.ListMetricsRequest{})
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after context cancel")
+	}
+}
+
+func TestStart_TrustedSubnetGuardsUpdatesOnly(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	cfg := &server.Config{
+		ListenAddress:   address,
+		TrustedSubnet:   []string{"10.0.0.0/8"},
+		ShutdownTimeout: time.Second,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Start(ctx, cfg, zap.NewNop())
+	}()
+
+	update := func(realIP string) int {
+		req, err := http.NewRequest(http.MethodPost, "http://"+address+"/update/",
+			strings.NewReader(`{"id":"requests","type":"counter","delta":1}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if realIP != "" {
+			req.Header.Set("X-Real-IP", realIP)
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	// pages and scrapers send no X-Real-IP.
+	assert.Eventually(t, func() bool {
+		res, err := http.Get("http://" + address + "/")
+		if err != nil {
+			return false
+		}
+		res.Body.Close()
+		return res.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, http.StatusForbidden, update(""))
+	assert.Equal(t, http.StatusForbidden, update("192.168.1.1"))
+	assert.Equal(t, http.StatusOK, update("10.1.2.3"))
+
+	cancel()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after context cancel")
+	}
+}
+
+func TestStart_AdminRoutesRequireSignature(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	cfg := &server.Config{
+		ListenAddress:   address,
+		HashBodyKey:     "testKey",
+		ShutdownTimeout: time.Second,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Start(ctx, cfg, zap.NewNop())
+	}()
+
+	del := func(signedAt time.Time, key string) int {
+		req, err := http.NewRequest(http.MethodDelete, "http://"+address+"/value/gauge/missing", nil)
+		require.NoError(t, err)
+		if key != "" {
+			timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+			req.Header.Set(middlewares.AdminTimestampHeader, timestamp)
+			req.Header.Set(middlewares.AdminSignatureHeader, middlewares.AdminSignature(
+				http.MethodDelete, "/value/gauge/missing", timestamp, nil, key,
+			))
+		}
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	assert.Eventually(t, func() bool {
+		res, err := http.Get("http://" + address + "/")
+		if err != nil {
+			return false
+		}
+		res.Body.Close()
+		return res.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, http.StatusForbidden, del(time.Now(), ""))
+	assert.Equal(t, http.StatusForbidden, del(time.Now(), "otherKey"))
+	assert.Equal(t, http.StatusForbidden, del(time.Now().Add(-time.Hour), "testKey"))
+	assert.Equal(t, http.StatusNotFound, del(time.Now(), "testKey"))
+
+	cancel()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after context cancel")
+	}
+}
+
+func TestStart_ReloadRequiresFreshSignature(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	configPath := filepath.Join(t.TempDir(), "server.json")
+	require.NoError(t, os.WriteFile(configPath, []byte(`{"key": "testKey"}`), 0o600))
+	os.Args = nil
+	t.Setenv("CONFIG", configPath)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	cfg := &server.Config{
+		ListenAddress:   address,
+		HashBodyKey:     "testKey",
+		ShutdownTimeout: time.Second,
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Start(ctx, cfg, zap.NewNop())
+	}()
+
+	reload := func(signedAt time.Time, method, uri string) int {
+		timestamp := strconv.FormatInt(signedAt.Unix(), 10)
+		req, err := http.NewRequest(http.MethodPost, "http://"+address+"/admin/reload", nil)
+		require.NoError(t, err)
+		req.Header.Set(middlewares.AdminTimestampHeader, timestamp)
+		req.Header.Set(middlewares.AdminSignatureHeader, middlewares.AdminSignature(method, uri, timestamp, nil, "testKey"))
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	assert.Eventually(t, func() bool {
+		res, err := http.Get("http://" + address + "/")
+		if err != nil {
+			return false
+		}
+		res.Body.Close()
+		return res.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// a captured request is not accepted later or on another admin route.
+	assert.Equal(t, http.StatusForbidden, reload(time.Now().Add(-time.Hour), http.MethodPost, "/admin/reload"))
+	assert.Equal(t, http.StatusForbidden, reload(time.Now(), http.MethodDelete, "/value/"))
+	assert.Equal(t, http.StatusOK, reload(time.Now(), http.MethodPost, "/admin/reload"))
+
+	cancel()
+
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after context cancel")
+	}
+}
+
+func TestStart_RefusesGRPCWithCryptoKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keyPath := filepath.Join(t.TempDir(), "private.pem")
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}), 0o600))
+
+	// the server would stop at once without the check.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	cfg := &server.Config{
+		ListenAddress:   "127.0.0.1:0",
+		GRPCAddress:     "127.0.0.1:0",
+		CryptoKey:       keyPath,
+		ShutdownTimeout: time.Second,
+	}
+
+	assert.Error(t, server.Start(ctx, cfg, zap.NewNop()))
+}
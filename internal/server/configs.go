@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/alexflint/go-arg"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"github.com/screamsoul/go-metrics-tpl/pkg/configfile"
 )
 
 type Postgres struct {
@@ -13,15 +15,59 @@ type Postgres struct {
 	BackoffRetries   bool            `arg:"--backoff,env:BACKOFF_RETRIES" default:"true" help:"Повтор запроса при разрыве соединения"`
 }
 
+type Retention struct {
+	RetentionInterval time.Duration `arg:"--retention-interval,env:RETENTION_INTERVAL" default:"1m" help:"Интервал сжатия истории метрик (0 отключает)"`
+	RetentionRaw      time.Duration `arg:"--retention-raw,env:RETENTION_RAW" default:"1h" help:"Время хранения сырых значений до агрегации по минутам (0 хранит без агрегации)"`
+	RetentionMinute   time.Duration `arg:"--retention-minute,env:RETENTION_MINUTE" default:"24h" help:"Время хранения минутных агрегатов до агрегации по часам (0 хранит без агрегации)"`
+	RetentionHour     time.Duration `arg:"--retention-hour,env:RETENTION_HOUR" default:"720h" help:"Время хранения часовых агрегатов (0 хранит бессрочно)"`
+}
+
+// Policy returns the retention policy of the metric history.
+func (r Retention) Policy() repositories.RetentionPolicy {
+	return repositories.RetentionPolicy{
+		RawTTL:    r.RetentionRaw,
+		MinuteTTL: r.RetentionMinute,
+		HourTTL:   r.RetentionHour,
+	}
+}
+
+type StatsD struct {
+	StatsdUDPAddress    string        `arg:"--statsd-udp,env:STATSD_UDP_ADDRESS" default:"" help:"Адрес UDP приёмника StatsD (пустая строка отключает)"`
+	StatsdTCPAddress    string        `arg:"--statsd-tcp,env:STATSD_TCP_ADDRESS" default:"" help:"Адрес TCP приёмника StatsD (пустая строка отключает)"`
+	StatsdFlushInterval time.Duration `arg:"--statsd-flush-interval,env:STATSD_FLUSH_INTERVAL" default:"10s" help:"Интервал записи агрегированных метрик StatsD в хранилище"`
+}
+
+// Enabled reports whether at least one StatsD listener is configured.
+func (s StatsD) Enabled() bool {
+	return s.StatsdUDPAddress != "" || s.StatsdTCPAddress != ""
+}
+
+type Graphite struct {
+	GraphiteAddress        string        `arg:"--graphite-address,env:GRAPHITE_ADDRESS" default:"" help:"Адрес TCP приёмника Graphite (пустая строка отключает)"`
+	GraphiteTemplates      []string      `arg:"--graphite-template,env:GRAPHITE_TEMPLATES" help:"Шаблоны разбора путей Graphite на имя и метки, например \"servers.* .host.name*\""`
+	GraphiteMaxConnections int           `arg:"--graphite-max-connections,env:GRAPHITE_MAX_CONNECTIONS" default:"100" help:"Максимальное число одновременных соединений Graphite"`
+	GraphiteIdleTimeout    time.Duration `arg:"--graphite-idle-timeout,env:GRAPHITE_IDLE_TIMEOUT" default:"1m" help:"Время, после которого соединение Graphite без данных закрывается (0 отключает)"`
+}
+
 type Config struct {
 	Postgres
-	ListenAddress   string `arg:"-a,env:ADDRESS" default:"localhost:8080" help:"Адрес и порт сервера"`
-	LogLevel        string `arg:"--ll,env:LOG_LEVEL" default:"INFO" help:"Уровень логирования"`
-	StoreInterval   int    `arg:"-i,env:STORE_INTERVAL" default:"300" help:"Интервал времени в секундах, по истечении которого текущие показания сервера сохраняются на диск"`
-	FileStoragePath string `arg:"-f,env:FILE_STORAGE_PATH" default:"/tmp/metrics-db.json" help:"Полное имя файла, куда сохраняются текущие значения"`
-	Restore         bool   `arg:"-r,env:RESTORE" default:"true" help:"Загружать или нет ранее сохранённые значения из указанного файла при старте сервера"`
-	HashBodyKey     string `arg:"-k,env:KEY" default:"" help:"hash key"`
-	Debug           bool   `arg:"--debug,env:DEBUG" default:"false" help:"debug mode"`
+	Retention
+	StatsD
+	Graphite
+	ConfigFile      string        `arg:"-c,--config,env:CONFIG" default:"" help:"Путь к файлу конфигурации в формате JSON или YAML (ключи - имена переменных окружения в нижнем регистре)"`
+	ListenAddress   string        `arg:"-a,env:ADDRESS" default:"localhost:8080" help:"Адрес и порт сервера"`
+	GRPCAddress     string        `arg:"--grpc-address,env:GRPC_ADDRESS" default:"" help:"Адрес и порт gRPC сервера (пустая строка отключает)"`
+	LogLevel        string        `arg:"--ll,env:LOG_LEVEL" default:"INFO" help:"Уровень логирования"`
+	StoreInterval   int           `arg:"-i,env:STORE_INTERVAL" default:"300" help:"Интервал времени в секундах, по истечении которого текущие показания сервера сохраняются на диск"`
+	FileStoragePath string        `arg:"-f,env:FILE_STORAGE_PATH" default:"/tmp/metrics-db.json" help:"Полное имя файла, куда сохраняются текущие значения"`
+	Restore         bool          `arg:"-r,env:RESTORE" default:"true" help:"Загружать или нет ранее сохранённые значения из указанного файла при старте сервера"`
+	HashBodyKey     string        `arg:"-k,env:KEY" default:"" help:"hash key"`
+	TrustedSubnet   []string      `arg:"-t,--trusted-subnet,env:TRUSTED_SUBNET" help:"Доверенные подсети агентов в формате CIDR, проверяются для /update/, /updates/ и админских маршрутов (пусто разрешает все)"`
+	TrustedProxy    []string      `arg:"--trusted-proxy,env:TRUSTED_PROXY" help:"Подсети доверенных прокси в формате CIDR, только от них админские маршруты берут адрес клиента из заголовка X-Real-IP"`
+	CryptoKey       string        `arg:"--crypto-key,env:CRYPTO_KEY" default:"" help:"Путь к файлу с приватным RSA ключом для расшифровки метрик агента, с ключом /update/ и /updates/ принимают только зашифрованные запросы (несовместим с --grpc-address)"`
+	Debug           bool          `arg:"--debug,env:DEBUG" default:"false" help:"debug mode"`
+	ShutdownTimeout time.Duration `arg:"--shutdown-timeout,env:SHUTDOWN_TIMEOUT" default:"10s" help:"Время ожидания завершения обработки запросов при остановке сервера"`
+	PrometheusPath  string        `arg:"--prometheus-path,env:PROMETHEUS_PATH" default:"/metrics" help:"Путь эндпоинта метрик в формате Prometheus (пустая строка отключает)"`
 }
 
 func NewConfig() (*Config, error) {
@@ -31,6 +77,12 @@ func NewConfig() (*Config, error) {
 		return nil, err
 	}
 
+	if cfg.ConfigFile != "" {
+		if err := configfile.Load(&cfg, cfg.ConfigFile, configfile.Args()); err != nil {
+			return nil, err
+		}
+	}
+
 	if cfg.Postgres.BackoffIntervals == nil && cfg.Postgres.BackoffRetries {
 		cfg.Postgres.BackoffIntervals = []time.Duration{1 * time.Second, 3 * time.Second, 5 * time.Second}
 	} else if !cfg.Postgres.BackoffRetries {
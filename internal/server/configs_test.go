@@ -2,11 +2,14 @@ package server_test
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
 	"github.com/screamsoul/go-metrics-tpl/internal/server"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestBackoffIntervalConfig(t *testing.T) {
@@ -59,3 +62,59 @@ func TestBackoffIntervalConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestRetentionConfig(t *testing.T) {
+	os.Args = nil
+	t.Setenv("RETENTION_RAW", "30m")
+	t.Setenv("RETENTION_HOUR", "0s")
+
+	cfg, err := server.NewConfig()
+	assert.NoError(t, err)
+
+	assert.Equal(t, time.Minute, cfg.RetentionInterval)
+	assert.Equal(t, repositories.RetentionPolicy{
+		RawTTL:    30 * time.Minute,
+		MinuteTTL: 24 * time.Hour,
+		HourTTL:   0,
+	}, cfg.Retention.Policy())
+}
+
+func TestConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"address": "localhost:9090",
+		"store_interval": 10,
+		"restore": false,
+		"backoff_intervals": ["2s", "4s"],
+		"retention_raw": "2h",
+		"trusted_subnet": ["10.0.0.0/8"]
+	}`), 0o600))
+
+	os.Args = []string{"server", "-i", "20"}
+	defer func() { os.Args = nil }()
+	t.Setenv("CONFIG", path)
+	t.Setenv("RESTORE", "true")
+
+	cfg, err := server.NewConfig()
+	require.NoError(t, err)
+
+	assert.Equal(t, "localhost:9090", cfg.ListenAddress)
+	assert.Equal(t, 20, cfg.StoreInterval)
+	assert.True(t, cfg.Restore)
+	assert.Equal(t, []time.Duration{2 * time.Second, 4 * time.Second}, cfg.Postgres.BackoffIntervals)
+	assert.Equal(t, 2*time.Hour, cfg.RetentionRaw)
+	assert.Equal(t, []string{"10.0.0.0/8"}, cfg.TrustedSubnet)
+	assert.Equal(t, "/tmp/metrics-db.json", cfg.FileStoragePath)
+}
+
+func TestConfigFileUnknownKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "server.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("adress: localhost:9090\n"), 0o600))
+
+	os.Args = []string{"server", "-c", path}
+	defer func() { os.Args = nil }()
+
+	_, err := server.NewConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown keys: adress")
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/middlewares"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories/file"
+	"github.com/screamsoul/go-metrics-tpl/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// Reloader re-reads the configuration and applies reloadable settings to the running server:
+// the hash key, the log level and the snapshot interval. Other settings require a restart.
+//
+// Flags and environment variables of the process do not change, so in practice
+// the settings are changed in the configuration file.
+type Reloader struct {
+	mu         sync.Mutex
+	loadConfig func() (*Config, error)
+	hashKey    *middlewares.HashKey
+	storage    *file.FileRestoreMetricWrapper
+	logger     *zap.Logger
+}
+
+func NewReloader(
+	loadConfig func() (*Config, error),
+	hashKey *middlewares.HashKey,
+	storage *file.FileRestoreMetricWrapper,
+) *Reloader {
+	return &Reloader{
+		loadConfig: loadConfig,
+		hashKey:    hashKey,
+		storage:    storage,
+		logger:     logging.GetLogger(),
+	}
+}
+
+// Reload re-reads the configuration, nothing is applied if it is invalid.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := r.loadConfig()
+	if err != nil {
+		r.logger.Error("reload config error", zap.Error(err))
+		return err
+	}
+
+	if err := logging.SetLevel(cfg.LogLevel); err != nil {
+		r.logger.Error("reload config error", zap.Error(err))
+		return err
+	}
+
+	r.hashKey.Store(cfg.HashBodyKey)
+	r.storage.SetStoreInterval(cfg.StoreInterval)
+
+	r.logger.Info("config reloaded",
+		zap.String("LogLevel", cfg.LogLevel),
+		zap.Int("StoreInterval", cfg.StoreInterval),
+		zap.Bool("HashBodyKey", cfg.HashBodyKey != ""),
+	)
+
+	return nil
+}
+
+// ReloadHandler admin handler, reloads the configuration like SIGHUP.
+// It must be mounted behind middlewares.NewAdminMiddleware, which requires a fresh signature.
+// The error is only logged, so that the configuration is not exposed to the caller.
+func (r *Reloader) ReloadHandler(w http.ResponseWriter, req *http.Request) {
+	if err := r.Reload(); err != nil {
+		http.Error(w, "The config cannot be reloaded, see the server log", http.StatusBadRequest)
+		return
+	}
+}
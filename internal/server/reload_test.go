@@ -0,0 +1,68 @@
+package server_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/middlewares"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories/file"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories/memory"
+	"github.com/screamsoul/go-metrics-tpl/internal/server"
+	"github.com/screamsoul/go-metrics-tpl/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestReloader(t *testing.T) {
+	require.NoError(t, logging.Initialize("info"))
+
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "server.json")
+	writeConfig := func(content string) {
+		require.NoError(t, os.WriteFile(configPath, []byte(content), 0o600))
+	}
+
+	os.Args = nil
+	t.Setenv("CONFIG", configPath)
+	writeConfig(`{"key": "old", "store_interval": 300}`)
+
+	ctx := context.Background()
+	storage := file.NewFileRestoreMetricWrapper(ctx, memory.NewMemStorage(), filepath.Join(dir, "metrics.json"), 300, false)
+	defer storage.Shutdown(ctx)
+
+	hashKey := middlewares.NewHashKey("old")
+	reloader := server.NewReloader(server.NewConfig, hashKey, storage)
+
+	writeConfig(`{"key": "new", "store_interval": 5, "log_level": "ERROR"}`)
+	require.NoError(t, reloader.Reload())
+
+	assert.Equal(t, "new", hashKey.Load())
+	assert.Equal(t, 5, storage.StoreInterval())
+	assert.False(t, logging.GetLogger().Core().Enabled(zapcore.InfoLevel))
+
+	// invalid config is not applied
+	writeConfig(`{"key": "other", "log_level": "LOUD"}`)
+	assert.Error(t, reloader.Reload())
+	assert.Equal(t, "new", hashKey.Load())
+
+	writeConfig(`{"key": "other", "unknown": 1}`)
+
+	rr := httptest.NewRecorder()
+	reloader.ReloadHandler(rr, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.NotContains(t, rr.Body.String(), "unknown")
+
+	writeConfig(`{"key": "other", "log_level": "INFO"}`)
+
+	rr = httptest.NewRecorder()
+	reloader.ReloadHandler(rr, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "other", hashKey.Load())
+	assert.Equal(t, 300, storage.StoreInterval())
+	assert.True(t, logging.GetLogger().Core().Enabled(zapcore.InfoLevel))
+}
@@ -0,0 +1,178 @@
+package statsd
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+)
+
+// Suffixes of the metrics a timer is flushed as.
+const (
+	TimerCountSuffix = ".count"
+	TimerMinSuffix   = ".min"
+	TimerMaxSuffix   = ".max"
+	TimerMeanSuffix  = ".mean"
+	TimerSumSuffix   = ".sum"
+)
+
+// series identifies the aggregated metric series.
+type series struct {
+	name   string
+	labels metrics.Labels
+}
+
+func (s series) metric(mType metrics.MetricType) metrics.Metrics {
+	return metrics.Metrics{ID: s.name, MType: mType, Labels: s.labels}
+}
+
+type counterState struct {
+	series
+	value float64
+}
+
+type gaugeState struct {
+	series
+	value float64
+	set   bool // the value was set in the interval, otherwise value is added to the stored one
+}
+
+type timerState struct {
+	series
+	min, max, sum float64
+	count         int
+}
+
+// Aggregator accumulates StatsD samples between flushes.
+//
+// Counters are summed with the sample rate applied and written as counter deltas,
+// the fractional part is carried over to the next flush. Gauges keep the last value,
+// relative gauges are added to the value set in the interval or to the stored value.
+// Timers are written as `.min`, `.max`, `.mean` and `.sum` gauges and a `.count` counter.
+type Aggregator struct {
+	mu        sync.Mutex
+	counters  map[string]*counterState
+	gauges    map[string]*gaugeState
+	timers    map[string]*timerState
+	remainder map[string]float64
+}
+
+func NewAggregator() *Aggregator {
+	return &Aggregator{
+		counters:  make(map[string]*counterState),
+		gauges:    make(map[string]*gaugeState),
+		timers:    make(map[string]*timerState),
+		remainder: make(map[string]float64),
+	}
+}
+
+// Add adds the sample to the current interval.
+func (a *Aggregator) Add(sample Sample) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	s := series{name: sample.Name, labels: sample.Labels}
+
+	switch sample.Type {
+	case Counter:
+		a.addCounter(s, sample.Value/sample.Rate)
+	case Gauge:
+		key := s.name + s.labels.String()
+		g, ok := a.gauges[key]
+		if !ok {
+			g = &gaugeState{series: s}
+			a.gauges[key] = g
+		}
+		if sample.Relative {
+			g.value += sample.Value
+		} else {
+			g.value, g.set = sample.Value, true
+		}
+	case Timer, Histogram:
+		key := s.name + s.labels.String()
+		t, ok := a.timers[key]
+		if !ok {
+			t = &timerState{series: s, min: sample.Value, max: sample.Value}
+			a.timers[key] = t
+		}
+		t.min, t.max = min(t.min, sample.Value), max(t.max, sample.Value)
+		t.sum += sample.Value
+		t.count++
+		a.addCounter(series{name: s.name + TimerCountSuffix, labels: s.labels}, 1/sample.Rate)
+	}
+}
+
+func (a *Aggregator) addCounter(s series, value float64) {
+	key := s.name + s.labels.String()
+	c, ok := a.counters[key]
+	if !ok {
+		c = &counterState{series: s}
+		a.counters[key] = c
+	}
+	c.value += value
+}
+
+// Flush returns the metrics aggregated since the previous flush and starts a new interval.
+// The current values of relative gauges not set in the interval are read from the storage,
+// a missing gauge starts from zero.
+func (a *Aggregator) Flush(ctx context.Context, store repositories.MetricStorage) []metrics.Metrics {
+	a.mu.Lock()
+	counters, gauges, timers := a.counters, a.gauges, a.timers
+	a.counters = make(map[string]*counterState)
+	a.gauges = make(map[string]*gaugeState)
+	a.timers = make(map[string]*timerState)
+
+	batch := make([]metrics.Metrics, 0, len(counters)+len(gauges)+4*len(timers))
+	for key, c := range counters {
+		total := c.value + a.remainder[key]
+		delta := int64(math.Round(total))
+		if rest := total - float64(delta); rest != 0 {
+			a.remainder[key] = rest
+		} else {
+			delete(a.remainder, key)
+		}
+
+		m := c.metric(metrics.Counter)
+		m.Delta = &delta
+		batch = append(batch, m)
+	}
+	a.mu.Unlock()
+
+	for _, g := range gauges {
+		m := g.metric(metrics.Gauge)
+		value := g.value
+		if !g.set {
+			current := g.metric(metrics.Gauge)
+			if err := store.Get(ctx, &current); err == nil && current.Value != nil {
+				value += *current.Value
+			}
+		}
+		m.Value = &value
+		batch = append(batch, m)
+	}
+
+	for _, t := range timers {
+		mean := t.sum / float64(t.count)
+		for _, stat := range []struct {
+			suffix string
+			value  float64
+		}{
+			{TimerMinSuffix, t.min},
+			{TimerMaxSuffix, t.max},
+			{TimerMeanSuffix, mean},
+			{TimerSumSuffix, t.sum},
+		} {
+			value := stat.value
+			batch = append(batch, metrics.Metrics{
+				ID:     t.name + stat.suffix,
+				MType:  metrics.Gauge,
+				Value:  &value,
+				Labels: t.labels,
+			})
+		}
+	}
+
+	return batch
+}
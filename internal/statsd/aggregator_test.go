@@ -0,0 +1,82 @@
+package statsd
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories/memory"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// values returns the flushed values by type and series key.
+func values(batch []metrics.Metrics) map[string]float64 {
+	result := make(map[string]float64)
+	for _, m := range batch {
+		key := string(m.MType) + " " + m.SeriesKey()
+		if m.MType == metrics.Counter {
+			result[key] = float64(*m.Delta)
+		} else {
+			result[key] = *m.Value
+		}
+	}
+	return result
+}
+
+func TestAggregatorFlush(t *testing.T) {
+	ctx := context.Background()
+
+	store := memory.NewMemStorage()
+	stored := 10.0
+	require.NoError(t, store.Add(ctx, metrics.Metrics{ID: "queue", MType: metrics.Gauge, Value: &stored}))
+
+	aggregator := NewAggregator()
+	for _, line := range []string{
+		"hits:1|c", "hits:1|c|@0.5", "hits:1|c|#host:a",
+		"temp:20|g", "temp:+2|g",
+		"queue:-3|g",
+		"db:10|ms", "db:30|ms|@0.5",
+	} {
+		sample, err := ParseLine(line)
+		require.NoError(t, err)
+		aggregator.Add(sample)
+	}
+
+	batch := aggregator.Flush(ctx, store)
+	assert.Equal(t, map[string]float64{
+		"counter hits":           3,
+		`counter hits{host="a"}`: 1,
+		"gauge temp":             22,
+		"gauge queue":            7,
+		"gauge db.min":           10,
+		"gauge db.max":           30,
+		"gauge db.mean":          20,
+		"gauge db.sum":           40,
+		"counter db.count":       3,
+	}, values(batch))
+
+	assert.Empty(t, aggregator.Flush(ctx, store))
+}
+
+func TestAggregatorCarriesCounterRemainder(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewMemStorage()
+
+	aggregator := NewAggregator()
+	sample, err := ParseLine("hits:1|c|@0.4")
+	require.NoError(t, err)
+
+	var deltas []int64
+	for i := 0; i < 2; i++ {
+		aggregator.Add(sample)
+		for _, m := range aggregator.Flush(ctx, store) {
+			deltas = append(deltas, *m.Delta)
+		}
+	}
+
+	// 2.5 is rounded to 2, the rest is added to the next 2.5
+	sort.Slice(deltas, func(i, j int) bool { return deltas[i] < deltas[j] })
+	assert.Equal(t, []int64{2, 3}, deltas)
+}
@@ -0,0 +1,2 @@
+// Module with a StatsD listener aggregating StatsD samples and writing them to the metric storage.
+package statsd
@@ -0,0 +1,103 @@
+package statsd
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+)
+
+// Type the type of a StatsD sample.
+type Type string
+
+const (
+	Counter Type = "c"
+	Gauge   Type = "g"
+	Timer   Type = "ms"
+	// Histogram the DogStatsD histogram, aggregated like a timer.
+	Histogram Type = "h"
+)
+
+// Sample a single parsed StatsD sample.
+type Sample struct {
+	Name  string
+	Type  Type
+	Value float64
+	// Relative the gauge value is added to the current value, set by a leading `+` or `-`.
+	Relative bool
+	// Rate the sample rate set by `|@rate`, 1 when not set.
+	Rate float64
+	// Labels DogStatsD tags set by `|#name:value,...`.
+	Labels metrics.Labels
+}
+
+// ParseLine parses a StatsD line: `name:value|type[|@rate][|#tags]`.
+func ParseLine(line string) (Sample, error) {
+	sample := Sample{Rate: 1}
+
+	name, rest, ok := strings.Cut(line, ":")
+	if !ok || name == "" {
+		return sample, fmt.Errorf("bad line `%s`: missing name", line)
+	}
+	sample.Name = name
+
+	fields := strings.Split(rest, "|")
+	if len(fields) < 2 {
+		return sample, fmt.Errorf("bad line `%s`: missing type", line)
+	}
+
+	sample.Type = Type(fields[1])
+	switch sample.Type {
+	case Counter, Gauge, Timer, Histogram:
+	default:
+		return sample, fmt.Errorf("bad line `%s`: unsupported type `%s`", line, fields[1])
+	}
+
+	value := fields[0]
+	if sample.Type == Gauge && (strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-")) {
+		sample.Relative = true
+	}
+	var err error
+	if sample.Value, err = strconv.ParseFloat(value, 64); err != nil {
+		return sample, fmt.Errorf("bad line `%s`: bad value: %w", line, err)
+	}
+	if math.IsNaN(sample.Value) || math.IsInf(sample.Value, 0) {
+		return sample, fmt.Errorf("bad line `%s`: value is not finite", line)
+	}
+
+	for _, field := range fields[2:] {
+		switch {
+		case strings.HasPrefix(field, "@"):
+			rate, err := strconv.ParseFloat(field[1:], 64)
+			if err != nil || !(rate > 0 && rate <= 1) {
+				return sample, fmt.Errorf("bad line `%s`: sample rate must be in (0, 1]", line)
+			}
+			sample.Rate = rate
+		case strings.HasPrefix(field, "#"):
+			if sample.Labels, err = parseTags(field[1:]); err != nil {
+				return sample, fmt.Errorf("bad line `%s`: %w", line, err)
+			}
+		default:
+			return sample, fmt.Errorf("bad line `%s`: unknown field `%s`", line, field)
+		}
+	}
+
+	return sample, nil
+}
+
+// parseTags parses DogStatsD tags into labels, a tag without a value gets an empty value.
+func parseTags(tags string) (metrics.Labels, error) {
+	if tags == "" {
+		return nil, errors.New("empty tags")
+	}
+
+	labels := make(metrics.Labels)
+	for _, tag := range strings.Split(tags, ",") {
+		name, value, _ := strings.Cut(tag, ":")
+		labels[name] = value
+	}
+	return labels, labels.Validate()
+}
@@ -0,0 +1,43 @@
+package statsd
+
+import (
+	"testing"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLine(t *testing.T) {
+	testCases := []struct {
+		line   string
+		expect Sample
+	}{
+		{line: "hits:1|c", expect: Sample{Name: "hits", Type: Counter, Value: 1, Rate: 1}},
+		{line: "hits:2|c|@0.5", expect: Sample{Name: "hits", Type: Counter, Value: 2, Rate: 0.5}},
+		{line: "temp:21.5|g", expect: Sample{Name: "temp", Type: Gauge, Value: 21.5, Rate: 1}},
+		{line: "temp:+3|g", expect: Sample{Name: "temp", Type: Gauge, Value: 3, Relative: true, Rate: 1}},
+		{line: "temp:-3|g", expect: Sample{Name: "temp", Type: Gauge, Value: -3, Relative: true, Rate: 1}},
+		{line: "db.query:12|ms|@0.1", expect: Sample{Name: "db.query", Type: Timer, Value: 12, Rate: 0.1}},
+		{
+			line:   "req:1|c|#host:a,region:eu",
+			expect: Sample{Name: "req", Type: Counter, Value: 1, Rate: 1, Labels: metrics.Labels{"host": "a", "region": "eu"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.line, func(t *testing.T) {
+			sample, err := ParseLine(tc.line)
+			require.NoError(t, err)
+			assert.Equal(t, tc.expect, sample)
+		})
+	}
+
+	for _, line := range []string{
+		"hits", ":1|c", "hits:1", "hits:abc|c", "users:1|s", "hits:1|c|@0", "hits:1|c|@2", "hits:1|c|x", "hits:1|c|#bad-tag:1",
+		"hits:NaN|c", "temp:+Inf|g", "temp:-inf|g", "latency:1e400|ms", "hits:1|c|@NaN",
+	} {
+		_, err := ParseLine(line)
+		assert.Error(t, err, line)
+	}
+}
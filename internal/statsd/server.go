@@ -0,0 +1,210 @@
+package statsd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories"
+	"github.com/screamsoul/go-metrics-tpl/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// maxPacketSize the maximum size of a StatsD UDP packet.
+const maxPacketSize = 64 * 1024
+
+// Server receives StatsD lines over UDP and TCP and writes aggregated metrics
+// to the storage every flush interval.
+type Server struct {
+	aggregator    *Aggregator
+	store         repositories.MetricStorage
+	flushInterval time.Duration
+	logger        *zap.Logger
+
+	udp    net.PacketConn
+	tcp    net.Listener
+	mu     sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func NewServer(store repositories.MetricStorage, flushInterval time.Duration) *Server {
+	return &Server{
+		aggregator:    NewAggregator(),
+		store:         store,
+		flushInterval: flushInterval,
+		logger:        logging.GetLogger(),
+		conns:         make(map[net.Conn]struct{}),
+	}
+}
+
+// Listen binds the UDP and TCP addresses, an empty address disables the transport.
+func (s *Server) Listen(udpAddress, tcpAddress string) (err error) {
+	if udpAddress != "" {
+		if s.udp, err = net.ListenPacket("udp", udpAddress); err != nil {
+			return err
+		}
+	}
+	if tcpAddress != "" {
+		if s.tcp, err = net.Listen("tcp", tcpAddress); err != nil {
+			s.close()
+			return err
+		}
+	}
+	return nil
+}
+
+// UDPAddr returns the bound UDP address or nil.
+func (s *Server) UDPAddr() net.Addr {
+	if s.udp == nil {
+		return nil
+	}
+	return s.udp.LocalAddr()
+}
+
+// TCPAddr returns the bound TCP address or nil.
+func (s *Server) TCPAddr() net.Addr {
+	if s.tcp == nil {
+		return nil
+	}
+	return s.tcp.Addr()
+}
+
+// Run serves the bound listeners and flushes aggregated metrics until the context is done,
+// then closes the listeners and flushes the rest.
+func (s *Server) Run(ctx context.Context) {
+	if s.udp != nil {
+		s.wg.Add(1)
+		go s.serveUDP()
+	}
+	if s.tcp != nil {
+		s.wg.Add(1)
+		go s.serveTCP()
+	}
+
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.close()
+			s.wg.Wait()
+			s.Flush(context.WithoutCancel(ctx))
+			return
+		case <-ticker.C:
+			s.Flush(ctx)
+		}
+	}
+}
+
+// Flush writes the metrics aggregated since the previous flush to the storage.
+func (s *Server) Flush(ctx context.Context) {
+	batch := s.aggregator.Flush(ctx, s.store)
+	if len(batch) == 0 {
+		return
+	}
+
+	if err := s.store.BulkAdd(ctx, batch); err != nil {
+		s.logger.Error("error write statsd metrics", zap.Int("count", len(batch)), zap.Error(err))
+	}
+}
+
+// Handle parses the StatsD lines and adds valid samples to the current interval.
+func (s *Server) Handle(data string) {
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		sample, err := ParseLine(line)
+		if err != nil {
+			s.logger.Debug("skip statsd line", zap.Error(err))
+			continue
+		}
+		s.aggregator.Add(sample)
+	}
+}
+
+func (s *Server) serveUDP() {
+	defer s.wg.Done()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, _, err := s.udp.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			s.logger.Warn("statsd udp read error", zap.Error(err))
+			continue
+		}
+		s.Handle(string(buf[:n]))
+	}
+}
+
+func (s *Server) serveTCP() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.tcp.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			s.logger.Warn("statsd tcp accept error", zap.Error(err))
+			continue
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go s.serveConn(conn)
+	}
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	scanner := bufio.NewScanner(conn)
+	scanner.Buffer(make([]byte, 4096), maxPacketSize)
+	for scanner.Scan() {
+		s.Handle(scanner.Text())
+	}
+}
+
+// close closes the listeners and open TCP connections.
+func (s *Server) close() {
+	if s.udp != nil {
+		_ = s.udp.Close()
+	}
+	if s.tcp != nil {
+		_ = s.tcp.Close()
+	}
+
+	s.mu.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+}
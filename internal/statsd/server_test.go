@@ -0,0 +1,81 @@
+package statsd_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/screamsoul/go-metrics-tpl/internal/models/metrics"
+	"github.com/screamsoul/go-metrics-tpl/internal/repositories/memory"
+	"github.com/screamsoul/go-metrics-tpl/internal/statsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServerReceivesUDPAndTCP(t *testing.T) {
+	store := memory.NewMemStorage()
+
+	server := statsd.NewServer(store, 20*time.Millisecond)
+	require.NoError(t, server.Listen("127.0.0.1:0", "127.0.0.1:0"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		server.Run(ctx)
+		close(done)
+	}()
+
+	udp, err := net.Dial("udp", server.UDPAddr().String())
+	require.NoError(t, err)
+	defer udp.Close()
+	_, err = udp.Write([]byte("hits:2|c\ntemp:21.5|g\nbad line"))
+	require.NoError(t, err)
+
+	tcp, err := net.Dial("tcp", server.TCPAddr().String())
+	require.NoError(t, err)
+	defer tcp.Close()
+	_, err = tcp.Write([]byte("hits:3|c\n"))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		m := metrics.Metrics{ID: "hits", MType: metrics.Counter}
+		return store.Get(ctx, &m) == nil && *m.Delta == 5
+	}, 5*time.Second, 10*time.Millisecond)
+
+	temp := metrics.Metrics{ID: "temp", MType: metrics.Gauge}
+	require.NoError(t, store.Get(ctx, &temp))
+	assert.Equal(t, 21.5, *temp.Value)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("statsd server did not stop")
+	}
+}
+
+func TestServerFlushesOnStop(t *testing.T) {
+	store := memory.NewMemStorage()
+
+	server := statsd.NewServer(store, time.Hour)
+	require.NoError(t, server.Listen("", ""))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	server.Handle("hits:1|c")
+	cancel()
+	server.Run(ctx)
+
+	m := metrics.Metrics{ID: "hits", MType: metrics.Counter}
+	require.NoError(t, store.Get(context.Background(), &m))
+	assert.Equal(t, int64(1), *m.Delta)
+}
+
+func TestServerListenError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	server := statsd.NewServer(memory.NewMemStorage(), time.Second)
+	assert.Error(t, server.Listen("127.0.0.1:0", listener.Addr().String()))
+}
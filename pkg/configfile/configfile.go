@@ -0,0 +1,199 @@
+// Package configfile fills go-arg configuration structs from a JSON or YAML file.
+//
+// A field is addressed in the file by its environment variable name in lower case,
+// e.g. `env:STORE_INTERVAL` is the `store_interval` key. Values are parsed the same way
+// as flags: durations as "10s", lists as arrays. The file has a lower priority than
+// flags and environment variables, but a higher one than the `default` tags:
+// Load only sets fields which were not given on the command line or in the environment.
+package configfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	scalar "github.com/alexflint/go-scalar"
+	"gopkg.in/yaml.v3"
+)
+
+// field a configuration field addressable from the file.
+type field struct {
+	value reflect.Value
+	short string
+	long  string
+	env   string
+}
+
+// Args returns the command line arguments without the program name.
+func Args() []string {
+	if len(os.Args) == 0 {
+		return nil
+	}
+	return os.Args[1:]
+}
+
+// Load reads the file at path into dest, a pointer to a struct already parsed by go-arg.
+//
+// Fields set by flags in args or by environment variables are kept.
+// Unknown keys and values which cannot be parsed are reported as errors.
+func Load(dest any, path string, args []string) error {
+	values, err := read(path)
+	if err != nil {
+		return err
+	}
+
+	fields := make(map[string]field)
+	collectFields(reflect.ValueOf(dest).Elem(), fields)
+
+	var unknown []string
+	for key := range values {
+		if _, ok := fields[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("config file %s: unknown keys: %s", path, strings.Join(unknown, ", "))
+	}
+
+	for key, raw := range values {
+		f := fields[key]
+		if f.isExplicit(args) {
+			continue
+		}
+		if err := setValue(f.value, raw); err != nil {
+			return fmt.Errorf("config file %s: key %q: %w", path, key, err)
+		}
+	}
+
+	return nil
+}
+
+func read(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]any)
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	default:
+		err = json.Unmarshal(data, &values)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return values, nil
+}
+
+// collectFields walks the struct and embedded structs, indexing fields by their file key.
+func collectFields(v reflect.Value, fields map[string]field) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			collectFields(v.Field(i), fields)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		f := parseArgTag(sf)
+		if f.env == "" {
+			continue
+		}
+		f.value = v.Field(i)
+		fields[strings.ToLower(f.env)] = f
+	}
+}
+
+// parseArgTag extracts names of the option from the go-arg tag.
+func parseArgTag(sf reflect.StructField) field {
+	f := field{long: strings.ToLower(sf.Name)}
+
+	tag, ok := sf.Tag.Lookup("arg")
+	if !ok || tag == "-" {
+		return field{}
+	}
+
+	for _, item := range strings.Split(tag, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case strings.HasPrefix(item, "--"):
+			f.long = item[2:]
+		case strings.HasPrefix(item, "-"):
+			f.short = item[1:]
+		case item == "env":
+			f.env = strings.ToUpper(sf.Name)
+		case strings.HasPrefix(item, "env:"):
+			f.env = item[len("env:"):]
+		}
+	}
+
+	return f
+}
+
+// isExplicit reports whether the field is set by a flag or an environment variable.
+func (f field) isExplicit(args []string) bool {
+	if _, ok := os.LookupEnv(f.env); ok {
+		return true
+	}
+
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if name != "" && (name == f.long || name == f.short) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func setValue(v reflect.Value, raw any) error {
+	if v.Kind() == reflect.Slice {
+		items, ok := raw.([]any)
+		if !ok {
+			return fmt.Errorf("expected a list, got %v", raw)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(slice.Index(i), item); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	var s string
+	switch value := raw.(type) {
+	case string:
+		s = value
+	case bool:
+		s = strconv.FormatBool(value)
+	case int:
+		s = strconv.Itoa(value)
+	case float64:
+		s = strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Errorf("unsupported value %v", raw)
+	}
+
+	return scalar.ParseValue(v, s)
+}
@@ -0,0 +1,159 @@
+package configfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testEmbedded struct {
+	Intervals []time.Duration `arg:"--intervals,env:TEST_INTERVALS"`
+}
+
+type testConfig struct {
+	testEmbedded
+	Address  string        `arg:"-a,env:TEST_ADDRESS" default:"localhost:8080"`
+	Interval time.Duration `arg:"--interval,env:TEST_INTERVAL" default:"1s"`
+	Count    int           `arg:"-n,env:TEST_COUNT" default:"1"`
+	Restore  bool          `arg:"-r,env:TEST_RESTORE" default:"true"`
+	NoEnv    string        `arg:"--no-env"`
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func parse(t *testing.T, path string, args []string) (testConfig, error) {
+	var cfg testConfig
+
+	p, err := arg.NewParser(arg.Config{}, &cfg)
+	require.NoError(t, err)
+	require.NoError(t, p.Parse(args))
+
+	return cfg, Load(&cfg, path, args)
+}
+
+func TestLoad_Formats(t *testing.T) {
+	expected := testConfig{
+		testEmbedded: testEmbedded{Intervals: []time.Duration{time.Second, 3 * time.Second}},
+		Address:      "example.com:9090",
+		Interval:     time.Minute,
+		Count:        5,
+		Restore:      false,
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name: "json",
+			file: "config.json",
+			content: `{
+				"test_address": "example.com:9090",
+				"test_interval": "1m",
+				"test_count": 5,
+				"test_restore": false,
+				"test_intervals": ["1s", "3s"]
+			}`,
+		},
+		{
+			name: "yaml",
+			file: "config.yaml",
+			content: `
+test_address: example.com:9090
+test_interval: 1m
+test_count: 5
+test_restore: false
+test_intervals: [1s, 3s]
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := parse(t, writeFile(t, tt.file, tt.content), nil)
+			require.NoError(t, err)
+			assert.Equal(t, expected, cfg)
+		})
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "config.json", `{
+		"test_address": "file:1",
+		"test_interval": "1m",
+		"test_count": 5,
+		"test_restore": false
+	}`)
+
+	t.Setenv("TEST_INTERVAL", "1h")
+	t.Setenv("TEST_COUNT", "7")
+
+	cfg, err := parse(t, path, []string{"-n", "10"})
+	require.NoError(t, err)
+
+	// flags > env > file > defaults
+	assert.Equal(t, 10, cfg.Count)
+	assert.Equal(t, time.Hour, cfg.Interval)
+	assert.Equal(t, "file:1", cfg.Address)
+	assert.False(t, cfg.Restore)
+
+	cfg, err = parse(t, path, []string{"-r=true", "--intervals", "2s"})
+	require.NoError(t, err)
+	assert.True(t, cfg.Restore)
+	assert.Equal(t, []time.Duration{2 * time.Second}, cfg.Intervals)
+}
+
+func TestLoad_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		errText string
+	}{
+		{
+			name:    "unknown keys",
+			file:    "config.json",
+			content: `{"test_address": "a", "adress": "b", "no_env": "c"}`,
+			errText: "unknown keys: adress, no_env",
+		},
+		{
+			name:    "invalid duration",
+			file:    "config.json",
+			content: `{"test_interval": 10}`,
+			errText: `key "test_interval"`,
+		},
+		{
+			name:    "list expected",
+			file:    "config.yaml",
+			content: `test_intervals: 1s`,
+			errText: "expected a list",
+		},
+		{
+			name:    "invalid json",
+			file:    "config.json",
+			content: `{`,
+			errText: "config.json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parse(t, writeFile(t, tt.file, tt.content), nil)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errText)
+		})
+	}
+
+	_, err := parse(t, filepath.Join(t.TempDir(), "missing.json"), nil)
+	assert.Error(t, err)
+}
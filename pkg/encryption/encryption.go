@@ -0,0 +1,172 @@
+// Package encryption implements hybrid RSA encryption of request bodies.
+//
+// A message is encrypted with a random AES-256-GCM session key, the session key
+// is encrypted with the RSA public key (OAEP, SHA-256). The sealed message layout is
+//
+//	| key length (2 bytes, big endian) | encrypted session key | nonce | AES-GCM ciphertext |
+//
+// so a batch of any size can be encrypted with a single RSA operation.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// HeaderName the request header which marks an encrypted body.
+const HeaderName = "Content-Encryption"
+
+// Scheme the value of HeaderName for bodies sealed by Encrypt.
+const Scheme = "rsa-oaep-aes256gcm"
+
+const sessionKeySize = 32
+
+var ErrMalformedMessage = errors.New("malformed encrypted message")
+
+// Encrypt seals the plaintext for the owner of the private key paired with publicKey.
+func Encrypt(publicKey *rsa.PublicKey, plaintext []byte) ([]byte, error) {
+	sessionKey := make([]byte, sessionKeySize)
+	if _, err := rand.Read(sessionKey); err != nil {
+		return nil, err
+	}
+
+	encryptedKey, err := rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, sessionKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 2, 2+len(encryptedKey)+len(nonce)+len(plaintext)+gcm.Overhead())
+	binary.BigEndian.PutUint16(out, uint16(len(encryptedKey)))
+	out = append(out, encryptedKey...)
+	out = append(out, nonce...)
+
+	return gcm.Seal(out, nonce, plaintext, nil), nil
+}
+
+// Decrypt opens the message sealed by Encrypt.
+func Decrypt(privateKey *rsa.PrivateKey, message []byte) ([]byte, error) {
+	if len(message) < 2 {
+		return nil, ErrMalformedMessage
+	}
+	keyLen := int(binary.BigEndian.Uint16(message))
+	message = message[2:]
+	if len(message) < keyLen {
+		return nil, ErrMalformedMessage
+	}
+
+	sessionKey, err := rsa.DecryptOAEP(sha256.New(), rand.Reader, privateKey, message[:keyLen], nil)
+	if err != nil {
+		return nil, err
+	}
+	message = message[keyLen:]
+
+	gcm, err := newGCM(sessionKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(message) < gcm.NonceSize() {
+		return nil, ErrMalformedMessage
+	}
+
+	return gcm.Open(nil, message[:gcm.NonceSize()], message[gcm.NonceSize():], nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// LoadPublicKey reads a PEM encoded RSA public key (PKIX or PKCS #1) or a certificate.
+func LoadPublicKey(path string) (*rsa.PublicKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return asRSAPublicKey(key)
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return asRSAPublicKey(cert.PublicKey)
+	default:
+		return nil, fmt.Errorf("unsupported public key type %q", block.Type)
+	}
+}
+
+// LoadPrivateKey reads a PEM encoded RSA private key (PKCS #1 or PKCS #8).
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("private key is %T, not RSA", key)
+		}
+		return rsaKey, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %q", block.Type)
+	}
+}
+
+func asRSAPublicKey(key any) (*rsa.PublicKey, error) {
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is %T, not RSA", key)
+	}
+	return rsaKey, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+	return block, nil
+}
@@ -0,0 +1,108 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptDecrypt(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name      string
+		plaintext []byte
+	}{
+		{name: "empty", plaintext: []byte{}},
+		{name: "small", plaintext: []byte(`[{"id":"Alloc","type":"gauge","value":1}]`)},
+		// bigger than a single RSA block
+		{name: "large", plaintext: bytes.Repeat([]byte("metric"), 10000)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message, err := Encrypt(&key.PublicKey, tt.plaintext)
+			require.NoError(t, err)
+			if len(tt.plaintext) > 0 {
+				assert.False(t, bytes.Contains(message, tt.plaintext))
+			}
+
+			plaintext, err := Decrypt(key, message)
+			require.NoError(t, err)
+			assert.Equal(t, tt.plaintext, append([]byte{}, plaintext...))
+		})
+	}
+}
+
+func TestDecryptRejectsForeignOrCorruptedMessage(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	message, err := Encrypt(&otherKey.PublicKey, []byte("payload"))
+	require.NoError(t, err)
+
+	_, err = Decrypt(key, message)
+	assert.Error(t, err)
+
+	message, err = Encrypt(&key.PublicKey, []byte("payload"))
+	require.NoError(t, err)
+	message[len(message)-1] ^= 0xff
+
+	_, err = Decrypt(key, message)
+	assert.Error(t, err)
+
+	_, err = Decrypt(key, []byte{0xff})
+	assert.ErrorIs(t, err, ErrMalformedMessage)
+}
+
+func TestLoadKeys(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	writePEM := func(name, blockType string, der []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600))
+		return path
+	}
+
+	pkix, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	for _, path := range []string{
+		writePEM("pkix.pem", "PUBLIC KEY", pkix),
+		writePEM("pkcs1.pem", "RSA PUBLIC KEY", x509.MarshalPKCS1PublicKey(&key.PublicKey)),
+	} {
+		publicKey, err := LoadPublicKey(path)
+		require.NoError(t, err)
+		assert.True(t, key.PublicKey.Equal(publicKey))
+	}
+
+	for _, path := range []string{
+		writePEM("pkcs8.pem", "PRIVATE KEY", pkcs8),
+		writePEM("pkcs1-private.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)),
+	} {
+		privateKey, err := LoadPrivateKey(path)
+		require.NoError(t, err)
+		assert.True(t, key.Equal(privateKey))
+	}
+
+	_, err = LoadPrivateKey(writePEM("wrong.pem", "PUBLIC KEY", pkix))
+	assert.Error(t, err)
+
+	_, err = LoadPublicKey(filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+}
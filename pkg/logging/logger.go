@@ -7,6 +7,9 @@ import (
 
 var log *zap.Logger = zap.NewNop()
 
+// atomicLevel the level of the logger, shared with the logger so it can be changed at runtime.
+var atomicLevel = zap.NewAtomicLevel()
+
 func Initialize(level string) error {
 	// преобразуем текстовый уровень логирования в zap.AtomicLevel
 	lvl, err := zapcore.ParseLevel(level)
@@ -16,7 +19,8 @@ func Initialize(level string) error {
 	// создаём новую конфигурацию логера
 	cfg := zap.NewProductionConfig()
 	// устанавливаем уровень
-	cfg.Level = zap.NewAtomicLevelAt(lvl)
+	atomicLevel.SetLevel(lvl)
+	cfg.Level = atomicLevel
 	// создаём логер на основе конфигурации
 	zl, err := cfg.Build()
 	if err != nil {
@@ -30,3 +34,13 @@ func Initialize(level string) error {
 func GetLogger() *zap.Logger {
 	return log
 }
+
+// SetLevel changes the level of the initialized logger atomically.
+func SetLevel(lvl string) error {
+	parsed, err := zapcore.ParseLevel(lvl)
+	if err != nil {
+		return err
+	}
+	atomicLevel.SetLevel(parsed)
+	return nil
+}
@@ -52,6 +52,20 @@ func (s *LoggingSuite) TestInitialize_Fail() {
 
 }
 
+func (s *LoggingSuite) TestSetLevel() {
+	logger := GetLogger()
+
+	s.Require().NoError(SetLevel("error"))
+	s.False(logger.Core().Enabled(zapcore.InfoLevel), "Info should be disabled after the level is raised")
+	s.True(logger.Core().Enabled(zapcore.ErrorLevel))
+
+	s.Require().NoError(SetLevel("debug"))
+	s.True(logger.Core().Enabled(zapcore.DebugLevel), "Debug should be enabled for the same logger")
+
+	s.Require().Error(SetLevel("fali lavel"))
+	s.True(logger.Core().Enabled(zapcore.DebugLevel), "Level should not change on error")
+}
+
 func TestLoggingSuite(t *testing.T) {
 	suite.Run(t, new(LoggingSuite))
 }